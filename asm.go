@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/assembler"
+	"github.com/pmcatominey/gochip8/octo"
+	"github.com/pmcatominey/gochip8/symbols"
+)
+
+// runAsm assembles a source file to a rom: gochip8 asm [flags] game.asm, or
+// compiles Octo source with a .8o extension
+func runAsm(args []string) {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	output := flags.String("o", "", "rom file to write, defaults to the source file with a .ch8 extension")
+	listing := flags.String("listing", "", "write the source with the address and bytes of each line to a file")
+	writeSymbols := flags.Bool("symbols", false, "write a symbol map for debuggers next to the rom, with a .sym.json extension")
+	flags.Parse(args)
+
+	source := flags.Arg(0)
+	if len(source) == 0 {
+		fmt.Println("no source file specified")
+		os.Exit(1)
+	}
+	if len(*output) == 0 {
+		*output = strings.TrimSuffix(source, filepath.Ext(source)) + ".ch8"
+	}
+
+	var rom []byte
+	var syms *symbols.Map
+	if filepath.Ext(source) == ".8o" {
+		// Octo source is compiled, without a listing
+		if len(*listing) > 0 {
+			fmt.Println("listings aren't supported for Octo source")
+			os.Exit(1)
+		}
+		program, err := octo.CompileFile(source)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		rom, syms = program.ROM, program.Symbols
+	} else {
+		program, err := assembler.AssembleFile(source)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		rom, syms = program.ROM, program.Symbols
+
+		if len(*listing) > 0 {
+			err := createFile(*listing, func(f *os.File) error {
+				return program.WriteListing(f)
+			})
+			if err != nil {
+				fmt.Println("error writing listing:", err.Error())
+				os.Exit(1)
+			}
+		}
+	}
+
+	if err := ioutil.WriteFile(*output, rom, 0644); err != nil {
+		fmt.Println("error writing rom:", err.Error())
+		os.Exit(1)
+	}
+
+	if *writeSymbols {
+		// Source paths are relative to the symbol map
+		path := symbols.DefaultPath(*output)
+		for i, line := range syms.Lines {
+			if rel, err := filepath.Rel(filepath.Dir(path), line.File); err == nil {
+				syms.Lines[i].File = rel
+			}
+		}
+
+		if err := syms.WriteFile(path); err != nil {
+			fmt.Println("error writing symbols:", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("assembled", len(rom), "bytes to", *output)
+}
@@ -0,0 +1,578 @@
+// Package assembler assembles Chip 8 programs written with the mnemonics of
+// Cowgod's Chip-8 Technical Reference, as used by Chipper and printed by the
+// disassembler, into roms.
+//
+// Each line is an optional label, an instruction or directive and a comment
+// starting with ;. Mnemonics, registers and directives are case insensitive:
+//
+//	        sprite = 6              ; constants are defined with = or EQU
+//	main:   LD I, paddle            ; labels end with :
+//	        DRW VA, VB, sprite
+//	        JP $                    ; $ is the address of the instruction
+//	paddle: DB 0x80, 0x80, #80      ; hex numbers start with 0x or #
+//	        DW 0b1000000010000000   ; binary with 0b
+//	        INCLUDE "font.asm"      ; relative to the including file
+//
+// Expressions combine numbers, 'c' characters, labels, constants and $ with
+// the operators of Go: | ^ & << >> + - * / % and unary - ~, with parentheses.
+// DB also takes "strings".
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/symbols"
+)
+
+// Error is an error at a column of a source line
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// ErrorList is every error found assembling a program
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	s := make([]string, len(l))
+	for i, err := range l {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Program is an assembled program
+type Program struct {
+	ROM     []byte
+	Symbols *symbols.Map // labels, and the line of each instruction
+
+	statements []*statement
+}
+
+// statement is a line of source
+type statement struct {
+	file   string
+	line   int
+	source string
+
+	label    string
+	labelCol int
+	constant string // name defined by EQU
+
+	name     string // mnemonic or directive, upper case
+	nameCol  int
+	operands []operand
+
+	address int
+	data    []byte // assembled bytes
+	code    bool   // true for instructions, false for data
+}
+
+// operand is an operand of a statement
+type operand struct {
+	text string
+	col  int
+}
+
+// constant is a name defined with = or EQU, evaluated when first used
+type constant struct {
+	st    *statement
+	expr  operand
+	value int
+	state int
+}
+
+// States of a constant
+const (
+	unevaluated = iota
+	evaluating
+	evaluated
+	failed
+)
+
+// errReported is returned for errors which have already been recorded, such
+// as using an invalid constant
+var errReported = errors.New("error already reported")
+
+// assembler holds the state of assembling a program
+type assembler struct {
+	readFile func(string) ([]byte, error)
+
+	statements []*statement
+	labels     map[string]*statement
+	constants  map[string]*constant
+	including  []string // files being included, to detect cycles
+
+	errors ErrorList
+}
+
+// Reserved names which can't be labels or constants
+var reserved = map[string]bool{
+	"I": true, "DT": true, "ST": true, "K": true, "F": true, "HF": true,
+	"B": true, "R": true, "LONG": true, "EQU": true,
+}
+
+// maxErrors stops assembling after this many errors
+const maxErrors = 20
+
+// AssembleFile assembles the source file at path
+func AssembleFile(path string) (*Program, error) {
+	return Assemble(path, os.ReadFile)
+}
+
+// Assemble assembles the source file at path, reading it and any included
+// files with readFile. Errors are returned as an ErrorList
+func Assemble(path string, readFile func(string) ([]byte, error)) (*Program, error) {
+	a := &assembler{
+		readFile:  readFile,
+		labels:    make(map[string]*statement),
+		constants: make(map[string]*constant),
+	}
+
+	src, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse every line, giving labels their addresses
+	a.include(path, src, chip8.ProgramStartAddress)
+	if len(a.errors) > 0 {
+		return nil, a.errors
+	}
+
+	// Then assemble, when every label is known
+	p := &Program{Symbols: symbols.New(), statements: a.statements}
+	for _, st := range a.statements {
+		a.assemble(st)
+		if len(a.errors) >= maxErrors {
+			break
+		}
+
+		p.ROM = append(p.ROM, st.data...)
+		if st.code {
+			p.Symbols.Lines = append(p.Symbols.Lines, symbols.Line{File: st.file, Line: st.line, Address: uint16(st.address)})
+		}
+	}
+	if len(a.errors) > 0 {
+		return nil, a.errors
+	}
+
+	for name, st := range a.labels {
+		p.Symbols.Labels[name] = uint16(st.address)
+	}
+	return p, nil
+}
+
+// errorf records an error at a column of st
+func (a *assembler) errorf(st *statement, col int, format string, args ...interface{}) {
+	a.errors = append(a.errors, &Error{st.file, st.line, col, fmt.Sprintf(format, args...)})
+}
+
+// error records err at a column of st, or the column of a columnError
+func (a *assembler) error(st *statement, col int, err error) {
+	if ce, ok := err.(*columnError); ok {
+		col = ce.col
+	}
+	a.errorf(st, col, "%s", err.Error())
+}
+
+// include parses the lines of a file starting at address, returning the
+// address after them
+func (a *assembler) include(path string, src []byte, address int) int {
+	a.including = append(a.including, path)
+	defer func() { a.including = a.including[:len(a.including)-1] }()
+
+	text := strings.TrimSuffix(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i, line := range strings.Split(text, "\n") {
+		if len(a.errors) >= maxErrors {
+			break
+		}
+
+		st := &statement{file: path, line: i + 1, source: line, address: address}
+		a.parse(st)
+		a.statements = append(a.statements, st)
+
+		if st.name == "INCLUDE" {
+			address = a.includeFile(st, address)
+			continue
+		}
+
+		address += a.size(st)
+		if address > chip8.MemorySize {
+			a.errorf(st, st.nameCol, "program is larger than memory")
+			break
+		}
+	}
+	return address
+}
+
+// includeFile includes the file named by an INCLUDE statement
+func (a *assembler) includeFile(st *statement, address int) int {
+	if len(st.operands) != 1 || !isString(st.operands[0].text) {
+		a.errorf(st, st.nameCol, "INCLUDE expects a \"file\"")
+		return address
+	}
+
+	name := st.operands[0].text
+	name = name[1 : len(name)-1]
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(st.file), name)
+	}
+
+	for _, including := range a.including {
+		if including == name {
+			a.errorf(st, st.operands[0].col, "%s includes itself", name)
+			return address
+		}
+	}
+
+	src, err := a.readFile(name)
+	if err != nil {
+		a.errorf(st, st.operands[0].col, "%s", err.Error())
+		return address
+	}
+	return a.include(name, src, address)
+}
+
+// parse splits a line into its label, name and operands
+func (a *assembler) parse(st *statement) {
+	line := stripComment(st.source)
+	i := skipSpace(line, 0)
+
+	// name returns the name starting at i and the index after it
+	name := func(i int) (string, int) {
+		start := i
+		for i < len(line) && isIdentChar(line[i]) {
+			i++
+		}
+		return line[start:i], i
+	}
+
+	word, end := name(i)
+	if word != "" && end < len(line) && line[end] == ':' {
+		st.label, st.labelCol = word, i+1
+		a.defineLabel(st)
+
+		i = skipSpace(line, end+1)
+		word, end = name(i)
+	}
+
+	if word == "" {
+		if i < len(line) {
+			a.errorf(st, i+1, "unexpected %q", line[i])
+		}
+		return
+	}
+
+	// Constants are name = expr or name EQU expr
+	rest := skipSpace(line, end)
+	next, nextEnd := name(rest)
+	if strings.EqualFold(next, "EQU") || strings.HasPrefix(line[rest:], "=") {
+		if strings.HasPrefix(line[rest:], "=") {
+			nextEnd = rest + 1
+		}
+		st.name = "EQU"
+		st.nameCol = rest + 1
+		st.operands = splitOperands(line, nextEnd)
+		a.defineConstant(st, word, i+1)
+		return
+	}
+
+	st.name, st.nameCol = strings.ToUpper(word), i+1
+	st.operands = splitOperands(line, end)
+}
+
+// defineLabel records the label of st
+func (a *assembler) defineLabel(st *statement) {
+	if a.defined(st, st.label, st.labelCol) {
+		a.labels[st.label] = st
+	}
+}
+
+// defineConstant records a constant defined by st
+func (a *assembler) defineConstant(st *statement, name string, col int) {
+	if len(st.operands) != 1 {
+		a.errorf(st, st.nameCol, "expected a single value for %s", name)
+		return
+	}
+	if a.defined(st, name, col) {
+		st.constant = name
+		a.constants[name] = &constant{st: st, expr: st.operands[0]}
+	}
+}
+
+// defined checks a name can be defined, returning false after recording an
+// error if it's reserved or already defined
+func (a *assembler) defined(st *statement, name string, col int) bool {
+	if reserved[strings.ToUpper(name)] || isRegister(name) {
+		a.errorf(st, col, "%s is reserved", name)
+		return false
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		a.errorf(st, col, "%s isn't a valid name", name)
+		return false
+	}
+
+	var other *statement
+	if l, ok := a.labels[name]; ok {
+		other = l
+	} else if c, ok := a.constants[name]; ok {
+		other = c.st
+	}
+	if other != nil {
+		a.errorf(st, col, "%s is already defined at %s:%d", name, other.file, other.line)
+		return false
+	}
+	return true
+}
+
+// stripComment removes a comment from a line, ignoring ; in strings and characters
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// skipSpace returns the index of the first character from i which isn't a space
+func skipSpace(line string, i int) int {
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// splitOperands splits a line from index i at commas outside of strings and
+// parentheses, trimming the operands
+func splitOperands(line string, i int) []operand {
+	line = strings.TrimRight(line, " \t")
+	var operands []operand
+	var quote byte
+	depth := 0
+	start := i
+
+	add := func(end int) {
+		s := skipSpace(line, start)
+		text := strings.TrimRight(line[s:end], " \t")
+		operands = append(operands, operand{text, s + 1})
+	}
+
+	for ; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(i)
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(line[start:]) != "" || len(operands) > 0 {
+		add(len(line))
+	}
+	return operands
+}
+
+// isString returns true if s is a "string"
+func isString(s string) bool {
+	return len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"'
+}
+
+// size returns the number of bytes st assembles to
+func (a *assembler) size(st *statement) int {
+	switch st.name {
+	case "", "EQU", "INCLUDE":
+		return 0
+	case "DB":
+		n := 0
+		for _, o := range st.operands {
+			if isString(o.text) {
+				n += len(o.text) - 2
+			} else {
+				n++
+			}
+		}
+		return n
+	case "DW":
+		return 2 * len(st.operands)
+	case "LD":
+		if len(st.operands) == 2 && hasPrefixFold(st.operands[1].text, "LONG ") {
+			return 4
+		}
+	}
+	return 2
+}
+
+// assemble assembles the data of st
+func (a *assembler) assemble(st *statement) {
+	switch st.name {
+	case "", "INCLUDE":
+	case "EQU":
+		// Evaluated when used, but errors are reported even when it isn't
+		if c := a.constants[st.constant]; c != nil {
+			a.evaluateConstant(c)
+		}
+	case "DB":
+		for _, o := range st.operands {
+			if isString(o.text) {
+				st.data = append(st.data, o.text[1:len(o.text)-1]...)
+				continue
+			}
+			if v, ok := a.value(st, o, -0x80, 0xFF); ok {
+				st.data = append(st.data, byte(v))
+			}
+		}
+	case "DW":
+		for _, o := range st.operands {
+			if v, ok := a.value(st, o, -0x8000, 0xFFFF); ok {
+				st.data = append(st.data, byte(v>>8), byte(v))
+			}
+		}
+	default:
+		op, long, ok := a.instruction(st)
+		if !ok {
+			return
+		}
+		st.code = true
+		st.data = []byte{byte(op >> 8), byte(op)}
+		if st.name == "LD" && long >= 0 {
+			st.data = append(st.data, byte(long>>8), byte(long))
+		}
+	}
+}
+
+// evaluateConstant returns the value of c, evaluating it the first time.
+// Errors are recorded where c is defined and errReported returned
+func (a *assembler) evaluateConstant(c *constant) (int, error) {
+	switch c.state {
+	case evaluated:
+		return c.value, nil
+	case failed:
+		return 0, errReported
+	case evaluating:
+		return 0, errors.New("constant refers to itself")
+	}
+
+	c.state = evaluating
+	v, err := evaluate(c.expr.text, c.expr.col, c.st.address, a.symbol(c.st))
+	if err != nil {
+		if err != errReported {
+			a.error(c.st, c.expr.col, err)
+		}
+		c.state = failed
+		return 0, errReported
+	}
+
+	c.state, c.value = evaluated, v
+	return v, nil
+}
+
+// symbol returns a function giving the value of names used by st
+func (a *assembler) symbol(st *statement) func(string, int) (int, error) {
+	return func(name string, col int) (int, error) {
+		if l, ok := a.labels[name]; ok {
+			return l.address, nil
+		}
+		if c, ok := a.constants[name]; ok {
+			v, err := a.evaluateConstant(c)
+			if err != nil && err != errReported {
+				return 0, &columnError{col, fmt.Sprintf("%s: %s", name, err.Error())}
+			}
+			return v, err
+		}
+		return 0, &columnError{col, fmt.Sprintf("undefined: %s", name)}
+	}
+}
+
+// value evaluates an operand of st between min and max, recording an error
+// and returning false if it fails
+func (a *assembler) value(st *statement, o operand, min, max int) (int, bool) {
+	v, err := evaluate(o.text, o.col, st.address, a.symbol(st))
+	if err != nil {
+		if err != errReported {
+			a.error(st, o.col, err)
+		}
+		return 0, false
+	}
+	if v < min || v > max {
+		a.errorf(st, o.col, "%d out of range %d to %d", v, min, max)
+		return 0, false
+	}
+	return v, true
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring case
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// WriteListing writes the source with the address and bytes assembled from
+// each line. Lines of other files start with a comment naming the file
+func (p *Program) WriteListing(w io.Writer) error {
+	file := ""
+	for i, st := range p.statements {
+		if st.file != file {
+			if i > 0 {
+				if _, err := fmt.Fprintf(w, "; %s\n", st.file); err != nil {
+					return err
+				}
+			}
+			file = st.file
+		}
+
+		// Up to 8 bytes a line, the rest on following lines
+		data := st.data
+		address, bytes := "", ""
+		if len(data) > 0 || st.label != "" {
+			address = fmt.Sprintf("%#04x", st.address)
+		}
+		if len(data) > 8 {
+			data = data[:8]
+		}
+		bytes = fmt.Sprintf("%X", data)
+
+		line := fmt.Sprintf("%-6s  %-16s  %s", address, bytes, st.source)
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " \t")); err != nil {
+			return err
+		}
+		for j := 8; j < len(st.data); j += 8 {
+			end := j + 8
+			if end > len(st.data) {
+				end = len(st.data)
+			}
+			if _, err := fmt.Fprintf(w, "%#04x  %X\n", st.address+j, st.data[j:end]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
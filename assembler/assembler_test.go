@@ -0,0 +1,183 @@
+package assembler
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// files returns a readFile function reading from a map of sources
+func files(sources map[string]string) func(string) ([]byte, error) {
+	return func(path string) ([]byte, error) {
+		src, ok := sources[path]
+		if !ok {
+			return nil, fmt.Errorf("open %s: %w", path, os.ErrNotExist)
+		}
+		return []byte(src), nil
+	}
+}
+
+// assemble assembles a single source file
+func assemble(src string) (*Program, error) {
+	return Assemble("test.asm", files(map[string]string{"test.asm": src}))
+}
+
+func TestAssemble(t *testing.T) {
+	p, err := Assemble("game.asm", files(map[string]string{
+		"game.asm": `; A test program
+height = 2
+rows EQU height * 2 - 1
+
+main:   LD I, sprite            ; comment, with a comma
+        LD V0, 'A'
+        ld v1, -1
+        DRW V0, V1, rows
+loop:   ADD V0, (1 << 2) | 1
+        SE V0, #1F
+        JP loop
+        CALL sub
+        JP $
+        INCLUDE "lib/sub.asm"
+sprite: DB 0x80, 0b01000001, "hi"
+        DW end - main, -2
+end:
+`,
+		"lib/sub.asm": `sub:    LD I, LONG 0x1234
+        RET
+`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		0xA2, 0x18, // LD I, sprite
+		0x60, 0x41, // LD V0, 'A'
+		0x61, 0xFF, // LD V1, -1
+		0xD0, 0x13, // DRW V0, V1, rows
+		0x70, 0x05, // ADD V0, 5
+		0x30, 0x1F, // SE V0, #1F
+		0x12, 0x08, // JP loop
+		0x22, 0x12, // CALL sub
+		0x12, 0x10, // JP $
+		0xF0, 0x00, 0x12, 0x34, // LD I, LONG 0x1234
+		0x00, 0xEE, // RET
+		0x80, 0x41, 'h', 'i', // DB
+		0x00, 0x20, 0xFF, 0xFE, // DW
+	}
+	if !bytes.Equal(p.ROM, expected) {
+		t.Errorf("expected\n% X\nactually\n% X", expected, p.ROM)
+	}
+
+	for name, address := range map[string]uint16{"main": 0x200, "loop": 0x208, "sub": 0x212, "sprite": 0x218, "end": 0x220} {
+		if p.Symbols.Labels[name] != address {
+			t.Errorf("expected %s at %#04x, actually %#04x", name, address, p.Symbols.Labels[name])
+		}
+	}
+	if _, ok := p.Symbols.Labels["height"]; ok {
+		t.Error("expected constants not to be labels")
+	}
+
+	if line, ok := p.Symbols.Address("lib/sub.asm", 2); !ok || line.Address != 0x216 {
+		t.Errorf("expected lib/sub.asm:2 at 0x216, actually %+v", line)
+	}
+	if line, ok := p.Symbols.Line(0x218); ok {
+		t.Errorf("expected no line for data, actually %+v", line)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for src, expected := range map[string]string{
+		"  LD V0, missing":          "test.asm:1:10: undefined: missing",
+		"\n  ADD V0, 256":           "test.asm:2:11: 256 out of range -128 to 255",
+		"  DRW V0, VG, 1":           "test.asm:1:11: expected a register V0 to VF, found \"VG\"",
+		"  FOO V0":                  "test.asm:1:3: unknown instruction FOO",
+		"  CLS V0":                  "test.asm:1:3: CLS expects no operands",
+		"  LD K, V0":                "test.asm:1:3: invalid operands for LD",
+		"  JP 1 +":                  "test.asm:1:9: unexpected end of expression",
+		"  SE V0, (1":               "test.asm:1:12: expected )",
+		"  LD V0, 0x1G":             "test.asm:1:10: invalid number \"0x1G\"",
+		"a:\na:":                    "test.asm:2:1: a is already defined at test.asm:1",
+		"I: CLS":                    "test.asm:1:1: I is reserved",
+		"x = y\ny = x\n  LD V0, x":  "test.asm:2:5: x: constant refers to itself",
+		"  INCLUDE \"missing.asm\"": "test.asm:1:11: open missing.asm: file does not exist",
+		"  SAVE V1, V2":             "test.asm:1:3: SAVE expects 1 operand",
+		"  SAVE V1 - 3":             "test.asm:1:13: expected a register V0 to VF, found \"3\"",
+	} {
+		_, err := assemble(src)
+		if err == nil {
+			t.Errorf("%q: expected error %q", src, expected)
+			continue
+		}
+		if err.Error() != expected {
+			t.Errorf("%q: expected error %q, actually %q", src, expected, err.Error())
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	_, err := assemble("  LD V0, a\n  LD V1, b\n  JP c")
+	list, ok := err.(ErrorList)
+	if !ok || len(list) != 3 || list[2].Line != 3 || list[2].Column != 6 {
+		t.Errorf("expected 3 errors, actually %v", err)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	_, err := Assemble("a.asm", files(map[string]string{
+		"a.asm": `INCLUDE "b.asm"`,
+		"b.asm": `INCLUDE "a.asm"`,
+	}))
+	if err == nil || err.Error() != "b.asm:1:9: a.asm includes itself" {
+		t.Errorf("expected an include cycle error, actually %v", err)
+	}
+}
+
+// Every instruction the disassembler prints assembles back to its opcode
+func TestDisassemblerMnemonics(t *testing.T) {
+	for op := 0; op <= 0xFFFF; op++ {
+		mnemonic := disassembler.Mnemonic(uint16(op))
+		if strings.HasPrefix(mnemonic, "DW") || op == 0xF000 {
+			continue
+		}
+
+		p, err := assemble("  " + mnemonic)
+		if err != nil {
+			t.Fatalf("%#04x %s: %v", op, mnemonic, err)
+		}
+		if expected := []byte{byte(op >> 8), byte(op)}; !bytes.Equal(p.ROM, expected) {
+			t.Fatalf("%s: expected % X, actually % X", mnemonic, expected, p.ROM)
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	p, err := Assemble("game.asm", files(map[string]string{
+		"game.asm": "; Draw\nstart:  LD I, data\n        INCLUDE \"data.asm\"\n        JP start\n",
+		"data.asm": "data:   DB 1, 2, 3, 4, 5, 6, 7, 8, 9\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.WriteListing(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `                          ; Draw
+0x0200  A202              start:  LD I, data
+                                  INCLUDE "data.asm"
+; data.asm
+0x0202  0102030405060708  data:   DB 1, 2, 3, 4, 5, 6, 7, 8, 9
+0x020a  09
+; game.asm
+0x020b  1200                      JP start
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nactually\n%s", expected, buf)
+	}
+}
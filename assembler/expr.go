@@ -0,0 +1,245 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// token is a lexical token of an expression
+type token struct {
+	text string
+	col  int // column in the source line, from 1
+}
+
+// binaryOperators by precedence, lowest first
+var binaryOperators = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// tokenize splits an expression starting at column col into tokens
+func tokenize(s string, col int) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+		case isIdentChar(c) || c == '#':
+			// Numbers, names and Chipper's #hex
+			i++
+			for i < len(s) && isIdentChar(s[i]) {
+				i++
+			}
+		case c == '\'':
+			// Character literal
+			if i+2 >= len(s) || s[i+2] != '\'' {
+				return nil, &columnError{col + i, "unterminated character literal"}
+			}
+			i += 3
+		case c == '<' || c == '>':
+			if i+1 >= len(s) || s[i+1] != c {
+				return nil, &columnError{col + i, fmt.Sprintf("unexpected %q, expected %c%c", c, c, c)}
+			}
+			i += 2
+		case strings.IndexByte("+-*/%&|^~()$", c) >= 0:
+			i++
+		default:
+			return nil, &columnError{col + i, fmt.Sprintf("unexpected %q in expression", c)}
+		}
+		tokens = append(tokens, token{s[start:i], col + start})
+	}
+	return tokens, nil
+}
+
+// isIdentChar returns true if c can be part of a name or number
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// columnError is an error at a column of the current line
+type columnError struct {
+	col int
+	msg string
+}
+
+func (e *columnError) Error() string {
+	return e.msg
+}
+
+// parser evaluates an expression with recursive descent
+type parser struct {
+	tokens []token
+	pos    int
+	end    int // column after the expression, for errors at its end
+
+	// symbol returns the value of a name
+	symbol func(name string, col int) (int, error)
+
+	// here is the address of the statement, the value of $
+	here int
+}
+
+// evaluate returns the value of the expression s starting at column col
+func evaluate(s string, col int, here int, symbol func(string, int) (int, error)) (int, error) {
+	tokens, err := tokenize(s, col)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, &columnError{col, "expected an expression"}
+	}
+
+	p := &parser{tokens: tokens, end: col + len(s), symbol: symbol, here: here}
+	v, err := p.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.tokens) {
+		return 0, &columnError{p.tokens[p.pos].col, fmt.Sprintf("unexpected %q", p.tokens[p.pos].text)}
+	}
+	return v, nil
+}
+
+// peek returns the next token, or an empty token at the end
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{col: p.end}
+}
+
+// binary parses operators of a precedence level and above
+func (p *parser) binary(level int) (int, error) {
+	if level == len(binaryOperators) {
+		return p.unary()
+	}
+
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if !contains(binaryOperators[level], op.text) {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+
+		switch op.text {
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "&":
+			left &= right
+		case "<<", ">>":
+			if right < 0 || right > 31 {
+				return 0, &columnError{op.col, fmt.Sprintf("shift by %d out of range", right)}
+			}
+			if op.text == "<<" {
+				left <<= right
+			} else {
+				left >>= right
+			}
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/", "%":
+			if right == 0 {
+				return 0, &columnError{op.col, "division by zero"}
+			}
+			if op.text == "/" {
+				left /= right
+			} else {
+				left %= right
+			}
+		}
+	}
+}
+
+// unary parses unary operators, parentheses and values
+func (p *parser) unary() (int, error) {
+	t := p.peek()
+	if t.text == "" {
+		return 0, &columnError{t.col, "unexpected end of expression"}
+	}
+	p.pos++
+
+	switch t.text {
+	case "-", "+", "~":
+		v, err := p.unary()
+		switch t.text {
+		case "-":
+			v = -v
+		case "~":
+			v = ^v
+		}
+		return v, err
+	case "(":
+		v, err := p.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		if closing := p.peek(); closing.text != ")" {
+			return 0, &columnError{closing.col, "expected )"}
+		}
+		p.pos++
+		return v, nil
+	case "$":
+		return p.here, nil
+	}
+
+	switch c := t.text[0]; {
+	case c == '\'':
+		return int(t.text[1]), nil
+	case c == '#':
+		return parseNumber(t.text[1:], 16, t)
+	case c >= '0' && c <= '9':
+		lower := strings.ToLower(t.text)
+		switch {
+		case strings.HasPrefix(lower, "0x"):
+			return parseNumber(t.text[2:], 16, t)
+		case strings.HasPrefix(lower, "0b"):
+			return parseNumber(t.text[2:], 2, t)
+		}
+		return parseNumber(t.text, 10, t)
+	case isIdentChar(c):
+		return p.symbol(t.text, t.col)
+	}
+	return 0, &columnError{t.col, fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// parseNumber parses the digits of t in base
+func parseNumber(digits string, base int, t token) (int, error) {
+	v, err := strconv.ParseInt(digits, base, 32)
+	if err != nil {
+		return 0, &columnError{t.col, fmt.Sprintf("invalid number %q", t.text)}
+	}
+	return int(v), nil
+}
+
+// contains returns true if s is one of list
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Instructions without operands
+var noOperands = map[string]uint16{
+	"CLS":   0x00E0,
+	"RET":   0x00EE,
+	"SCR":   0x00FB,
+	"SCL":   0x00FC,
+	"EXIT":  0x00FD,
+	"LOW":   0x00FE,
+	"HIGH":  0x00FF,
+	"AUDIO": 0xF002,
+}
+
+// 8xyn instructions with two registers, by n
+var aluInstructions = map[string]uint16{
+	"OR":   0x1,
+	"AND":  0x2,
+	"XOR":  0x3,
+	"SUB":  0x5,
+	"SHR":  0x6,
+	"SUBN": 0x7,
+	"SHL":  0xE,
+}
+
+// Fxkk instructions with a single register, by kk
+var registerInstructions = map[string]uint16{
+	"SKP":   0xE09E,
+	"SKNP":  0xE0A1,
+	"PITCH": 0xF03A,
+}
+
+// LD kk to Vx from a special operand, and from Vx to one, by kk
+var (
+	loadsFrom = map[string]uint16{"DT": 0x07, "K": 0x0A, "[I]": 0x65, "R": 0x85}
+	storesTo  = map[string]uint16{"DT": 0x15, "ST": 0x18, "F": 0x29, "HF": 0x30, "B": 0x33, "[I]": 0x55, "R": 0x75}
+)
+
+// register returns the number of a V register operand
+func register(o operand) (uint16, bool) {
+	if !isRegister(o.text) {
+		return 0, false
+	}
+	c := o.text[1] | 0x20
+	if c >= 'a' {
+		return uint16(c-'a') + 10, true
+	}
+	return uint16(c - '0'), true
+}
+
+// isRegister returns true if s is a V register, V0 to VF
+func isRegister(s string) bool {
+	if len(s) != 2 || s[0]|0x20 != 'v' {
+		return false
+	}
+	c := s[1] | 0x20
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f'
+}
+
+// keyword returns an operand upper case without spaces, to match I, DT, [I] etc.
+func keyword(o operand) string {
+	return strings.ToUpper(strings.ReplaceAll(o.text, " ", ""))
+}
+
+// instruction returns the opcode of the instruction st, and the address
+// following F000 long loads or -1, recording an error and returning false
+// if it's invalid
+func (a *assembler) instruction(st *statement) (op uint16, long int, ok bool) {
+	ops := st.operands
+	long = -1
+
+	// count checks the number of operands is between min and max
+	count := func(min, max int) bool {
+		if len(ops) < min || len(ops) > max {
+			expected := "no operands"
+			switch {
+			case min != max:
+				expected = "1 or 2 operands"
+			case min == 1:
+				expected = "1 operand"
+			case min > 1:
+				expected = fmt.Sprintf("%d operands", min)
+			}
+			a.errorf(st, st.nameCol, "%s expects %s", st.name, expected)
+			return false
+		}
+		return true
+	}
+
+	// value returns an operand between 0 and max, allowing negative bytes
+	value := func(o operand, max int) (uint16, bool) {
+		min := 0
+		if max == 0xFF {
+			min = -0x80
+		}
+		v, ok := a.value(st, o, min, max)
+		return uint16(v) & uint16(max), ok
+	}
+
+	// vx returns a register operand, recording an error if it isn't one
+	vx := func(o operand) (uint16, bool) {
+		x, ok := register(o)
+		if !ok {
+			a.errorf(st, o.col, "expected a register V0 to VF, found %q", o.text)
+		}
+		return x, ok
+	}
+
+	invalid := func() (uint16, int, bool) {
+		a.errorf(st, st.nameCol, "invalid operands for %s", st.name)
+		return 0, -1, false
+	}
+
+	if op, found := noOperands[st.name]; found {
+		return op, long, count(0, 0)
+	}
+
+	switch st.name {
+	case "SCD", "SCU":
+		if !count(1, 1) {
+			return 0, long, false
+		}
+		n, ok := value(ops[0], 0xF)
+		if st.name == "SCU" {
+			return 0x00D0 | n, long, ok
+		}
+		return 0x00C0 | n, long, ok
+
+	case "SYS", "CALL":
+		if !count(1, 1) {
+			return 0, long, false
+		}
+		nnn, ok := value(ops[0], 0xFFF)
+		if st.name == "CALL" {
+			return 0x2000 | nnn, long, ok
+		}
+		return nnn, long, ok
+
+	case "JP":
+		if !count(1, 2) {
+			return 0, long, false
+		}
+		if len(ops) == 2 {
+			if x, ok := register(ops[0]); !ok || x != 0 {
+				a.errorf(st, ops[0].col, "JP with 2 operands expects V0, found %q", ops[0].text)
+				return 0, long, false
+			}
+			nnn, ok := value(ops[1], 0xFFF)
+			return 0xB000 | nnn, long, ok
+		}
+		nnn, ok := value(ops[0], 0xFFF)
+		return 0x1000 | nnn, long, ok
+
+	case "SE", "SNE":
+		if !count(2, 2) {
+			return 0, long, false
+		}
+		x, ok := vx(ops[0])
+		if !ok {
+			return 0, long, false
+		}
+		if y, isReg := register(ops[1]); isReg {
+			if st.name == "SE" {
+				return 0x5000 | x<<8 | y<<4, long, true
+			}
+			return 0x9000 | x<<8 | y<<4, long, true
+		}
+		kk, ok := value(ops[1], 0xFF)
+		if st.name == "SE" {
+			return 0x3000 | x<<8 | kk, long, ok
+		}
+		return 0x4000 | x<<8 | kk, long, ok
+
+	case "SAVE", "LOAD":
+		if !count(1, 1) {
+			return 0, long, false
+		}
+		// A range of registers, Vx - Vy
+		first, last, found := strings.Cut(ops[0].text, "-")
+		if !found {
+			a.errorf(st, ops[0].col, "%s expects a range of registers, Vx - Vy", st.name)
+			return 0, long, false
+		}
+		x, ok := vx(operand{strings.TrimSpace(first), ops[0].col})
+		if !ok {
+			return 0, long, false
+		}
+		lastCol := ops[0].col + len(first) + 1 + len(last) - len(strings.TrimLeft(last, " \t"))
+		y, ok := vx(operand{strings.TrimSpace(last), lastCol})
+		if st.name == "SAVE" {
+			return 0x5002 | x<<8 | y<<4, long, ok
+		}
+		return 0x5003 | x<<8 | y<<4, long, ok
+
+	case "LD":
+		if !count(2, 2) {
+			return 0, long, false
+		}
+		dst, src := ops[0], ops[1]
+
+		if x, ok := register(dst); ok {
+			if y, ok := register(src); ok {
+				return 0x8000 | x<<8 | y<<4, long, true
+			}
+			if kk, ok := loadsFrom[keyword(src)]; ok {
+				return 0xF000 | x<<8 | kk, long, true
+			}
+			kk, ok := value(src, 0xFF)
+			return 0x6000 | x<<8 | kk, long, ok
+		}
+
+		if keyword(dst) == "I" {
+			if hasPrefixFold(src.text, "LONG ") {
+				text := strings.TrimSpace(src.text[len("LONG "):])
+				v, ok := a.value(st, operand{text, src.col + len(src.text) - len(text)}, 0, 0xFFFF)
+				return 0xF000, v, ok
+			}
+			nnn, ok := value(src, 0xFFF)
+			return 0xA000 | nnn, long, ok
+		}
+
+		if kk, ok := storesTo[keyword(dst)]; ok {
+			x, ok := vx(src)
+			return 0xF000 | x<<8 | kk, long, ok
+		}
+		return invalid()
+
+	case "ADD":
+		if !count(2, 2) {
+			return 0, long, false
+		}
+		if keyword(ops[0]) == "I" {
+			x, ok := vx(ops[1])
+			return 0xF01E | x<<8, long, ok
+		}
+		x, ok := vx(ops[0])
+		if !ok {
+			return 0, long, false
+		}
+		if y, ok := register(ops[1]); ok {
+			return 0x8004 | x<<8 | y<<4, long, true
+		}
+		kk, ok := value(ops[1], 0xFF)
+		return 0x7000 | x<<8 | kk, long, ok
+
+	case "OR", "AND", "XOR", "SUB", "SUBN", "SHR", "SHL":
+		// Shifts can leave out Vy, shifting Vx in place with either quirk
+		min := 2
+		if st.name == "SHR" || st.name == "SHL" {
+			min = 1
+		}
+		if !count(min, 2) {
+			return 0, long, false
+		}
+		x, ok := vx(ops[0])
+		y := x
+		if ok && len(ops) == 2 {
+			y, ok = vx(ops[1])
+		}
+		return 0x8000 | x<<8 | y<<4 | aluInstructions[st.name], long, ok
+
+	case "RND":
+		if !count(2, 2) {
+			return 0, long, false
+		}
+		x, ok := vx(ops[0])
+		if !ok {
+			return 0, long, false
+		}
+		kk, ok := value(ops[1], 0xFF)
+		return 0xC000 | x<<8 | kk, long, ok
+
+	case "DRW":
+		if !count(3, 3) {
+			return 0, long, false
+		}
+		x, ok := vx(ops[0])
+		if !ok {
+			return 0, long, false
+		}
+		y, ok := vx(ops[1])
+		if !ok {
+			return 0, long, false
+		}
+		n, ok := value(ops[2], 0xF)
+		return 0xD000 | x<<8 | y<<4 | n, long, ok
+
+	case "SKP", "SKNP", "PITCH":
+		if !count(1, 1) {
+			return 0, long, false
+		}
+		x, ok := vx(ops[0])
+		return registerInstructions[st.name] | x<<8, long, ok
+
+	case "PLANE":
+		if !count(1, 1) {
+			return 0, long, false
+		}
+		n, ok := value(ops[0], 0xF)
+		return 0xF001 | n<<8, long, ok
+	}
+
+	a.errorf(st, st.nameCol, "unknown instruction %s", st.name)
+	return 0, long, false
+}
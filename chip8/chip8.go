@@ -1,7 +1,12 @@
 package chip8
 
+import (
+	"math"
+	"time"
+)
+
 const (
-	MemorySize     = 4096
+	MemorySize     = 0x10000 // XO-CHIP addressable memory (64KB)
 	StackSize      = 16
 	VRegisterCount = 16
 	KeyCount       = 16
@@ -10,11 +15,30 @@ const (
 	DisplayWidth  = 64
 	DisplayHeight = 32
 
+	// SUPER-CHIP high resolution
+	HiResDisplayWidth  = 128
+	HiResDisplayHeight = 64
+
+	// Number of RPL user flags available to Fx75/Fx85
+	RPLFlagCount = 16
+
+	// XO-CHIP display bitplanes, each pixel can be one of 4 colours
+	PlaneCount = 2
+
+	// Size in bytes of the XO-CHIP audio pattern buffer, played one bit per sample
+	AudioPatternSize = 16
+
 	// Starting memory address where fonts are loaded
 	fontStartAddress = 0x000
 
+	// Starting memory address where the SUPER-CHIP large font is loaded, after the small font
+	bigFontStartAddress = 0x050 // (80)
+
 	// Starting memory address where roms are loaded
-	programStartAddress = 0x200 // (512)
+	ProgramStartAddress = 0x200 // (512)
+
+	// Audio pitch giving a playback rate of 4000 samples per second
+	defaultPitch = 64
 )
 
 var (
@@ -37,6 +61,33 @@ var (
 		0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
 		0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 	}
+
+	// Square wave played when a program has not loaded an audio pattern,
+	// at the default pitch this is a 500hz tone
+	defaultAudioPattern = [AudioPatternSize]byte{
+		0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0,
+		0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0,
+	}
+
+	// Sprite data for SUPER-CHIP large (8x10) hexidecimal character set
+	bigFontData = [...]byte{
+		0xFF, 0xFF, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, // 0
+		0x18, 0x78, 0x78, 0x18, 0x18, 0x18, 0x18, 0x18, 0xFF, 0xFF, // 1
+		0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // 2
+		0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 3
+		0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0x03, 0x03, // 4
+		0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 5
+		0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 6
+		0xFF, 0xFF, 0x03, 0x03, 0x06, 0x0C, 0x18, 0x18, 0x18, 0x18, // 7
+		0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 8
+		0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 9
+		0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+		0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+		0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+		0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+		0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+		0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+	}
 )
 
 type Chip8 struct {
@@ -46,9 +97,19 @@ type Chip8 struct {
 	stack  [StackSize]uint16
 
 	v [VRegisterCount]byte // V registers (including carry v[0xF])
-	i uint16               // I register, used for storing memory addresses, only lower 12 bits are used
+	i uint16               // I register, used for storing memory addresses
+
+	display [HiResDisplayWidth][HiResDisplayHeight]byte // display, bit n is set if pixel is on in plane n
+	hires   bool                                        // true when in SUPER-CHIP 128x64 mode
+	planes  byte                                        // XO-CHIP bitmask of planes selected for drawing
+
+	audioPattern [AudioPatternSize]byte // XO-CHIP 1-bit audio samples, played while the sound timer is active
+	pitch        byte                   // XO-CHIP audio playback rate, 64 is 4000 samples per second
 
-	display [DisplayWidth][DisplayHeight]byte // display, 1 if pixel is on, 0 if not
+	rpl [RPLFlagCount]byte // SUPER-CHIP RPL user flags, persist across Reset
+
+	// exited is true once the program has executed 00FD
+	exited bool
 
 	// DrawFlag is true when the display has been updated and should be rendered
 	DrawFlag bool
@@ -60,12 +121,23 @@ type Chip8 struct {
 	sound, delay byte // timers, counting down at 60 hz
 
 	rand RandSource // source of random byte used in an instruction
+
+	quirks           Quirks // interpreter behaviours for ambiguous instructions
+	waitingForVBlank bool   // execution should be paused until the next call to UpdateTimers
+
+	cycles int // COSMAC VIP machine cycles left to run, negative when overspent
+
+	unknownOpcodePolicy UnknownOpcodePolicy   // how unknown opcodes are handled by Step
+	unknownOpcodeTrap   UnknownOpcodeTrapFunc // called for unknown opcodes with UnknownOpcodeTrap
+
+	hooks []*Hooks // registered by AddHooks, nil without any
 }
 
-// New creates a new Chip 8, reading the program file from file at filename
-func New(program []byte) *Chip8 {
+// New creates a new Chip 8 with program loaded, behaving according to quirks
+func New(program []byte, quirks Quirks) *Chip8 {
 	c8 := &Chip8{
-		rand: Rand{},
+		rand:   NewSeededRand(time.Now().UnixNano()),
+		quirks: quirks,
 	}
 
 	c8.Reset()
@@ -83,7 +155,7 @@ func (c *Chip8) Reset() {
 	}
 
 	// Clear registers
-	c.pc = programStartAddress
+	c.pc = ProgramStartAddress
 	c.sp = 0
 	c.i = 0
 	for i := 0; i < len(c.v); i++ {
@@ -96,12 +168,11 @@ func (c *Chip8) Reset() {
 	}
 
 	// Clear display
-	for x := 0; x < DisplayWidth; x++ {
-		for y := 0; y < DisplayHeight; y++ {
-			c.display[x][y] = 0
-		}
-	}
+	c.display = [HiResDisplayWidth][HiResDisplayHeight]byte{}
+	c.planes = 1
+	c.hires = false
 	c.DrawFlag = false
+	c.exited = false
 
 	// Keyboard
 	for i := 0; i < len(c.keys); i++ {
@@ -109,22 +180,32 @@ func (c *Chip8) Reset() {
 	}
 	c.waitingKeyRegister = -1
 	c.waitingForKey = false
+	c.waitingForVBlank = false
 
 	// Timers
 	c.delay = 0
 	c.sound = 0
+
+	// Audio
+	c.audioPattern = defaultAudioPattern
+	c.pitch = defaultPitch
+
+	c.cycles = 0
 }
 
 // LoadProgram loads program and font data into memory
 func (c *Chip8) LoadProgram(program []byte) {
 	// Load program into memory
 	for i := 0; i < len(program); i++ {
-		c.memory[programStartAddress+i] = program[i]
+		c.memory[ProgramStartAddress+i] = program[i]
 	}
-	// Load font into memory
+	// Load fonts into memory
 	for i := 0; i < len(fontData); i++ {
 		c.memory[fontStartAddress+i] = fontData[i]
 	}
+	for i := 0; i < len(bigFontData); i++ {
+		c.memory[bigFontStartAddress+i] = bigFontData[i]
+	}
 }
 
 func (c *Chip8) PressKey(key Key) {
@@ -136,6 +217,9 @@ func (c *Chip8) PressKey(key Key) {
 	if c.waitingForKey {
 		c.waitingForKey = false
 		c.v[c.waitingKeyRegister] = byte(key)
+		if len(c.hooks) > 0 {
+			c.hookKeyWait(int(c.waitingKeyRegister), false)
+		}
 		c.waitingKeyRegister = -1
 	}
 
@@ -147,28 +231,89 @@ func (c *Chip8) DePressKey(key Key) {
 }
 
 // Step emulates the execution of a single instruction
-// returns true if an instruction was actually executed
-func (c *Chip8) Step() bool {
-	// Check if PC is in bounds
-	if c.pc < programStartAddress || c.pc > MemorySize-1 {
-		panic("pc is at invalid address")
+// returns true if an instruction was actually executed, if execution
+// fails an *Error is returned and PC is left at the failed instruction
+func (c *Chip8) Step() (bool, error) {
+	// Check if PC is in bounds, opcodes are 2 bytes
+	if c.pc < ProgramStartAddress || int(c.pc) > MemorySize-2 {
+		return false, &Error{PC: c.pc, Err: ErrInvalidPC}
 	}
 
-	// Check if waiting for a key
-	if c.waitingForKey {
-		return false
+	if c.paused() {
+		return false, nil
 	}
 
 	// Get the opcode from program memory
+	pc := c.pc
 	op := GetOpcode(c.memory[c.pc], c.memory[c.pc+1])
 
 	c.pc += 2
 
 	instruction := DecodeOpcode(op)
 
-	instruction.implementation(op, c)
+	if len(c.hooks) > 0 {
+		c.hookInstruction(pc, instruction, false)
+	}
+
+	if err := instruction.implementation(op, c); err != nil {
+		c.pc = pc
+		return false, &Error{PC: pc, Opcode: op, Err: err}
+	}
+
+	if len(c.hooks) > 0 {
+		c.hookInstruction(pc, instruction, true)
+	}
+
+	return true, nil
+}
+
+// paused returns true while waiting for a key or the display, or once the
+// program has exited
+func (c *Chip8) paused() bool {
+	return c.waitingForKey || c.waitingForVBlank || c.exited
+}
+
+// SetUnknownOpcodePolicy sets how Step handles opcodes which can't be
+// decoded, trap is only used with UnknownOpcodeTrap
+func (c *Chip8) SetUnknownOpcodePolicy(policy UnknownOpcodePolicy, trap UnknownOpcodeTrapFunc) {
+	c.unknownOpcodePolicy = policy
+	c.unknownOpcodeTrap = trap
+}
 
-	return true
+// unknownOpcode applies the unknown opcode policy to op
+func (c *Chip8) unknownOpcode(op uint16) error {
+	switch c.unknownOpcodePolicy {
+	case UnknownOpcodeHalt:
+		return ErrUnknownOpcode
+	case UnknownOpcodeTrap:
+		if c.unknownOpcodeTrap != nil {
+			// PC has already been incremented past the opcode
+			return c.unknownOpcodeTrap(c.pc-2, op)
+		}
+	}
+	return nil
+}
+
+// skipInstruction increments PC past the next instruction, XO-CHIP
+// long loads (F000 nnnn) are 4 bytes long
+func (c *Chip8) skipInstruction() {
+	if GetOpcode(c.memory[c.pc], c.memory[c.pc+1]) == 0xF000 {
+		c.pc += 4
+	} else {
+		c.pc += 2
+	}
+}
+
+// AudioPattern returns the 1-bit audio samples which should be played, most
+// significant bit first, while ShouldBuzz returns true
+func (c *Chip8) AudioPattern() [AudioPatternSize]byte {
+	return c.audioPattern
+}
+
+// AudioSampleRate returns the rate in samples (bits) per second at which the
+// audio pattern should be played
+func (c *Chip8) AudioSampleRate() float64 {
+	return 4000 * math.Pow(2, (float64(c.pitch)-defaultPitch)/48)
 }
 
 // ShouldBuzz returns true if the sound timer is greater than 0, in
@@ -181,6 +326,14 @@ func (c *Chip8) ShouldBuzz() bool {
 // UpdateTimers will decrement both the sound and delay timers given
 // that the Chip 8 is not currently waiting for a key press
 func (c *Chip8) UpdateTimers() {
+	// A vertical blank has occurred
+	c.waitingForVBlank = false
+
+	// Random sources such as VIPRand advance with the interrupt
+	if t, ok := c.rand.(interface{ Tick() }); ok {
+		t.Tick()
+	}
+
 	// Check if execution is paused for key press
 	if c.waitingForKey {
 		return
@@ -191,9 +344,113 @@ func (c *Chip8) UpdateTimers() {
 	}
 	if c.sound > 0 {
 		c.sound--
+		if c.sound == 0 && len(c.hooks) > 0 {
+			c.hookBuzzer(false)
+		}
 	}
 }
 
-func (c *Chip8) Display() [DisplayWidth][DisplayHeight]byte {
+// Display returns the display buffer, only the area given by Resolution
+// is in use. Each pixel is a bitmask of the planes it is on in, 1 for
+// the first plane and 2 for the second, giving 4 possible colours
+func (c *Chip8) Display() [HiResDisplayWidth][HiResDisplayHeight]byte {
 	return c.display
 }
+
+// Resolution returns the width and height of the display in the current mode
+func (c *Chip8) Resolution() (width, height int) {
+	if c.hires {
+		return HiResDisplayWidth, HiResDisplayHeight
+	}
+	return DisplayWidth, DisplayHeight
+}
+
+// Exited returns true when the program has exited with 00FD, Step
+// will not execute any further instructions until Reset
+func (c *Chip8) Exited() bool {
+	return c.exited
+}
+
+// SetRand sets the source of random bytes used by Cxkk, by default
+// a SeededRand seeded with the current time is used
+func (c *Chip8) SetRand(r RandSource) {
+	c.rand = r
+}
+
+// Quirks returns the interpreter behaviours the Chip 8 was created with
+func (c *Chip8) Quirks() Quirks {
+	return c.quirks
+}
+
+// Registers is a copy of the CPU state, returned by Chip8.Registers
+type Registers struct {
+	PC, I uint16
+	SP    uint16 // number of return addresses on the stack
+	V     [VRegisterCount]byte
+	Stack [StackSize]uint16 // return addresses, only the first SP are in use
+
+	Delay, Sound byte // timers
+}
+
+// Registers returns a copy of the CPU state
+func (c *Chip8) Registers() Registers {
+	return Registers{
+		PC:    c.pc,
+		I:     c.i,
+		SP:    c.sp,
+		V:     c.v,
+		Stack: c.stack,
+		Delay: c.delay,
+		Sound: c.sound,
+	}
+}
+
+// SetRegisters restores the CPU state from r, returning ErrStackOverflow
+// if the stack pointer is beyond the stack
+func (c *Chip8) SetRegisters(r Registers) error {
+	if r.SP > StackSize {
+		return ErrStackOverflow
+	}
+
+	c.pc = r.PC
+	c.i = r.I
+	c.sp = r.SP
+	c.v = r.V
+	c.stack = r.Stack
+	c.delay = r.Delay
+	c.sound = r.Sound
+	return nil
+}
+
+// Keys returns the pressed state of each key
+func (c *Chip8) Keys() [KeyCount]bool {
+	return c.keys
+}
+
+// WaitingForKey returns true while execution is paused by Fx0A
+func (c *Chip8) WaitingForKey() bool {
+	return c.waitingForKey
+}
+
+// ReadMemory returns a copy of n bytes of memory from address, returning
+// ErrMemoryOutOfBounds if they extend past the end of memory
+func (c *Chip8) ReadMemory(address uint16, n int) ([]byte, error) {
+	if err := checkMemory(address, n); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, n)
+	copy(data, c.memory[address:])
+	return data, nil
+}
+
+// WriteMemory copies data into memory at address, returning
+// ErrMemoryOutOfBounds without writing if it extends past the end of memory
+func (c *Chip8) WriteMemory(address uint16, data []byte) error {
+	if err := checkMemory(address, len(data)); err != nil {
+		return err
+	}
+
+	copy(c.memory[address:], data)
+	return nil
+}
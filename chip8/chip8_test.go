@@ -1,19 +1,20 @@
 package chip8
 
 import (
+	"errors"
 	"testing"
 )
 
 func TestPCInitialization(t *testing.T) {
-	c8 := New([]byte{})
+	c8 := New([]byte{}, Quirks{})
 
-	if c8.pc != programStartAddress {
-		t.Errorf("PC should be %#X at initialization", programStartAddress)
+	if c8.pc != ProgramStartAddress {
+		t.Errorf("PC should be %#X at initialization", ProgramStartAddress)
 	}
 }
 
 func TestIncorrectKeyValue(t *testing.T) {
-	c := New([]byte{})
+	c := New([]byte{}, Quirks{})
 
 	// Array bounds would panic if not caught by PressKey
 	// Test by deferring recover
@@ -27,33 +28,25 @@ func TestIncorrectKeyValue(t *testing.T) {
 }
 
 func TestPCTooHigh(t *testing.T) {
-	c := New([]byte{})
+	c := New([]byte{}, Quirks{})
 
 	// Set PC out of range
-	c.pc = MemorySize
+	c.pc = MemorySize - 1
 
-	defer func() {
-		if err := recover(); err == nil {
-			t.Error("Step did not panic as expected")
-		}
-	}()
-
-	c.Step()
+	if _, err := c.Step(); !errors.Is(err, ErrInvalidPC) {
+		t.Errorf("expected ErrInvalidPC, actually %v", err)
+	}
 }
 
 func TestPCTooLow(t *testing.T) {
-	c := New([]byte{})
+	c := New([]byte{}, Quirks{})
 
 	// Set PC out of range
-	c.pc = programStartAddress - 1
-
-	defer func() {
-		if err := recover(); err == nil {
-			t.Error("Step did not panic as expected")
-		}
-	}()
+	c.pc = ProgramStartAddress - 1
 
-	c.Step()
+	if _, err := c.Step(); !errors.Is(err, ErrInvalidPC) {
+		t.Errorf("expected ErrInvalidPC, actually %v", err)
+	}
 }
 
 func TestWaitingForKey(t *testing.T) {
@@ -61,10 +54,10 @@ func TestWaitingForKey(t *testing.T) {
 		0xF2, 0x0A, // Wait for key opcode, store key pressed in V2
 		0x00, 0xE0, // Clear Screen
 		0x00, 0xE0, // Clear Screen
-	})
+	}, Quirks{})
 
 	// Check not initially waiting for key
-	if c.Step() == false {
+	if ok, _ := c.Step(); !ok {
 		t.Error("step returned false when not waiting for key")
 	}
 
@@ -72,7 +65,7 @@ func TestWaitingForKey(t *testing.T) {
 		t.Error("expected waitingForKey to be true")
 	}
 
-	if c.Step() == true {
+	if ok, _ := c.Step(); ok {
 		t.Error("step returned true when waiting for key")
 	}
 
@@ -82,7 +75,150 @@ func TestWaitingForKey(t *testing.T) {
 
 	c.PressKey(KeyA)
 
-	if c.Step() == false {
+	if ok, _ := c.Step(); !ok {
 		t.Error("step returned false when not waiting for key")
 	}
 }
+
+func TestStackOverflow(t *testing.T) {
+	c := New([]byte{
+		0x22, 0x00, // Call self
+	}, Quirks{})
+
+	for i := 0; i < StackSize; i++ {
+		if _, err := c.Step(); err != nil {
+			t.Fatalf("unexpected error before stack is full: %v", err)
+		}
+	}
+
+	_, err := c.Step()
+	if !errors.Is(err, ErrStackOverflow) {
+		t.Fatalf("expected ErrStackOverflow, actually %v", err)
+	}
+
+	var stepErr *Error
+	if !errors.As(err, &stepErr) || stepErr.PC != ProgramStartAddress || stepErr.Opcode != 0x2200 {
+		t.Error("error should carry PC and opcode of the failed instruction")
+	}
+
+	if c.pc != ProgramStartAddress {
+		t.Error("PC should be left at the failed instruction")
+	}
+}
+
+func TestStackUnderflow(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xEE,
+	}, Quirks{})
+
+	if _, err := c.Step(); !errors.Is(err, ErrStackUnderflow) {
+		t.Errorf("expected ErrStackUnderflow, actually %v", err)
+	}
+}
+
+func TestMemoryOutOfBounds(t *testing.T) {
+	c := New([]byte{
+		0xF2, 0x55,
+	}, Quirks{})
+	c.i = MemorySize - 2
+
+	if _, err := c.Step(); !errors.Is(err, ErrMemoryOutOfBounds) {
+		t.Errorf("expected ErrMemoryOutOfBounds, actually %v", err)
+	}
+}
+
+func TestUnknownOpcodePolicy(t *testing.T) {
+	program := []byte{
+		0xE0, 0x00, // Unknown opcode
+	}
+
+	c := New(program, Quirks{})
+	if ok, err := c.Step(); !ok || err != nil {
+		t.Error("unknown opcode should be ignored by default")
+	}
+
+	c = New(program, Quirks{})
+	c.SetUnknownOpcodePolicy(UnknownOpcodeHalt, nil)
+	if _, err := c.Step(); !errors.Is(err, ErrUnknownOpcode) {
+		t.Errorf("expected ErrUnknownOpcode, actually %v", err)
+	}
+
+	trapErr := errors.New("trapped")
+	var trappedPC, trappedOpcode uint16
+	c = New(program, Quirks{})
+	c.SetUnknownOpcodePolicy(UnknownOpcodeTrap, func(pc, opcode uint16) error {
+		trappedPC, trappedOpcode = pc, opcode
+		return trapErr
+	})
+	if _, err := c.Step(); !errors.Is(err, trapErr) {
+		t.Errorf("expected error from trap, actually %v", err)
+	}
+	if trappedPC != ProgramStartAddress || trappedOpcode != 0xE000 {
+		t.Error("trap was not called with PC and opcode")
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	c := New([]byte{
+		0x60, 0x12, // V0 = 0x12
+		0xA3, 0x00, // I = 0x300
+		0x22, 0x08, // Call 0x208
+		0x00, 0x00,
+		0xF0, 0x15, // Delay timer = V0
+	}, Quirks{})
+
+	for i := 0; i < 4; i++ {
+		c.Step()
+	}
+
+	r := c.Registers()
+	if r.PC != 0x20A || r.I != 0x300 || r.SP != 1 || r.Stack[0] != 0x206 || r.V[0] != 0x12 || r.Delay != 0x12 {
+		t.Errorf("unexpected registers %+v", r)
+	}
+}
+
+func TestReadMemory(t *testing.T) {
+	c := New([]byte{0x12, 0x34}, Quirks{})
+
+	data, err := c.ReadMemory(0x200, 2)
+	if err != nil || data[0] != 0x12 || data[1] != 0x34 {
+		t.Errorf("expected 0x12 0x34, actually %v %v", data, err)
+	}
+
+	if _, err := c.ReadMemory(MemorySize-1, 2); err != ErrMemoryOutOfBounds {
+		t.Errorf("expected ErrMemoryOutOfBounds, actually %v", err)
+	}
+}
+
+func TestWriteMemory(t *testing.T) {
+	c := New([]byte{}, Quirks{})
+
+	if err := c.WriteMemory(0x300, []byte{0xAB, 0xCD}); err != nil {
+		t.Fatal(err)
+	}
+	if c.memory[0x300] != 0xAB || c.memory[0x301] != 0xCD {
+		t.Error("expected memory to be written")
+	}
+
+	if err := c.WriteMemory(MemorySize-1, []byte{1, 2}); err != ErrMemoryOutOfBounds || c.memory[MemorySize-1] != 0 {
+		t.Errorf("expected ErrMemoryOutOfBounds without writing, actually %v", err)
+	}
+}
+
+func TestSetRegisters(t *testing.T) {
+	c := New([]byte{}, Quirks{})
+
+	r := c.Registers()
+	r.PC, r.V[3], r.SP, r.Stack[0], r.Sound = 0x300, 7, 1, 0x202, 10
+	if err := c.SetRegisters(r); err != nil {
+		t.Fatal(err)
+	}
+	if c.pc != 0x300 || c.v[3] != 7 || c.sp != 1 || c.stack[0] != 0x202 || !c.ShouldBuzz() {
+		t.Errorf("unexpected registers %+v", c.Registers())
+	}
+
+	r.SP = StackSize + 1
+	if err := c.SetRegisters(r); err != ErrStackOverflow {
+		t.Errorf("expected ErrStackOverflow, actually %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package chip8
+
+// clearDisplay turns off every pixel of the display in the selected planes
+func (c *Chip8) clearDisplay() {
+	for x := 0; x < HiResDisplayWidth; x++ {
+		for y := 0; y < HiResDisplayHeight; y++ {
+			c.display[x][y] &^= c.planes
+		}
+	}
+}
+
+// setResolution switches between low and high resolution, clearing all planes
+func (c *Chip8) setResolution(hires bool) {
+	c.hires = hires
+	c.display = [HiResDisplayWidth][HiResDisplayHeight]byte{}
+	c.DrawFlag = true
+}
+
+// drawSprite XORs a sprite from memory at I onto the display at (startX, startY),
+// sprites are 8 pixels wide with one byte per row or 16 pixels wide with two.
+// When more than one plane is selected the sprite for each plane follows the
+// last in memory. VF is set to 1 if any pixel was turned off
+func (c *Chip8) drawSprite(startX, startY, width, height uint16) error {
+	// Check the sprite for every selected plane is within memory
+	var planes int
+	for plane := byte(0); plane < PlaneCount; plane++ {
+		if c.planes&(1<<plane) != 0 {
+			planes++
+		}
+	}
+	if err := checkMemory(c.i, planes*int(height*width/8)); err != nil {
+		return err
+	}
+	if len(c.hooks) > 0 {
+		c.hookMemoryRead(c.i, planes*int(height*width/8), AccessDraw)
+	}
+
+	w, h := c.Resolution()
+	displayWidth, displayHeight := uint16(w), uint16(h)
+
+	// Starting position always wraps
+	startX %= displayWidth
+	startY %= displayHeight
+
+	// Set VF to 0
+	c.v[0xF] = 0
+
+	bytesPerRow := width / 8
+	address := c.i
+
+	for plane := byte(0); plane < PlaneCount; plane++ {
+		bit := byte(1) << plane
+		if c.planes&bit == 0 {
+			continue
+		}
+
+		// Read sprite and XOR to display
+		var x, y uint16
+		for y = 0; y < height; y++ {
+			var line uint16
+			for b := uint16(0); b < bytesPerRow; b++ {
+				line = line<<8 | uint16(c.memory[address])
+				address++
+			}
+
+			for x = 0; x < width; x++ {
+				xCoord := startX + x
+				yCoord := startY + y
+
+				// Clip or wrap pixels past the edge of the display
+				if xCoord >= displayWidth || yCoord >= displayHeight {
+					if c.quirks.ClipSprites {
+						continue
+					}
+					xCoord %= displayWidth
+					yCoord %= displayHeight
+				}
+
+				if line&(1<<(width-1-x)) != 0 {
+					if c.display[xCoord][yCoord]&bit != 0 {
+						// Coliision, set VF
+						c.v[0xF] = 1
+					}
+
+					c.display[xCoord][yCoord] ^= bit
+				}
+			}
+		}
+	}
+
+	c.DrawFlag = true
+
+	return nil
+}
+
+// scrollDown moves the selected planes down n pixels, blank lines are inserted at the top
+func (c *Chip8) scrollDown(n int) {
+	w, h := c.Resolution()
+	for x := 0; x < w; x++ {
+		for y := h - 1; y >= 0; y-- {
+			var pixel byte
+			if y >= n {
+				pixel = c.display[x][y-n]
+			}
+			c.setPixelPlanes(x, y, pixel)
+		}
+	}
+	c.DrawFlag = true
+}
+
+// scrollUp moves the selected planes up n pixels, blank lines are inserted at the bottom
+func (c *Chip8) scrollUp(n int) {
+	w, h := c.Resolution()
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			var pixel byte
+			if y+n < h {
+				pixel = c.display[x][y+n]
+			}
+			c.setPixelPlanes(x, y, pixel)
+		}
+	}
+	c.DrawFlag = true
+}
+
+// scrollHorizontal moves the selected planes n pixels right, or left if n is negative,
+// blank columns are inserted at the opposite edge
+func (c *Chip8) scrollHorizontal(n int) {
+	w, h := c.Resolution()
+	for y := 0; y < h; y++ {
+		if n > 0 {
+			for x := w - 1; x >= 0; x-- {
+				var pixel byte
+				if x >= n {
+					pixel = c.display[x-n][y]
+				}
+				c.setPixelPlanes(x, y, pixel)
+			}
+		} else {
+			for x := 0; x < w; x++ {
+				var pixel byte
+				if x-n < w {
+					pixel = c.display[x-n][y]
+				}
+				c.setPixelPlanes(x, y, pixel)
+			}
+		}
+	}
+	c.DrawFlag = true
+}
+
+// setPixelPlanes sets the selected planes of the pixel at (x, y) from pixel,
+// leaving other planes unchanged
+func (c *Chip8) setPixelPlanes(x, y int, pixel byte) {
+	c.display[x][y] = c.display[x][y]&^c.planes | pixel&c.planes
+}
@@ -0,0 +1,70 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidPC is returned when the program counter is outside of program memory
+	ErrInvalidPC = errors.New("pc is at invalid address")
+
+	// ErrStackOverflow is returned when a subroutine is called with a full stack
+	ErrStackOverflow = errors.New("stack overflow")
+
+	// ErrStackUnderflow is returned when returning from a subroutine with an empty stack
+	ErrStackUnderflow = errors.New("stack underflow")
+
+	// ErrMemoryOutOfBounds is returned when an instruction accesses memory past MemorySize
+	ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+
+	// ErrUnknownOpcode is returned for unknown opcodes when the policy is UnknownOpcodeHalt
+	ErrUnknownOpcode = errors.New("unknown opcode")
+)
+
+// Error is returned by Step when execution of an instruction fails, the
+// cause can be checked with errors.Is against the Err variables
+type Error struct {
+	// address of the instruction that failed
+	PC uint16
+
+	// opcode of the instruction that failed
+	Opcode uint16
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("chip8: %s at %#04x (opcode %#04x)", e.Err, e.PC, e.Opcode)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// UnknownOpcodePolicy controls how Step handles opcodes which can't be decoded
+type UnknownOpcodePolicy int
+
+const (
+	// UnknownOpcodeIgnore skips unknown opcodes, this is the default
+	UnknownOpcodeIgnore UnknownOpcodePolicy = iota
+
+	// UnknownOpcodeHalt stops execution at unknown opcodes, Step returns ErrUnknownOpcode
+	UnknownOpcodeHalt
+
+	// UnknownOpcodeTrap calls the trap function set with SetUnknownOpcodePolicy,
+	// execution halts if it returns an error
+	UnknownOpcodeTrap
+)
+
+// UnknownOpcodeTrapFunc is called with the address and value of an unknown opcode
+type UnknownOpcodeTrapFunc func(pc, opcode uint16) error
+
+// checkMemory returns ErrMemoryOutOfBounds if n bytes from address are not
+// all within memory
+func checkMemory(address uint16, n int) error {
+	if int(address)+n > MemorySize {
+		return ErrMemoryOutOfBounds
+	}
+	return nil
+}
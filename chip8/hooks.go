@@ -0,0 +1,158 @@
+package chip8
+
+// MemoryAccess identifies the instruction which read or wrote memory
+type MemoryAccess byte
+
+const (
+	AccessOther MemoryAccess = iota // F002 audio patterns and F000 nnnn long loads
+	AccessDraw                      // Dxyn sprite data
+	AccessBCD                       // Fx33
+	AccessStore                     // Fx55 and 5xy2
+	AccessLoad                      // Fx65 and 5xy3
+)
+
+func (a MemoryAccess) String() string {
+	switch a {
+	case AccessDraw:
+		return "draw"
+	case AccessBCD:
+		return "bcd"
+	case AccessStore:
+		return "store"
+	case AccessLoad:
+		return "load"
+	}
+	return "other"
+}
+
+// Hooks are callbacks for observing execution, any may be nil. They are
+// called on the goroutine calling Step or UpdateTimers, and must not call
+// Step themselves
+type Hooks struct {
+	// Called before an instruction at pc executes, and after if it didn't fail
+	BeforeInstruction func(pc uint16, inst Instruction)
+	AfterInstruction  func(pc uint16, inst Instruction)
+
+	// Called for each byte an instruction reads from or writes to memory,
+	// instruction fetches are not included
+	MemoryRead  func(address uint16, value byte, access MemoryAccess)
+	MemoryWrite func(address uint16, value byte, access MemoryAccess)
+
+	// Called when CALL pushes or RET pops a return address, depth is the
+	// number of return addresses on the stack afterwards
+	StackPush func(address uint16, depth int)
+	StackPop  func(address uint16, depth int)
+
+	// Called when Fx15 or Fx18 sets a timer
+	DelayTimerWrite func(value byte)
+	SoundTimerWrite func(value byte)
+
+	// Called when the sound timer becomes active or runs out
+	Buzzer func(on bool)
+
+	// Called when Fx0A starts waiting for a key to store in Vx, and again
+	// with waiting false when a key is pressed
+	KeyWait func(x int, waiting bool)
+}
+
+// AddHooks registers h, which is called for events until removed. Without
+// hooks their cost is a length check at each event
+func (c *Chip8) AddHooks(h *Hooks) {
+	c.hooks = append(c.hooks, h)
+}
+
+// RemoveHooks removes hooks registered by AddHooks
+func (c *Chip8) RemoveHooks(h *Hooks) {
+	for i, registered := range c.hooks {
+		if registered == h {
+			c.hooks = append(c.hooks[:i:i], c.hooks[i+1:]...)
+			break
+		}
+	}
+	if len(c.hooks) == 0 {
+		c.hooks = nil
+	}
+}
+
+// The hook functions below are only called when len(c.hooks) > 0, keeping
+// the check inline where the event happens
+
+func (c *Chip8) hookInstruction(pc uint16, inst Instruction, after bool) {
+	for _, h := range c.hooks {
+		switch {
+		case after && h.AfterInstruction != nil:
+			h.AfterInstruction(pc, inst)
+		case !after && h.BeforeInstruction != nil:
+			h.BeforeInstruction(pc, inst)
+		}
+	}
+}
+
+func (c *Chip8) hookMemoryRead(address uint16, n int, access MemoryAccess) {
+	for _, h := range c.hooks {
+		if h.MemoryRead == nil {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			h.MemoryRead(address+uint16(i), c.memory[address+uint16(i)], access)
+		}
+	}
+}
+
+func (c *Chip8) hookMemoryWrite(address uint16, n int, access MemoryAccess) {
+	for _, h := range c.hooks {
+		if h.MemoryWrite == nil {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			h.MemoryWrite(address+uint16(i), c.memory[address+uint16(i)], access)
+		}
+	}
+}
+
+func (c *Chip8) hookStack(address uint16, push bool) {
+	for _, h := range c.hooks {
+		switch {
+		case push && h.StackPush != nil:
+			h.StackPush(address, int(c.sp))
+		case !push && h.StackPop != nil:
+			h.StackPop(address, int(c.sp))
+		}
+	}
+}
+
+func (c *Chip8) hookDelayTimer() {
+	for _, h := range c.hooks {
+		if h.DelayTimerWrite != nil {
+			h.DelayTimerWrite(c.delay)
+		}
+	}
+}
+
+// hookSoundTimer reports a sound timer write which changed it from old
+func (c *Chip8) hookSoundTimer(old byte) {
+	for _, h := range c.hooks {
+		if h.SoundTimerWrite != nil {
+			h.SoundTimerWrite(c.sound)
+		}
+	}
+	if (old > 0) != (c.sound > 0) {
+		c.hookBuzzer(c.sound > 0)
+	}
+}
+
+func (c *Chip8) hookBuzzer(on bool) {
+	for _, h := range c.hooks {
+		if h.Buzzer != nil {
+			h.Buzzer(on)
+		}
+	}
+}
+
+func (c *Chip8) hookKeyWait(x int, waiting bool) {
+	for _, h := range c.hooks {
+		if h.KeyWait != nil {
+			h.KeyWait(x, waiting)
+		}
+	}
+}
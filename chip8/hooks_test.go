@@ -0,0 +1,164 @@
+package chip8
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordHooks returns hooks appending a line for each event to events
+func recordHooks(events *[]string) *Hooks {
+	add := func(format string, args ...interface{}) {
+		*events = append(*events, fmt.Sprintf(format, args...))
+	}
+	return &Hooks{
+		BeforeInstruction: func(pc uint16, inst Instruction) { add("before %#04x %#04x", pc, inst.Opcode) },
+		AfterInstruction:  func(pc uint16, inst Instruction) { add("after %#04x", pc) },
+		MemoryRead:        func(address uint16, value byte, access MemoryAccess) { add("read %#04x %d %s", address, value, access) },
+		MemoryWrite: func(address uint16, value byte, access MemoryAccess) {
+			add("write %#04x %d %s", address, value, access)
+		},
+		StackPush:       func(address uint16, depth int) { add("push %#04x %d", address, depth) },
+		StackPop:        func(address uint16, depth int) { add("pop %#04x %d", address, depth) },
+		DelayTimerWrite: func(value byte) { add("delay %d", value) },
+		SoundTimerWrite: func(value byte) { add("sound %d", value) },
+		Buzzer:          func(on bool) { add("buzzer %t", on) },
+		KeyWait:         func(x int, waiting bool) { add("key wait V%X %t", x, waiting) },
+	}
+}
+
+func TestHooks(t *testing.T) {
+	c := New([]byte{
+		0x22, 0x06, // 0x200 Call 0x206
+		0xF2, 0x0A, // 0x202 Wait for key in V2
+		0x12, 0x04, // 0x204 Loop
+		0x60, 0x7B, // 0x206 V0 = 123
+		0xA3, 0x00, // 0x208 I = 0x300
+		0xF0, 0x33, // 0x20A BCD of V0 at I
+		0xF1, 0x65, // 0x20C Load V0, V1 from I
+		0xF0, 0x15, // 0x20E Delay = V0
+		0xF1, 0x18, // 0x210 Sound = V1
+		0x00, 0xEE, // 0x212 Return
+	}, Quirks{})
+
+	var events []string
+	c.AddHooks(recordHooks(&events))
+
+	step := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := c.Step(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	step(1)
+	expectEvents(t, &events, "before 0x0200 0x2206", "push 0x0202 1", "after 0x0200")
+
+	step(3)
+	expectEvents(t, &events,
+		"before 0x0206 0x607b", "after 0x0206",
+		"before 0x0208 0xa300", "after 0x0208",
+		"before 0x020a 0xf033", "write 0x0300 1 bcd", "write 0x0301 2 bcd", "write 0x0302 3 bcd", "after 0x020a")
+
+	step(3)
+	expectEvents(t, &events,
+		"before 0x020c 0xf165", "read 0x0300 1 load", "read 0x0301 2 load", "after 0x020c",
+		"before 0x020e 0xf015", "delay 1", "after 0x020e",
+		"before 0x0210 0xf118", "sound 2", "buzzer true", "after 0x0210")
+
+	step(2)
+	expectEvents(t, &events,
+		"before 0x0212 0x00ee", "pop 0x0202 0", "after 0x0212",
+		"before 0x0202 0xf20a", "key wait V2 true", "after 0x0202")
+
+	c.PressKey(KeyA)
+	expectEvents(t, &events, "key wait V2 false")
+
+	c.UpdateTimers()
+	expectEvents(t, &events)
+	c.UpdateTimers()
+	expectEvents(t, &events, "buzzer false")
+}
+
+func TestHooksDraw(t *testing.T) {
+	c := New([]byte{
+		0xA0, 0x00, // I = sprite of 0
+		0xD0, 0x02, // Draw 2 rows at V0, V0
+	}, Quirks{})
+
+	var events []string
+	h := recordHooks(&events)
+	c.AddHooks(h)
+
+	c.Step()
+	events = nil
+	c.Step()
+	expectEvents(t, &events, "before 0x0202 0xd002", "read 0x0000 240 draw", "read 0x0001 144 draw", "after 0x0202")
+
+	c.RemoveHooks(h)
+	c.Step()
+	expectEvents(t, &events)
+}
+
+// expectEvents checks events matches expected, then clears it
+func expectEvents(t *testing.T, events *[]string, expected ...string) {
+	t.Helper()
+	if len(*events) != 0 || len(expected) != 0 {
+		if !reflect.DeepEqual(*events, expected) {
+			t.Errorf("expected events %q, actually %q", expected, *events)
+		}
+	}
+	*events = nil
+}
+
+// benchmarkProgram loops over common instructions, including memory
+// accesses, calls and drawing
+var benchmarkProgram = []byte{
+	0x60, 0x05, // 0x200 V0 = 5
+	0x70, 0x01, // 0x202 V0 += 1
+	0xA3, 0x00, // 0x204 I = 0x300
+	0xF0, 0x33, // 0x206 BCD of V0 at I
+	0xF2, 0x65, // 0x208 Load V0-V2 from I
+	0x22, 0x10, // 0x20A Call 0x210
+	0x12, 0x02, // 0x20C Loop
+	0x00, 0x00, // 0x20E
+	0xA0, 0x00, // 0x210 I = sprite of 0
+	0xD0, 0x15, // 0x212 Draw at V0, V1
+	0x00, 0xEE, // 0x214 Return
+}
+
+func benchmarkStep(b *testing.B, h *Hooks) {
+	c := New(benchmarkProgram, Quirks{})
+	if h != nil {
+		c.AddHooks(h)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Step(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	benchmarkStep(b, nil)
+}
+
+// Hooks which aren't set cost a nil check each
+func BenchmarkStepEmptyHooks(b *testing.B) {
+	benchmarkStep(b, &Hooks{})
+}
+
+func BenchmarkStepAllHooks(b *testing.B) {
+	var n int
+	benchmarkStep(b, &Hooks{
+		BeforeInstruction: func(uint16, Instruction) { n++ },
+		AfterInstruction:  func(uint16, Instruction) { n++ },
+		MemoryRead:        func(uint16, byte, MemoryAccess) { n++ },
+		MemoryWrite:       func(uint16, byte, MemoryAccess) { n++ },
+		StackPush:         func(uint16, int) { n++ },
+		StackPop:          func(uint16, int) { n++ },
+	})
+}
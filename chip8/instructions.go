@@ -9,14 +9,15 @@ type Instruction struct {
 
 	// Execute the Instruction
 	// pc should be incremented before calling to ensure any Jump/Calls are correct
-	implementation func(op uint16, c *Chip8)
+	implementation func(op uint16, c *Chip8) error
 }
 
 var (
 	unknown = Instruction{
 		0xFFFF,
 		"Unknown opcode",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
+			return c.unknownOpcode(op)
 		},
 	}
 )
@@ -78,37 +79,112 @@ func DecodeOpcode(op uint16) Instruction {
 }
 
 func decodeOpcode0(op uint16) Instruction {
-	if op == 0xE0 {
+	// SUPER-CHIP scroll down is identified by the highest 12 bits
+	if op&0xFFF0 == 0x00C0 {
+		return Instruction{
+			op,
+			"Scroll display down n lines when Opcode is 0x00Cn",
+			func(op uint16, c *Chip8) error {
+				c.scrollDown(int(op & 0xF))
+				return nil
+			},
+		}
+	}
+
+	// XO-CHIP scroll up likewise
+	if op&0xFFF0 == 0x00D0 {
+		return Instruction{
+			op,
+			"Scroll display up n lines when Opcode is 0x00Dn",
+			func(op uint16, c *Chip8) error {
+				c.scrollUp(int(op & 0xF))
+				return nil
+			},
+		}
+	}
+
+	switch op {
+	case 0xE0:
 		return Instruction{
 			op,
 			"Clear the screen",
-			func(op uint16, c *Chip8) {
-				for x := 0; x < DisplayWidth; x++ {
-					for y := 0; y < DisplayHeight; y++ {
-						c.display[x][y] = 0
-					}
-				}
+			func(op uint16, c *Chip8) error {
+				c.clearDisplay()
 				c.DrawFlag = true
+				return nil
 			},
 		}
-	} else if op == 0xEE {
+	case 0xEE:
 		return Instruction{
 			op,
 			"Return from a subroutine",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
+				if c.sp == 0 {
+					return ErrStackUnderflow
+				}
 				c.sp--
 				c.pc = c.stack[c.sp]
+				if len(c.hooks) > 0 {
+					c.hookStack(c.pc, false)
+				}
+				return nil
+			},
+		}
+	case 0xFB:
+		return Instruction{
+			op,
+			"Scroll display right 4 pixels",
+			func(op uint16, c *Chip8) error {
+				c.scrollHorizontal(4)
+				return nil
 			},
 		}
-	} else {
+	case 0xFC:
 		return Instruction{
 			op,
-			"Jump to subroutine in lowest 12 bits [IGNORED]",
-			func(op uint16, c *Chip8) {
-				// According to the reference this should be ignored by interpreters
+			"Scroll display left 4 pixels",
+			func(op uint16, c *Chip8) error {
+				c.scrollHorizontal(-4)
+				return nil
+			},
+		}
+	case 0xFD:
+		return Instruction{
+			op,
+			"Exit the interpreter",
+			func(op uint16, c *Chip8) error {
+				c.exited = true
+				return nil
+			},
+		}
+	case 0xFE:
+		return Instruction{
+			op,
+			"Switch to low resolution (64x32) mode",
+			func(op uint16, c *Chip8) error {
+				c.setResolution(false)
+				return nil
+			},
+		}
+	case 0xFF:
+		return Instruction{
+			op,
+			"Switch to high resolution (128x64) mode",
+			func(op uint16, c *Chip8) error {
+				c.setResolution(true)
+				return nil
 			},
 		}
 	}
+
+	return Instruction{
+		op,
+		"Jump to subroutine in lowest 12 bits [IGNORED]",
+		func(op uint16, c *Chip8) error {
+			// According to the reference this should be ignored by interpreters
+			return nil
+		},
+	}
 }
 
 func decodeOpcode1(op uint16) Instruction {
@@ -116,9 +192,10 @@ func decodeOpcode1(op uint16) Instruction {
 	return Instruction{
 		op,
 		"JUMP to location in lowest 12 bits",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			// Set PC to lowest 12 bits
 			c.pc = op & 0x0FFF
+			return nil
 		},
 	}
 }
@@ -128,10 +205,17 @@ func decodeOpcode2(op uint16) Instruction {
 	return Instruction{
 		op,
 		"CALL subroutine in lowest 12 bits",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
+			if c.sp == StackSize {
+				return ErrStackOverflow
+			}
 			c.stack[c.sp] = c.pc
 			c.sp++
+			if len(c.hooks) > 0 {
+				c.hookStack(c.stack[c.sp-1], true)
+			}
 			c.pc = op & 0x0FFF
+			return nil
 		},
 	}
 }
@@ -141,12 +225,13 @@ func decodeOpcode3(op uint16) Instruction {
 	return Instruction{
 		op,
 		"Skip next instruction if Vx == kk when Opcode is 0x3xkk",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			// If value in specified V register is equal to value in lowest byte
-			// skip next instruction by incrementing PC by 2
+			// skip next instruction
 			if c.v[getX(op)] == byte(op&0xFF) {
-				c.pc += 2
+				c.skipInstruction()
 			}
+			return nil
 		},
 	}
 }
@@ -156,29 +241,72 @@ func decodeOpcode4(op uint16) Instruction {
 	return Instruction{
 		op,
 		"Skip next instruction is Vx != kk when Opcode is 0x4xkk",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			// If value in specified V register is not equal to value in lowest byte
-			// skip next instruction by incrementing PC by 2
+			// skip next instruction
 			if c.v[getX(op)] != byte(op&0xFF) {
-				c.pc += 2
+				c.skipInstruction()
 			}
+			return nil
 		},
 	}
 }
 
 func decodeOpcode5(op uint16) Instruction {
-	// Only 1 opcode where highest nibble is 5
-	return Instruction{
-		op,
-		"Skip next instruction if Vx == Vy when Opcode is 0x5xy0",
-		func(op uint16, c *Chip8) {
-			// If value in Vx is equal to value in Vy
-			// skip next instruction by incrementing PC by 2
-			if c.v[getX(op)] == c.v[getY(op)] {
-				c.pc += 2
-			}
-		},
+	// Opcodes with highest nibble 5 are identified by the lowest nibble
+	switch op & 0xF {
+	case 0x0:
+		return Instruction{
+			op,
+			"Skip next instruction if Vx == Vy when Opcode is 0x5xy0",
+			func(op uint16, c *Chip8) error {
+				// If value in Vx is equal to value in Vy
+				// skip next instruction
+				if c.v[getX(op)] == c.v[getY(op)] {
+					c.skipInstruction()
+				}
+				return nil
+			},
+		}
+	case 0x2:
+		return Instruction{
+			op,
+			"Store Vx to Vy in memory starting at location I, I is unchanged",
+			func(op uint16, c *Chip8) error {
+				x, y := getX(op), getY(op)
+				if err := checkMemory(c.i, registerRange(x, y)+1); err != nil {
+					return err
+				}
+				for n := 0; n <= registerRange(x, y); n++ {
+					c.memory[c.i+uint16(n)] = c.v[registerInRange(x, y, n)]
+				}
+				if len(c.hooks) > 0 {
+					c.hookMemoryWrite(c.i, registerRange(x, y)+1, AccessStore)
+				}
+				return nil
+			},
+		}
+	case 0x3:
+		return Instruction{
+			op,
+			"Load into Vx to Vy from memory starting at location I, I is unchanged",
+			func(op uint16, c *Chip8) error {
+				x, y := getX(op), getY(op)
+				if err := checkMemory(c.i, registerRange(x, y)+1); err != nil {
+					return err
+				}
+				if len(c.hooks) > 0 {
+					c.hookMemoryRead(c.i, registerRange(x, y)+1, AccessLoad)
+				}
+				for n := 0; n <= registerRange(x, y); n++ {
+					c.v[registerInRange(x, y, n)] = c.memory[c.i+uint16(n)]
+				}
+				return nil
+			},
+		}
 	}
+
+	return unknown
 }
 
 func decodeOpcode6(op uint16) Instruction {
@@ -186,9 +314,10 @@ func decodeOpcode6(op uint16) Instruction {
 	return Instruction{
 		op,
 		"LOAD kk into Vx when Opcode is 0x6xkk",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			// Load value kk into Vx
 			c.v[getX(op)] = byte(op & 0xFF)
+			return nil
 		},
 	}
 }
@@ -198,9 +327,10 @@ func decodeOpcode7(op uint16) Instruction {
 	return Instruction{
 		op,
 		"ADD Vx to kk, store result in Vx when Opcode is 0x7xkk",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			i := getX(op)
 			c.v[i] = c.v[i] + byte(op&0xFF)
+			return nil
 		},
 	}
 }
@@ -212,109 +342,138 @@ func decodeOpcode8(op uint16) Instruction {
 		return Instruction{
 			op,
 			"Stores the value of Vy in Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.v[getX(op)] = c.v[getY(op)]
+				return nil
 			},
 		}
 	case 0x1:
 		return Instruction{
 			op,
 			"Store bitwise OR result of Vx and Vy in Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.v[getX(op)] = c.v[getX(op)] | c.v[getY(op)]
+				if c.quirks.VFReset {
+					c.v[0xF] = 0
+				}
+				return nil
 			},
 		}
 	case 0x2:
 		return Instruction{
 			op,
 			"Store bitwise AND result of Vx and Vy in Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.v[getX(op)] = c.v[getX(op)] & c.v[getY(op)]
+				if c.quirks.VFReset {
+					c.v[0xF] = 0
+				}
+				return nil
 			},
 		}
 	case 0x3:
 		return Instruction{
 			op,
 			"Store bitwise XOR result of Vx and Vy in Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.v[getX(op)] = c.v[getX(op)] ^ c.v[getY(op)]
+				if c.quirks.VFReset {
+					c.v[0xF] = 0
+				}
+				return nil
 			},
 		}
 	case 0x4:
 		return Instruction{
 			op,
 			"Add Vx and Vy, set VF (flag) if result carries (> 255) lowest 8 bits stored in Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				// Perform sum
 				var result uint16 = uint16(c.v[getX(op)]) + uint16(c.v[op>>4&0xF])
 				// Store only lowest 8 bits
 				c.v[getX(op)] = byte(result & 0xFF)
-				// Set VF for carry
+				// Set VF for carry after the result so it wins when Vx is VF
 				if result > 255 {
 					c.v[0xF] = 1
 				} else {
 					c.v[0xF] = 0
 				}
+				return nil
 			},
 		}
 	case 0x5:
 		return Instruction{
 			op,
-			"Subtract Vy from Vx, store result in Vx, VF = Vx > Vy ? 1 : 0",
-			func(op uint16, c *Chip8) {
-				// Set VF
-				if c.v[getX(op)] > c.v[getY(op)] {
+			"Subtract Vy from Vx, store result in Vx, VF = Vx >= Vy ? 1 : 0",
+			func(op uint16, c *Chip8) error {
+				x, y := c.v[getX(op)], c.v[getY(op)]
+				// Subtract
+				c.v[getX(op)] = x - y
+				// Set VF after the result so it wins when Vx is VF
+				if x >= y {
 					c.v[0xF] = 1
 				} else {
 					c.v[0xF] = 0
 				}
-				// Subtract
-				c.v[getX(op)] = c.v[getX(op)] - c.v[getY(op)]
+				return nil
 			},
 		}
 	case 0x6:
 		return Instruction{
 			op,
-			"Divide Vx by 2, if LSB of Vx is 1 set VF to 1 else 0",
-			func(op uint16, c *Chip8) {
-				// Set VF
-				if c.v[getX(op)]&1 == 1 {
+			"Divide Vx (or Vy) by 2, store in Vx, if LSB is 1 set VF to 1 else 0",
+			func(op uint16, c *Chip8) error {
+				value := c.v[getX(op)]
+				if c.quirks.ShiftVy {
+					value = c.v[getY(op)]
+				}
+				// Half value by shifting right one
+				c.v[getX(op)] = value >> 1
+				// Set VF after the result so it wins when Vx is VF
+				if value&1 == 1 {
 					c.v[0xF] = 1
 				} else {
 					c.v[0xF] = 0
 				}
-				// Half Vx by shifting right one
-				c.v[getX(op)] >>= 1
+				return nil
 			},
 		}
 	case 0x7:
 		return Instruction{
 			op,
-			"Subtract Vx from Vy, store result in Vx, VF = Vy > Vx ? 1 : 0",
-			func(op uint16, c *Chip8) {
-				// Set VF
-				if c.v[getY(op)] > c.v[getX(op)] {
+			"Subtract Vx from Vy, store result in Vx, VF = Vy >= Vx ? 1 : 0",
+			func(op uint16, c *Chip8) error {
+				x, y := c.v[getX(op)], c.v[getY(op)]
+				// Subtract
+				c.v[getX(op)] = y - x
+				// Set VF after the result so it wins when Vx is VF
+				if y >= x {
 					c.v[0xF] = 1
 				} else {
 					c.v[0xF] = 0
 				}
-				// Subtract
-				c.v[getX(op)] = c.v[getY(op)] - c.v[getX(op)]
+				return nil
 			},
 		}
 	case 0xE:
 		// return unknown
 		return Instruction{
 			op,
-			"Multiply Vx by 2. If MSB of Vx is 1 set VF to 1 else 0",
-			func(op uint16, c *Chip8) {
-				if ((c.v[getX(op)] & (1 << 7)) >> 7) == 1 {
+			"Multiply Vx (or Vy) by 2, store in Vx. If MSB is 1 set VF to 1 else 0",
+			func(op uint16, c *Chip8) error {
+				value := c.v[getX(op)]
+				if c.quirks.ShiftVy {
+					value = c.v[getY(op)]
+				}
+				// Multiply
+				c.v[getX(op)] = value * 2
+				// Set VF after the result so it wins when Vx is VF
+				if ((value & (1 << 7)) >> 7) == 1 {
 					c.v[0xF] = 1
 				} else {
 					c.v[0xF] = 0
 				}
-				// Multiply
-				c.v[getX(op)] *= 2
+				return nil
 			},
 		}
 	}
@@ -327,10 +486,11 @@ func decodeOpcode9(op uint16) Instruction {
 	return Instruction{
 		op,
 		"Skip next instruction if Vx != Vy",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			if c.v[getX(op)] != c.v[getY(op)] {
-				c.pc += 2
+				c.skipInstruction()
 			}
+			return nil
 		},
 	}
 }
@@ -340,8 +500,9 @@ func decodeOpcodeA(op uint16) Instruction {
 	return Instruction{
 		op,
 		"Set I to nnn (0xAnnn)",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			c.i = op & 0xFFF
+			return nil
 		},
 	}
 }
@@ -350,9 +511,14 @@ func decodeOpcodeB(op uint16) Instruction {
 	// Only 1 Opcode where highest nibble is B
 	return Instruction{
 		op,
-		"JUMP to location nnn + V0",
-		func(op uint16, c *Chip8) {
-			c.pc = op&0xFFF + uint16(c.v[0])
+		"JUMP to location nnn + V0 (or xnn + Vx)",
+		func(op uint16, c *Chip8) error {
+			if c.quirks.JumpVx {
+				c.pc = op&0xFFF + uint16(c.v[getX(op)])
+			} else {
+				c.pc = op&0xFFF + uint16(c.v[0])
+			}
+			return nil
 		},
 	}
 }
@@ -362,55 +528,47 @@ func decodeOpcodeC(op uint16) Instruction {
 	return Instruction{
 		op,
 		"Bitwise AND result of Vx and Rand(0-255) stored in Vx",
-		func(op uint16, c *Chip8) {
+		func(op uint16, c *Chip8) error {
 			rnd := c.rand.Byte()
 			c.v[getX(op)] = rnd & byte(op&0xFF)
+			return nil
 		},
 	}
 }
 
 func decodeOpcodeD(op uint16) Instruction {
-	// Only 1 Opcode where highest nibble is D
+	// SUPER-CHIP 16x16 sprites when n is 0
+	if op&0xF == 0 {
+		return Instruction{
+			op,
+			"Draw 16x16 sprite to screen (0xDxy0)",
+			func(op uint16, c *Chip8) error {
+				if err := c.drawSprite(uint16(c.v[getX(op)]), uint16(c.v[getY(op)]), 16, 16); err != nil {
+					return err
+				}
+
+				// Pause until the next vertical blank
+				if c.quirks.DisplayWait {
+					c.waitingForVBlank = true
+				}
+				return nil
+			},
+		}
+	}
+
 	return Instruction{
 		op,
 		"Draw to screen (too long to describe)",
-		func(op uint16, c *Chip8) {
-			startX := uint16(c.v[getX(op)])
-			startY := uint16(c.v[getY(op)])
-
-			// Set VF to 0
-			c.v[0xF] = 0
-
-			// Read sprite and XOR to display
-			var x, y uint16
-			for y = 0; y < op&0xF; y++ {
-				line := c.memory[c.i+y]
-
-				// Sprites are 8 wide
-				for x = 0; x < 8; x++ {
-					xCoord := startX + x
-					yCoord := startY + y
-
-					// Use modulo to wrap if necessary
-					if xCoord >= DisplayWidth {
-						xCoord %= (DisplayWidth - 1)
-					}
-					if yCoord >= DisplayHeight {
-						yCoord %= (DisplayHeight - 1)
-					}
-
-					if line&(1<<(7-x)) != 0 {
-						if c.display[xCoord][yCoord] == 1 {
-							// Coliision, set VF
-							c.v[0xF] = 1
-						}
-
-						c.display[xCoord][yCoord] ^= 1
-					}
-				}
+		func(op uint16, c *Chip8) error {
+			if err := c.drawSprite(uint16(c.v[getX(op)]), uint16(c.v[getY(op)]), 8, op&0xF); err != nil {
+				return err
 			}
 
-			c.DrawFlag = true
+			// Pause until the next vertical blank
+			if c.quirks.DisplayWait {
+				c.waitingForVBlank = true
+			}
+			return nil
 		},
 	}
 }
@@ -422,20 +580,24 @@ func decodeOpcodeE(op uint16) Instruction {
 		return Instruction{
 			op,
 			"Skip next instruction if if key with value in Vx is pressed",
-			func(op uint16, c *Chip8) {
-				if c.keys[c.v[getX(op)]] {
-					c.pc += 2
+			func(op uint16, c *Chip8) error {
+				// Only the lowest nibble is used as there are 16 keys
+				if c.keys[c.v[getX(op)]&0xF] {
+					c.skipInstruction()
 				}
+				return nil
 			},
 		}
 	case 0xA1:
 		return Instruction{
 			op,
 			"Skip next instruction if if key with value in Vx is NOT pressed",
-			func(op uint16, c *Chip8) {
-				if !c.keys[c.v[getX(op)]] {
-					c.pc += 2
+			func(op uint16, c *Chip8) error {
+				// Only the lowest nibble is used as there are 16 keys
+				if !c.keys[c.v[getX(op)]&0xF] {
+					c.skipInstruction()
 				}
+				return nil
 			},
 		}
 	}
@@ -444,92 +606,224 @@ func decodeOpcodeE(op uint16) Instruction {
 }
 
 func decodeOpcodeF(op uint16) Instruction {
+	// XO-CHIP long load, the address is in the following 2 bytes
+	if op == 0xF000 {
+		return Instruction{
+			op,
+			"Set I to nnnn when Opcode is 0xF000 0xnnnn",
+			func(op uint16, c *Chip8) error {
+				if err := checkMemory(c.pc, 2); err != nil {
+					return err
+				}
+				if len(c.hooks) > 0 {
+					c.hookMemoryRead(c.pc, 2, AccessOther)
+				}
+				c.i = GetOpcode(c.memory[c.pc], c.memory[c.pc+1])
+				c.pc += 2
+				return nil
+			},
+		}
+	}
+
 	// Opcodes with highest nibble F are identified by the lowest byte
 	switch op & 0xFF {
+	case 0x01:
+		return Instruction{
+			op,
+			"Select drawing planes from bitmask x",
+			func(op uint16, c *Chip8) error {
+				c.planes = getX(op) & (1<<PlaneCount - 1)
+				return nil
+			},
+		}
+	case 0x02:
+		if op != 0xF002 {
+			break
+		}
+		return Instruction{
+			op,
+			"Load 16 byte audio pattern from memory starting at location I",
+			func(op uint16, c *Chip8) error {
+				if err := checkMemory(c.i, AudioPatternSize); err != nil {
+					return err
+				}
+				if len(c.hooks) > 0 {
+					c.hookMemoryRead(c.i, AudioPatternSize, AccessOther)
+				}
+				for n := uint16(0); n < AudioPatternSize; n++ {
+					c.audioPattern[n] = c.memory[c.i+n]
+				}
+				return nil
+			},
+		}
 	case 0x07:
 		return Instruction{
 			op,
 			"Set Vx to value of delay timer",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.v[getX(op)] = c.delay
+				return nil
 			},
 		}
 	case 0x0A:
 		return Instruction{
 			op,
 			"Wait for a key press, store key value in Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.waitingForKey = true
 				c.waitingKeyRegister = int8(getX(op))
+				if len(c.hooks) > 0 {
+					c.hookKeyWait(int(getX(op)), true)
+				}
+				return nil
 			},
 		}
 	case 0x15:
 		return Instruction{
 			op,
 			"Set delay timer to Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.delay = c.v[getX(op)]
+				if len(c.hooks) > 0 {
+					c.hookDelayTimer()
+				}
+				return nil
 			},
 		}
 	case 0x18:
 		return Instruction{
 			op,
 			"Set sound timer to Vx",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
+				old := c.sound
 				c.sound = c.v[getX(op)]
+				if len(c.hooks) > 0 {
+					c.hookSoundTimer(old)
+				}
+				return nil
 			},
 		}
 	case 0x1E:
 		return Instruction{
 			op,
 			"Add I and Vx, store result in I",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				c.i = uint16(c.v[getX(op)]) + c.i
+				return nil
 			},
 		}
 	case 0x29:
 		return Instruction{
 			op,
 			"Set I to memory address of the sprite data for character in VX",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				// 5 bytes per character, starting at address 0x000
 				// makes calculating address simple
 				c.i = 5 * uint16(c.v[getX(op)])
+				return nil
+			},
+		}
+	case 0x30:
+		return Instruction{
+			op,
+			"Set I to memory address of the large sprite data for character in VX",
+			func(op uint16, c *Chip8) error {
+				// 10 bytes per character, following the small font
+				c.i = bigFontStartAddress + 10*uint16(c.v[getX(op)]&0xF)
+				return nil
+			},
+		}
+	case 0x3A:
+		return Instruction{
+			op,
+			"Set audio pitch to Vx",
+			func(op uint16, c *Chip8) error {
+				c.pitch = c.v[getX(op)]
+				return nil
 			},
 		}
 	case 0x33:
 		return Instruction{
 			op,
 			"Store BCD of Vx in memory, hundreds at I, tens at I+1, ones at I+2",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
+				if err := checkMemory(c.i, 3); err != nil {
+					return err
+				}
 				val := c.v[getX(op)]
 				c.memory[c.i] = (val / 100) % 10  // hundreds
 				c.memory[c.i+1] = (val / 10) % 10 // tens
 				c.memory[c.i+2] = val % 10        // ones
+				if len(c.hooks) > 0 {
+					c.hookMemoryWrite(c.i, 3, AccessBCD)
+				}
+				return nil
 			},
 		}
 	case 0x55:
 		return Instruction{
 			op,
 			"Store V0 to Vx in memory starting at location I",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				var reg uint16
 				end := uint16(getX(op))
+				if err := checkMemory(c.i, int(end)+1); err != nil {
+					return err
+				}
 				for reg = 0; reg <= end; reg++ {
 					c.memory[c.i+reg] = c.v[reg]
 				}
+				if len(c.hooks) > 0 {
+					c.hookMemoryWrite(c.i, int(end)+1, AccessStore)
+				}
+				if c.quirks.IncrementI {
+					c.i += end + 1
+				} else if c.quirks.IncrementIByX {
+					c.i += end
+				}
+				return nil
 			},
 		}
 	case 0x65:
 		return Instruction{
 			op,
 			"Load into V0 to Vx from memory starting at location I",
-			func(op uint16, c *Chip8) {
+			func(op uint16, c *Chip8) error {
 				var reg uint16
 				end := uint16(getX(op))
+				if err := checkMemory(c.i, int(end)+1); err != nil {
+					return err
+				}
+				if len(c.hooks) > 0 {
+					c.hookMemoryRead(c.i, int(end)+1, AccessLoad)
+				}
 				for reg = 0; reg <= end; reg++ {
 					c.v[reg] = c.memory[c.i+reg]
 				}
+				if c.quirks.IncrementI {
+					c.i += end + 1
+				} else if c.quirks.IncrementIByX {
+					c.i += end
+				}
+				return nil
+			},
+		}
+	case 0x75:
+		return Instruction{
+			op,
+			"Store V0 to Vx in RPL user flags",
+			func(op uint16, c *Chip8) error {
+				copy(c.rpl[:getX(op)+1], c.v[:getX(op)+1])
+				return nil
+			},
+		}
+	case 0x85:
+		return Instruction{
+			op,
+			"Load into V0 to Vx from RPL user flags",
+			func(op uint16, c *Chip8) error {
+				copy(c.v[:getX(op)+1], c.rpl[:getX(op)+1])
+				return nil
 			},
 		}
 	}
@@ -7,7 +7,7 @@ import (
 func Test0x00E0(t *testing.T) {
 	c := New([]byte{
 		0x00, 0xE0,
-	})
+	}, Quirks{})
 
 	// Set all pixels to on
 	for x := 0; x < DisplayWidth; x++ {
@@ -41,7 +41,7 @@ func Test0x00EE(t *testing.T) {
 
 	c := New([]byte{
 		0x00, 0xEE,
-	})
+	}, Quirks{})
 
 	// Add address to stack
 	c.stack[c.sp] = stackAddressTest
@@ -58,12 +58,110 @@ func Test0x00EE(t *testing.T) {
 func Test0x0nnn(t *testing.T) {
 	c := New([]byte{
 		0x01, 0x11,
-	})
+	}, Quirks{})
 
 	c.Step()
 
 	// Ensure PC did not Jump as this instruction is ignored
-	if c.pc != programStartAddress+2 {
+	if c.pc != ProgramStartAddress+2 {
 		t.Error("expected instruction to be ignored and have no effect")
 	}
 }
+
+func Test0x00Cn(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xC2,
+	}, Quirks{})
+	c.display[5][0] = 1
+
+	c.Step()
+
+	if c.display[5][0] != 0 || c.display[5][2] != 1 {
+		t.Error("display was not scrolled down 2 lines")
+	}
+}
+
+func Test0x00Dn(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xD2,
+	}, Quirks{})
+	c.display[5][2] = 1
+	c.display[5][DisplayHeight-1] = 1
+
+	c.Step()
+
+	if c.display[5][2] != 0 || c.display[5][0] != 1 {
+		t.Error("display was not scrolled up 2 lines")
+	}
+
+	if c.display[5][DisplayHeight-1] != 0 || c.display[5][DisplayHeight-3] != 1 {
+		t.Error("blank lines should be inserted at the bottom")
+	}
+}
+
+func Test0x00FB(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xFB,
+	}, Quirks{})
+	c.display[0][5] = 1
+	c.display[DisplayWidth-1][5] = 1
+
+	c.Step()
+
+	if c.display[0][5] != 0 || c.display[4][5] != 1 {
+		t.Error("display was not scrolled right 4 pixels")
+	}
+
+	if c.display[DisplayWidth-1][5] != 0 {
+		t.Error("pixel scrolled off the right edge should be removed")
+	}
+}
+
+func Test0x00FC(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xFC,
+	}, Quirks{})
+	c.display[4][5] = 1
+
+	c.Step()
+
+	if c.display[4][5] != 0 || c.display[0][5] != 1 {
+		t.Error("display was not scrolled left 4 pixels")
+	}
+}
+
+func Test0x00FD(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xFD,
+		0x00, 0xE0,
+	}, Quirks{})
+
+	c.Step()
+
+	if !c.Exited() {
+		t.Error("expected Exited to be true")
+	}
+
+	if ok, _ := c.Step(); ok {
+		t.Error("step returned true after exit")
+	}
+}
+
+func Test0x00FEAnd0x00FF(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xFF,
+		0x00, 0xFE,
+	}, Quirks{})
+
+	c.Step()
+
+	if w, h := c.Resolution(); w != HiResDisplayWidth || h != HiResDisplayHeight {
+		t.Errorf("expected high resolution, actually %dx%d", w, h)
+	}
+
+	c.Step()
+
+	if w, h := c.Resolution(); w != DisplayWidth || h != DisplayHeight {
+		t.Errorf("expected low resolution, actually %dx%d", w, h)
+	}
+}
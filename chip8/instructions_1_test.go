@@ -7,7 +7,7 @@ import (
 func Test0x1nnn(t *testing.T) {
 	c := New([]byte{
 		0x1F, 0xED,
-	})
+	}, Quirks{})
 
 	c.Step()
 
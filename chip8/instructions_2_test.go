@@ -7,7 +7,7 @@ import (
 func Test0x2nnn(t *testing.T) {
 	c := New([]byte{
 		0x2A, 0xAA,
-	})
+	}, Quirks{})
 
 	c.Step()
 
@@ -18,7 +18,7 @@ func Test0x2nnn(t *testing.T) {
 
 	// Check pc was placed onto stack
 	// Since there is only one instruction pc should be at start address + 2
-	if c.stack[c.sp-1] != programStartAddress+2 {
+	if c.stack[c.sp-1] != ProgramStartAddress+2 {
 		t.Error("pc was not added to stack")
 	}
 }
@@ -10,7 +10,7 @@ func Test0x3xkk(t *testing.T) {
 		0x30, 0xFF,
 		0x1F, 0xFF, // Jump, should be skipped
 		0x00, 0xE0, // Clear screen
-	})
+	}, Quirks{})
 	c.v[0] = 0xFF
 
 	c.Step()
@@ -21,3 +21,19 @@ func Test0x3xkk(t *testing.T) {
 		t.Error("Test instruction was not skipped as expected")
 	}
 }
+
+// Skipping a long load should skip all 4 bytes
+func Test0x3xkkSkipLongLoad(t *testing.T) {
+	c := New([]byte{
+		0x30, 0xFF,
+		0xF0, 0x00, 0x12, 0x34, // Long load, should be skipped
+		0x00, 0xE0, // Clear screen
+	}, Quirks{})
+	c.v[0] = 0xFF
+
+	c.Step()
+
+	if c.pc != ProgramStartAddress+6 {
+		t.Errorf("expected PC to be %#x, actually %#x", ProgramStartAddress+6, c.pc)
+	}
+}
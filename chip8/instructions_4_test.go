@@ -10,7 +10,7 @@ func Test0x4xkk(t *testing.T) {
 		0x40, 0xFF,
 		0x1F, 0xFF, // Jump, should be skipped
 		0x00, 0xE0, // Clear screen
-	})
+	}, Quirks{})
 	c.v[0] = 0xEE
 
 	c.Step()
@@ -10,7 +10,7 @@ func Test0x5xy0(t *testing.T) {
 		0x50, 0x10,
 		0x1F, 0xFF, // Jump, should be skipped
 		0x00, 0xE0, // Clear screen
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xEE
 	c.v[1] = 0xEE
@@ -23,3 +23,42 @@ func Test0x5xy0(t *testing.T) {
 		t.Error("Test instruction was not skipped as expected")
 	}
 }
+
+func Test0x5xy2(t *testing.T) {
+	c := New([]byte{
+		0x53, 0x12, // Store V3 down to V1
+	}, Quirks{})
+	c.v[1] = 0x11
+	c.v[2] = 0x22
+	c.v[3] = 0x33
+	c.i = 0x300
+
+	c.Step()
+
+	expected := []byte{0x33, 0x22, 0x11}
+	for n, value := range expected {
+		if c.memory[0x300+n] != value {
+			t.Errorf("memory at %#x does not contain correct value", 0x300+n)
+		}
+	}
+
+	if c.i != 0x300 {
+		t.Error("I should be unchanged")
+	}
+}
+
+func Test0x5xy3(t *testing.T) {
+	c := New([]byte{
+		0x51, 0x33, // Load V1 to V3
+	}, Quirks{})
+	c.memory[0x300] = 0x11
+	c.memory[0x301] = 0x22
+	c.memory[0x302] = 0x33
+	c.i = 0x300
+
+	c.Step()
+
+	if c.v[1] != 0x11 || c.v[2] != 0x22 || c.v[3] != 0x33 {
+		t.Error("registers were not loaded from memory")
+	}
+}
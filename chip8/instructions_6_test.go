@@ -7,7 +7,7 @@ import (
 func Test0x6xkk(t *testing.T) {
 	c := New([]byte{
 		0x60, 0xFF,
-	})
+	}, Quirks{})
 
 	c.Step()
 
@@ -7,7 +7,7 @@ import (
 func Test0x7xkk(t *testing.T) {
 	c := New([]byte{
 		0x70, 0x02,
-	})
+	}, Quirks{})
 	c.v[0] = 0x02
 
 	c.Step()
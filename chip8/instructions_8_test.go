@@ -7,7 +7,7 @@ import (
 func Test0x8xy0(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x10,
-	})
+	}, Quirks{})
 
 	c.v[1] = 0xFF
 
@@ -21,7 +21,7 @@ func Test0x8xy0(t *testing.T) {
 func Test0x8xy1(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x11,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0F
 	c.v[1] = 0xF0
@@ -36,7 +36,7 @@ func Test0x8xy1(t *testing.T) {
 func Test0x8xy2(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x12,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0F
 	c.v[1] = 0xF0
@@ -51,7 +51,7 @@ func Test0x8xy2(t *testing.T) {
 func Test0x8xy3(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x13,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0F
 	c.v[1] = 0xF0
@@ -66,7 +66,7 @@ func Test0x8xy3(t *testing.T) {
 func Test0x8xy4(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x14,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0F
 	c.v[1] = 0xF0
@@ -81,7 +81,7 @@ func Test0x8xy4(t *testing.T) {
 func Test0x8xy4Carry(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x14,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xFF
 	c.v[1] = 0x01
@@ -97,7 +97,7 @@ func Test0x8xy4Carry(t *testing.T) {
 func Test0x8xy4NoCarry(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x14,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xFF
 	c.v[1] = 0x00
@@ -113,7 +113,7 @@ func Test0x8xy4NoCarry(t *testing.T) {
 func Test0x8xy5NotBorrow(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x15,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0F // Vx
 	c.v[1] = 0x0A // Vy
@@ -133,7 +133,7 @@ func Test0x8xy5NotBorrow(t *testing.T) {
 func Test0x8xy5Borrow(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x15,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0A // Vx
 	c.v[1] = 0x0F // Vy
@@ -156,7 +156,7 @@ func Test0x8xy5Borrow(t *testing.T) {
 func Test0x8xy6LSB0(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x16,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xAA
 	c.v[0xF] = 1 // Force VF to 1 to check for 0 later
@@ -176,7 +176,7 @@ func Test0x8xy6LSB0(t *testing.T) {
 func Test0x8xy6LSB1(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x16,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xFF // Vx
 
@@ -195,7 +195,7 @@ func Test0x8xy6LSB1(t *testing.T) {
 func Test0x8xy7NotBorrow(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x17,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0A // Vx
 	c.v[1] = 0x0B // Vy
@@ -214,7 +214,7 @@ func Test0x8xy7NotBorrow(t *testing.T) {
 func Test0x8xy7Borrow(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x17,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x0B // Vx
 	c.v[1] = 0x0A // Vy
@@ -234,10 +234,104 @@ func Test0x8xy7Borrow(t *testing.T) {
 	}
 }
 
+func Test0x8xy5Equal(t *testing.T) {
+	c := New([]byte{
+		0x80, 0x15,
+	}, Quirks{})
+
+	c.v[0] = 0x0A // Vx
+	c.v[1] = 0x0A // Vy
+
+	c.Step()
+
+	// Equal values don't borrow
+	if c.v[0xF] != 1 {
+		t.Error("VF should be 1")
+	}
+}
+
+func Test0x8xy5FlagInVF(t *testing.T) {
+	c := New([]byte{
+		0x8F, 0x05,
+	}, Quirks{})
+
+	c.v[0] = 0x0A   // Vy
+	c.v[0xF] = 0x0F // Vx
+
+	c.Step()
+
+	// The flag replaces the result
+	if c.v[0xF] != 1 {
+		t.Error("VF should be 1")
+	}
+}
+
+func Test0x8xy7FlagInVF(t *testing.T) {
+	c := New([]byte{
+		0x8F, 0x07,
+	}, Quirks{})
+
+	c.v[0] = 0x0A   // Vy
+	c.v[0xF] = 0x0F // Vx
+
+	c.Step()
+
+	// The flag replaces the result
+	if c.v[0xF] != 0 {
+		t.Error("VF should be 0")
+	}
+}
+
+func Test0x8xy4FlagInVF(t *testing.T) {
+	c := New([]byte{
+		0x8F, 0x04,
+	}, Quirks{})
+
+	c.v[0] = 0x01   // Vy
+	c.v[0xF] = 0x01 // Vx
+
+	c.Step()
+
+	// The flag replaces the result
+	if c.v[0xF] != 0 {
+		t.Error("VF should be 0")
+	}
+}
+
+func Test0x8xy6FlagInVF(t *testing.T) {
+	c := New([]byte{
+		0x8F, 0x06,
+	}, Quirks{})
+
+	c.v[0xF] = 0x02 // Vx
+
+	c.Step()
+
+	// The flag replaces the result
+	if c.v[0xF] != 0 {
+		t.Error("VF should be 0")
+	}
+}
+
+func Test0x8xyEFlagInVF(t *testing.T) {
+	c := New([]byte{
+		0x8F, 0x0E,
+	}, Quirks{})
+
+	c.v[0xF] = 0x81 // Vx
+
+	c.Step()
+
+	// The flag replaces the result
+	if c.v[0xF] != 1 {
+		t.Error("VF should be 1")
+	}
+}
+
 func Test0x8xyEMSB0(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x1E,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0x7A
 	c.v[0xF] = 1 // Force VF to 1 to check for 0 later
@@ -257,7 +351,7 @@ func Test0x8xyEMSB0(t *testing.T) {
 func Test0x8xyEMSB1(t *testing.T) {
 	c := New([]byte{
 		0x80, 0x1E,
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xF0 // Vx
 	// introduce variable since constants do not wrap around
@@ -274,3 +368,55 @@ func Test0x8xyEMSB1(t *testing.T) {
 		t.Error("multiplication (<< 1) result is incorrect")
 	}
 }
+
+func Test0x8xy1VFReset(t *testing.T) {
+	c := New([]byte{
+		0x80, 0x11,
+	}, Quirks{VFReset: true})
+
+	c.v[0xF] = 1
+
+	c.Step()
+
+	if c.v[0xF] != 0 {
+		t.Error("VF should be reset to 0")
+	}
+}
+
+func Test0x8xy6ShiftVy(t *testing.T) {
+	c := New([]byte{
+		0x80, 0x16,
+	}, Quirks{ShiftVy: true})
+
+	c.v[0] = 0xFF // Vx
+	c.v[1] = 0xAA // Vy
+
+	c.Step()
+
+	if c.v[0xF] != 0 {
+		t.Error("VF should be 0 since LSB of Vy is 0")
+	}
+
+	if c.v[0] != (0xAA / 2) {
+		t.Error("Vx should contain Vy shifted right")
+	}
+}
+
+func Test0x8xyEShiftVy(t *testing.T) {
+	c := New([]byte{
+		0x80, 0x1E,
+	}, Quirks{ShiftVy: true})
+
+	c.v[0] = 0x01 // Vx
+	c.v[1] = 0x81 // Vy
+
+	c.Step()
+
+	if c.v[0xF] != 1 {
+		t.Error("VF should be 1 since MSB of Vy is 1")
+	}
+
+	if c.v[0] != 0x02 {
+		t.Error("Vx should contain Vy shifted left")
+	}
+}
@@ -10,7 +10,7 @@ func Test0x9xy0(t *testing.T) {
 		0x90, 0x10,
 		0x1F, 0xFF, // Jump, should be skipped
 		0x00, 0xE0, // Clear screen
-	})
+	}, Quirks{})
 
 	c.v[0] = 0xEE
 	c.v[1] = 0xFF
@@ -7,7 +7,7 @@ import (
 func Test0xAnnn(t *testing.T) {
 	c := New([]byte{
 		0xAF, 0xFF,
-	})
+	}, Quirks{})
 
 	c.Step()
 
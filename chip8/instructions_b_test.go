@@ -7,7 +7,7 @@ import (
 func Test0xBnnn(t *testing.T) {
 	c := New([]byte{
 		0xBA, 0xAA,
-	})
+	}, Quirks{})
 	c.v[0] = 0xBB
 
 	c.Step()
@@ -16,3 +16,17 @@ func Test0xBnnn(t *testing.T) {
 		t.Error("pc was not set to correct result")
 	}
 }
+
+func Test0xBxnnJumpVx(t *testing.T) {
+	c := New([]byte{
+		0xBA, 0xAA,
+	}, Quirks{JumpVx: true})
+	c.v[0] = 0x01
+	c.v[0xA] = 0xBB
+
+	c.Step()
+
+	if c.pc != (0xAAA + 0xBB) {
+		t.Error("pc was not set to xnn + Vx")
+	}
+}
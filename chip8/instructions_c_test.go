@@ -7,7 +7,7 @@ import (
 func Test0xCxkk(t *testing.T) {
 	c := New([]byte{
 		0xC0, 0xBB,
-	})
+	}, Quirks{})
 
 	c.rand = MockRand{}
 
@@ -5,5 +5,120 @@ import (
 )
 
 func Test0xDxyn(t *testing.T) {
-	t.Skip("Not implemented")
+	c := New([]byte{
+		0xD0, 0x15, // Draw 5 line sprite at V0, V1
+		0xD0, 0x15, // Draw it again to erase it
+	}, Quirks{})
+	c.v[0] = 2
+	c.v[1] = 3
+	c.i = 0x0 // font sprite for "0"
+
+	c.Step()
+
+	if !c.DrawFlag {
+		t.Error("DrawFlag is false, expected true after draw")
+	}
+
+	// Top line of "0" is 0xF0
+	for x := 0; x < 8; x++ {
+		expected := byte(0)
+		if x < 4 {
+			expected = 1
+		}
+		if c.display[2+x][3] != expected {
+			t.Errorf("pixel at x:%d y:3 should be %d", 2+x, expected)
+		}
+	}
+
+	if c.v[0xF] != 0 {
+		t.Error("VF should be 0 when no pixels collide")
+	}
+
+	c.Step()
+
+	if c.v[0xF] != 1 {
+		t.Error("VF should be 1 after pixels collide")
+	}
+
+	if c.display[2][3] != 0 {
+		t.Error("pixel should have been erased by second draw")
+	}
+}
+
+func Test0xDxynWrap(t *testing.T) {
+	c := New([]byte{
+		0xD0, 0x11,
+	}, Quirks{})
+	c.v[0] = DisplayWidth - 2
+	c.v[1] = DisplayHeight - 1
+	c.i = 0x0 // top line of "0" is 0xF0
+
+	c.Step()
+
+	if c.display[0][DisplayHeight-1] != 1 || c.display[1][DisplayHeight-1] != 1 {
+		t.Error("sprite did not wrap to the left edge of the display")
+	}
+}
+
+func Test0xDxynClip(t *testing.T) {
+	c := New([]byte{
+		0xD0, 0x11,
+	}, Quirks{ClipSprites: true})
+	c.v[0] = DisplayWidth - 2
+	c.v[1] = DisplayHeight - 1
+	c.i = 0x0 // top line of "0" is 0xF0
+
+	c.Step()
+
+	if c.display[DisplayWidth-1][DisplayHeight-1] != 1 {
+		t.Error("sprite was not drawn at the right edge of the display")
+	}
+
+	if c.display[0][DisplayHeight-1] != 0 || c.display[1][DisplayHeight-1] != 0 {
+		t.Error("sprite was not clipped at the right edge of the display")
+	}
+}
+
+func Test0xDxynDisplayWait(t *testing.T) {
+	c := New([]byte{
+		0xD0, 0x11,
+		0x00, 0xE0,
+	}, Quirks{DisplayWait: true})
+
+	c.Step()
+
+	if ok, _ := c.Step(); ok {
+		t.Error("step returned true while waiting for vertical blank")
+	}
+
+	c.UpdateTimers()
+
+	if ok, _ := c.Step(); !ok {
+		t.Error("step returned false after vertical blank")
+	}
+}
+
+func Test0xDxy0(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xFF, // High resolution
+		0xD0, 0x10,
+	}, Quirks{})
+	c.v[0] = 100
+	c.v[1] = 40
+	c.i = 0x300
+
+	// Two bytes per row, set the outermost pixels of the first and last row
+	c.memory[0x300] = 0x80
+	c.memory[0x301] = 0x01
+	c.memory[0x31E] = 0x80
+	c.memory[0x31F] = 0x01
+
+	c.Step()
+	c.Step()
+
+	for _, p := range [][2]int{{100, 40}, {115, 40}, {100, 55}, {115, 55}} {
+		if c.display[p[0]][p[1]] != 1 {
+			t.Errorf("pixel at x:%d y:%d should be 1", p[0], p[1])
+		}
+	}
 }
@@ -9,7 +9,7 @@ func Test0xEx9E(t *testing.T) {
 		0xE0, 0x9E,
 		0x1F, 0xFF, // Jump, should be skipped
 		0x00, 0xE0, // Clear screen
-	})
+	}, Quirks{})
 	c.v[0] = byte(KeyF)
 
 	c.PressKey(KeyF)
@@ -27,7 +27,7 @@ func Test0xExA1(t *testing.T) {
 		0xE0, 0xA1,
 		0x1F, 0xFF, // Jump, should be skipped
 		0x00, 0xE0, // Clear screen
-	})
+	}, Quirks{})
 	c.v[0] = byte(KeyF)
 
 	c.PressKey(KeyF)
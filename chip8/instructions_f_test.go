@@ -8,7 +8,7 @@ import (
 func Test0xFx07(t *testing.T) {
 	c := New([]byte{
 		0xF0, 0x07,
-	})
+	}, Quirks{})
 	c.delay = 0xFF
 
 	c.Step()
@@ -25,7 +25,7 @@ func Test0xFx0A(t *testing.T) {
 func Test0xFx15(t *testing.T) {
 	c := New([]byte{
 		0xF0, 0x15,
-	})
+	}, Quirks{})
 	c.v[0] = 0xFF
 
 	c.Step()
@@ -38,7 +38,7 @@ func Test0xFx15(t *testing.T) {
 func Test0xFx18(t *testing.T) {
 	c := New([]byte{
 		0xF0, 0x18,
-	})
+	}, Quirks{})
 	c.v[0] = 0xFF
 
 	c.Step()
@@ -51,7 +51,7 @@ func Test0xFx18(t *testing.T) {
 func Test0xFx1E(t *testing.T) {
 	c := New([]byte{
 		0xF0, 0x1E,
-	})
+	}, Quirks{})
 	c.v[0] = 0x0A
 	c.i = 0x0A
 
@@ -67,7 +67,7 @@ func Test0xFx29(t *testing.T) {
 
 	c := New([]byte{
 		0xF5, 0x29, // Hex character in V5
-	})
+	}, Quirks{})
 	c.v[0x5] = hex
 
 	c.Step()
@@ -83,7 +83,7 @@ func Test0xFx33(t *testing.T) {
 
 	c := New([]byte{
 		0xF0, 0x33,
-	})
+	}, Quirks{})
 
 	c.i = startMemoryAddress
 	c.v[0] = bcdTest // Vx
@@ -117,7 +117,7 @@ var (
 func Test0xFx55(t *testing.T) {
 	c := New([]byte{
 		0xFB, 0x55,
-	})
+	}, Quirks{})
 
 	// Setup register values
 	for i := 0; i < len(memoryValues); i++ {
@@ -137,7 +137,7 @@ func Test0xFx55(t *testing.T) {
 func Test0xFx65(t *testing.T) {
 	c := New([]byte{
 		0xFB, 0x65,
-	})
+	}, Quirks{})
 
 	// Setup register values
 	for i := 0; i < len(memoryValues); i++ {
@@ -153,3 +153,175 @@ func Test0xFx65(t *testing.T) {
 		}
 	}
 }
+
+func Test0xFx55IncrementI(t *testing.T) {
+	c := New([]byte{
+		0xFB, 0x55,
+	}, QuirksVIP)
+	c.i = memoryStartAddress
+
+	c.Step()
+
+	if c.i != memoryStartAddress+0xC {
+		t.Errorf("expected I to be %#x, actually %#x", memoryStartAddress+0xC, c.i)
+	}
+}
+
+func Test0xFx65IncrementI(t *testing.T) {
+	c := New([]byte{
+		0xFB, 0x65,
+	}, QuirksVIP)
+	c.i = memoryStartAddress
+
+	c.Step()
+
+	if c.i != memoryStartAddress+0xC {
+		t.Errorf("expected I to be %#x, actually %#x", memoryStartAddress+0xC, c.i)
+	}
+}
+
+func Test0xFx55IncrementIByX(t *testing.T) {
+	c := New([]byte{
+		0xFB, 0x55,
+	}, QuirksCHIP48)
+	c.i = memoryStartAddress
+
+	c.Step()
+
+	if c.i != memoryStartAddress+0xB {
+		t.Errorf("expected I to be %#x, actually %#x", memoryStartAddress+0xB, c.i)
+	}
+}
+
+func Test0xFx65IncrementIByX(t *testing.T) {
+	c := New([]byte{
+		0xFB, 0x65,
+	}, QuirksCHIP48)
+	c.i = memoryStartAddress
+
+	c.Step()
+
+	if c.i != memoryStartAddress+0xB {
+		t.Errorf("expected I to be %#x, actually %#x", memoryStartAddress+0xB, c.i)
+	}
+}
+
+func Test0xFx30(t *testing.T) {
+	c := New([]byte{
+		0xF5, 0x30, // Hex character in V5
+	}, Quirks{})
+	c.v[0x5] = 0xA
+
+	c.Step()
+
+	if c.i != bigFontStartAddress+10*0xA {
+		t.Error("incorrect large font memory address in I")
+	}
+
+	if c.memory[c.i] != bigFontData[10*0xA] {
+		t.Error("large font was not loaded into memory")
+	}
+}
+
+func Test0xFx75AndFx85(t *testing.T) {
+	c := New([]byte{
+		0xF3, 0x75,
+		0xF3, 0x85,
+	}, Quirks{})
+
+	for i := 0; i < 4; i++ {
+		c.v[i] = byte(i + 1)
+	}
+
+	c.Step()
+
+	for i := 0; i < 4; i++ {
+		c.v[i] = 0
+	}
+
+	c.Step()
+
+	for i := 0; i < 4; i++ {
+		if c.v[i] != byte(i+1) {
+			t.Errorf("V%X was not restored from RPL user flags", i)
+		}
+	}
+}
+
+func Test0xF000(t *testing.T) {
+	c := New([]byte{
+		0xF0, 0x00, 0xBE, 0xEF,
+	}, Quirks{})
+
+	c.Step()
+
+	if c.i != 0xBEEF {
+		t.Errorf("expected I to be 0xBEEF, actually %#x", c.i)
+	}
+
+	if c.pc != ProgramStartAddress+4 {
+		t.Error("PC should be incremented past the address")
+	}
+}
+
+func Test0xFn01(t *testing.T) {
+	c := New([]byte{
+		0xF3, 0x01, // Select both planes
+		0xD0, 0x01, // Draw 1 line sprite to each plane
+		0xF2, 0x01, // Select second plane
+		0x00, 0xE0, // Clear second plane
+	}, Quirks{})
+	c.i = 0x300
+	c.memory[0x300] = 0x80
+	c.memory[0x301] = 0x40
+
+	c.Step()
+	c.Step()
+
+	if c.display[0][0] != 1 || c.display[1][0] != 2 {
+		t.Error("sprite was not drawn to each plane")
+	}
+
+	c.Step()
+	c.Step()
+
+	if c.display[0][0] != 1 || c.display[1][0] != 0 {
+		t.Error("clear should only affect the selected plane")
+	}
+}
+
+func Test0xF002(t *testing.T) {
+	c := New([]byte{
+		0xF0, 0x02,
+	}, Quirks{})
+	c.i = 0x300
+	for n := 0; n < AudioPatternSize; n++ {
+		c.memory[0x300+n] = byte(n)
+	}
+
+	c.Step()
+
+	pattern := c.AudioPattern()
+	for n := 0; n < AudioPatternSize; n++ {
+		if pattern[n] != byte(n) {
+			t.Fatal("audio pattern was not loaded from memory")
+		}
+	}
+}
+
+func Test0xFx3A(t *testing.T) {
+	c := New([]byte{
+		0xF0, 0x3A,
+	}, Quirks{})
+	c.v[0] = 112
+
+	if c.AudioSampleRate() != 4000 {
+		t.Error("default sample rate should be 4000")
+	}
+
+	c.Step()
+
+	if c.AudioSampleRate() != 8000 {
+		t.Errorf("expected sample rate of 8000, actually %f", c.AudioSampleRate())
+	}
+}
@@ -0,0 +1,87 @@
+package chip8
+
+import (
+	"sort"
+	"strings"
+)
+
+// Quirks toggles the behaviours that differ between Chip 8 interpreters.
+// The zero value matches the original behaviour of gochip8.
+type Quirks struct {
+	// 8xy6/8xyE shift Vy and store the result in Vx, rather than shifting Vx in place
+	ShiftVy bool
+
+	// Fx55/Fx65 leave I pointing past the last register stored or loaded
+	IncrementI bool
+
+	// Bnnn jumps to xnn + Vx instead of nnn + V0
+	JumpVx bool
+
+	// 8xy1/8xy2/8xy3 reset VF to 0
+	VFReset bool
+
+	// Sprites are clipped at the edges of the display instead of wrapping around
+	ClipSprites bool
+
+	// Dxyn waits for the next vertical blank (call to UpdateTimers) before execution continues
+	DisplayWait bool
+
+	// Fx55/Fx65 leave I pointing at the last register stored or loaded, as
+	// CHIP-48 increments it by x, ignored with IncrementI
+	IncrementIByX bool
+}
+
+var (
+	// QuirksVIP matches the original COSMAC VIP interpreter
+	QuirksVIP = Quirks{
+		ShiftVy:     true,
+		IncrementI:  true,
+		VFReset:     true,
+		ClipSprites: true,
+		DisplayWait: true,
+	}
+
+	// QuirksCHIP48 matches the CHIP-48 interpreter for the HP48 calculators
+	QuirksCHIP48 = Quirks{
+		IncrementIByX: true,
+		JumpVx:        true,
+		ClipSprites:   true,
+	}
+
+	// QuirksSCHIP matches the SUPER-CHIP 1.1 interpreter
+	QuirksSCHIP = Quirks{
+		JumpVx:      true,
+		ClipSprites: true,
+	}
+
+	// QuirksXOCHIP matches the XO-CHIP extensions as implemented by Octo
+	QuirksXOCHIP = Quirks{
+		ShiftVy:    true,
+		IncrementI: true,
+	}
+
+	// QuirksPresets maps the name of each preset to its quirks
+	QuirksPresets = map[string]Quirks{
+		"vip":    QuirksVIP,
+		"chip48": QuirksCHIP48,
+		"schip":  QuirksSCHIP,
+		"xochip": QuirksXOCHIP,
+	}
+)
+
+// QuirksPreset returns the preset with the given name, ignoring case
+func QuirksPreset(name string) (Quirks, bool) {
+	q, ok := QuirksPresets[strings.ToLower(name)]
+	return q, ok
+}
+
+// QuirksPresetNames returns the names of all presets in alphabetical order
+func QuirksPresetNames() []string {
+	names := make([]string, 0, len(QuirksPresets))
+	for name := range QuirksPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
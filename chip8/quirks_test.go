@@ -0,0 +1,21 @@
+package chip8
+
+import (
+	"testing"
+)
+
+func TestQuirksPreset(t *testing.T) {
+	for _, name := range QuirksPresetNames() {
+		if _, ok := QuirksPreset(name); !ok {
+			t.Errorf("preset %q was not found", name)
+		}
+	}
+
+	if q, ok := QuirksPreset("VIP"); !ok || q != QuirksVIP {
+		t.Error("preset lookup should ignore case")
+	}
+
+	if _, ok := QuirksPreset("unknown"); ok {
+		t.Error("unknown preset should not be found")
+	}
+}
@@ -1,23 +1,151 @@
 package chip8
 
 import (
+	"encoding/binary"
+	"errors"
 	"math/rand"
 )
 
 const (
 	mockRandByte byte = 0xAB
+
+	// First byte of marshaled random source state, identifying the source
+	seededRandTag byte = 'S'
+	vipRandTag    byte = 'V'
+)
+
+var (
+	// ErrRandState is returned when unmarshaling random source state produced by a different source
+	ErrRandState = errors.New("chip8: invalid random source state")
+
+	// ErrVIPInterpreter is returned by VIPRand.SetInterpreter for a dump of the wrong size
+	ErrVIPInterpreter = errors.New("chip8: VIP interpreter dump must be 512 bytes")
 )
 
 type RandSource interface {
 	Byte() byte
 }
 
+// Rand implements RandSource using the global math/rand source, its state
+// can't be saved
 type Rand struct{}
 
 func (r Rand) Byte() byte {
 	return byte(rand.Int31n(256))
 }
 
+// SeededRand implements RandSource producing the same sequence of bytes
+// for the same seed, its state can be saved with MarshalBinary.
+// The generator is SplitMix64
+type SeededRand struct {
+	state uint64
+}
+
+// NewSeededRand creates a SeededRand from seed
+func NewSeededRand(seed int64) *SeededRand {
+	return &SeededRand{
+		state: uint64(seed),
+	}
+}
+
+func (r *SeededRand) Byte() byte {
+	r.state += 0x9E3779B97F4A7C15
+	z := r.state
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	z ^= z >> 31
+
+	// Highest bits are the best distributed
+	return byte(z >> 56)
+}
+
+func (r *SeededRand) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 9)
+	data[0] = seededRandTag
+	binary.BigEndian.PutUint64(data[1:], r.state)
+	return data, nil
+}
+
+func (r *SeededRand) UnmarshalBinary(data []byte) error {
+	if len(data) != 9 || data[0] != seededRandTag {
+		return ErrRandState
+	}
+	r.state = binary.BigEndian.Uint64(data[1:])
+	return nil
+}
+
+// VIPRand implements RandSource following the random number routine of the
+// COSMAC VIP Chip 8 interpreter, for roms which depend on its distribution.
+//
+// The interpreter keeps a counter which is incremented by the 60hz interrupt
+// and by each Cxkk. The counter indexes a byte in the second page of the
+// interpreter's own code, which is added to the previous random byte to give
+// the next. Successive bytes are therefore poorly distributed and depend on
+// the frame timing, as on the VIP.
+//
+// The interpreter is copyrighted and not included in gochip8, so Page is
+// filled with a fixed stand-in sequence by NewVIPRand. Pass a dump of the
+// interpreter to SetInterpreter to reproduce the original numbers exactly
+type VIPRand struct {
+	Page    [256]byte
+	counter byte
+	last    byte
+}
+
+// NewVIPRand creates a VIPRand starting with the counter at seed
+func NewVIPRand(seed byte) *VIPRand {
+	r := &VIPRand{
+		counter: seed,
+	}
+
+	// Fill page from a linear congruential generator
+	var x uint32 = 0x1802
+	for i := range r.Page {
+		x = x*1103515245 + 12345
+		r.Page[i] = byte(x >> 16)
+	}
+
+	return r
+}
+
+// SetInterpreter sets Page from a dump of the VIP interpreter, the 512 bytes
+// of VIP memory from 0x000 to 0x1FF
+func (r *VIPRand) SetInterpreter(dump []byte) error {
+	if len(dump) != 0x200 {
+		return ErrVIPInterpreter
+	}
+	copy(r.Page[:], dump[0x100:])
+	return nil
+}
+
+func (r *VIPRand) Byte() byte {
+	r.counter++
+	r.last += r.Page[r.counter]
+	return r.last
+}
+
+// Tick advances the counter as the VIP interrupt does, it is called by
+// Chip8.UpdateTimers
+func (r *VIPRand) Tick() {
+	r.counter++
+}
+
+func (r *VIPRand) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 3+len(r.Page))
+	data = append(data, vipRandTag, r.counter, r.last)
+	data = append(data, r.Page[:]...)
+	return data, nil
+}
+
+func (r *VIPRand) UnmarshalBinary(data []byte) error {
+	if len(data) != 3+len(r.Page) || data[0] != vipRandTag {
+		return ErrRandState
+	}
+	r.counter, r.last = data[1], data[2]
+	copy(r.Page[:], data[3:])
+	return nil
+}
+
 // MockRand implemented RandSource returning mockRandByte
 // every time
 type MockRand struct{}
@@ -32,3 +32,81 @@ func TestMockRand(t *testing.T) {
 		}
 	}
 }
+
+func TestSeededRand(t *testing.T) {
+	a, b := NewSeededRand(42), NewSeededRand(42)
+	for i := 0; i < 100; i++ {
+		if a.Byte() != b.Byte() {
+			t.Fatal("seeded random byte generators with same seed differ")
+		}
+	}
+
+	// Restore state into a generator with a different seed
+	state, _ := a.MarshalBinary()
+	c := NewSeededRand(7)
+	if err := c.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if a.Byte() != c.Byte() {
+			t.Fatal("restored seeded random byte generator differs")
+		}
+	}
+}
+
+func TestVIPRand(t *testing.T) {
+	a, b := NewVIPRand(0), NewVIPRand(0)
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			a.Tick()
+			b.Tick()
+		}
+		if a.Byte() != b.Byte() {
+			t.Fatal("VIP random byte generators with same seed differ")
+		}
+	}
+
+	state, _ := a.MarshalBinary()
+	if err := NewSeededRand(0).UnmarshalBinary(state); err != ErrRandState {
+		t.Error("seeded random byte generator should not accept VIP state")
+	}
+}
+
+func TestVIPRandSetInterpreter(t *testing.T) {
+	dump := make([]byte, 0x200)
+	for i := range dump {
+		dump[i] = byte(i >> 8)
+	}
+
+	r := NewVIPRand(0)
+	if err := r.SetInterpreter(dump); err != nil {
+		t.Fatal(err)
+	}
+	if r.Byte() != 1 || r.Byte() != 2 {
+		t.Error("expected bytes from the interpreter's second page")
+	}
+
+	if err := r.SetInterpreter(dump[:0x100]); err != ErrVIPInterpreter {
+		t.Errorf("expected ErrVIPInterpreter, actually %v", err)
+	}
+}
+
+func TestRandSaveState(t *testing.T) {
+	c := New([]byte{
+		0xC0, 0xFF,
+	}, Quirks{})
+	c.SetRand(NewSeededRand(1))
+
+	state, _ := c.MarshalBinary()
+	c.Step()
+	expected := c.v[0]
+
+	if err := c.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	c.Step()
+
+	if c.v[0] != expected {
+		t.Error("random source state was not restored from save state")
+	}
+}
@@ -0,0 +1,286 @@
+package chip8
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReplayVersion is the version of the replay file format written by
+// WriteReplay. Version 2 added the random source, version 1 replays use a
+// SeededRand
+const ReplayVersion = 2
+
+// Random source of a replay using a VIPRand
+const replayVIPRand = "vip"
+
+var (
+	// ErrReplayROMMismatch is returned when playing a replay with a different rom to the one recorded
+	ErrReplayROMMismatch = errors.New("chip8: rom does not match replay")
+
+	// ErrReplayVersion is returned when reading a replay from a newer version
+	ErrReplayVersion = errors.New("chip8: unsupported replay version")
+
+	// ErrReplayRand is returned when playing a replay with an unknown random source
+	ErrReplayRand = errors.New("chip8: unknown random source in replay")
+
+	// ErrReplayFinished is returned by Player.RunFrame once every frame has been played
+	ErrReplayFinished = errors.New("chip8: replay finished")
+)
+
+// Replay is a recording of every key press and release made while running
+// a rom, which is enough to reproduce the session since execution is
+// deterministic given the same random seed.
+//
+// A frame is any key events followed by a call to Runner.RunFrame, with
+// the runner's CyclesPerFrame and VIPTiming set from the replay
+type Replay struct {
+	Version int `json:"version"`
+
+	// Hex SHA-1 of the rom
+	ROMHash string `json:"rom_sha1"`
+
+	// Seed of the random source used by Cxkk
+	Seed int64 `json:"seed"`
+
+	// Random source, empty for a SeededRand or vip for a VIPRand with its
+	// counter starting at the low byte of Seed and VIPPage as its page
+	Rand    string `json:"rand,omitempty"`
+	VIPPage []byte `json:"vip_page,omitempty"`
+
+	Quirks         Quirks `json:"quirks"`
+	CyclesPerFrame int    `json:"cycles_per_frame"`
+	VIPTiming      bool   `json:"vip_timing,omitempty"`
+
+	// Number of frames recorded
+	Frames int `json:"frames"`
+
+	Events []ReplayEvent `json:"events"`
+
+	// Optional hex SHA-1 of the save state at the end of each frame
+	StateHashes []string `json:"state_hashes,omitempty"`
+}
+
+// ReplayEvent is a key press or release
+type ReplayEvent struct {
+	Frame int  `json:"frame"`
+	Key   Key  `json:"key"`
+	Down  bool `json:"down"`
+}
+
+// DesyncError is returned during playback when the state at the end of a
+// frame differs from the recording
+type DesyncError struct {
+	Frame int
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("chip8: replay desynced at frame %d", e.Frame)
+}
+
+// ROMHash returns the hex SHA-1 of rom as used in replays
+func ROMHash(rom []byte) string {
+	sum := sha1.Sum(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadReplay decodes a replay written by WriteReplay
+func ReadReplay(r io.Reader) (*Replay, error) {
+	replay := &Replay{}
+	if err := json.NewDecoder(r).Decode(replay); err != nil {
+		return nil, err
+	}
+
+	if replay.Version > ReplayVersion {
+		return nil, fmt.Errorf("%w %d, newest supported is %d", ErrReplayVersion, replay.Version, ReplayVersion)
+	}
+
+	return replay, nil
+}
+
+// WriteReplay encodes replay as JSON
+func WriteReplay(w io.Writer, replay *Replay) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(replay)
+}
+
+// Recorder records the key events of a Chip 8 into a Replay
+type Recorder struct {
+	replay     Replay
+	hashStates bool
+}
+
+// NewRecorder creates a Chip 8 running rom with a SeededRand, and a Recorder
+// for it. If hashStates is true the state at the end of every frame is
+// hashed so desyncs can be detected during playback
+func NewRecorder(rom []byte, seed int64, quirks Quirks, cyclesPerFrame int, hashStates bool) (*Recorder, *Chip8) {
+	c := New(rom, quirks)
+	c.SetRand(NewSeededRand(seed))
+
+	return &Recorder{
+		replay: Replay{
+			Version:        ReplayVersion,
+			ROMHash:        ROMHash(rom),
+			Seed:           seed,
+			Quirks:         quirks,
+			CyclesPerFrame: cyclesPerFrame,
+		},
+		hashStates: hashStates,
+	}, c
+}
+
+// UseVIPTiming records that frames are run with RunCycles, and sets the
+// cycles per frame to VIPCyclesPerFrame
+func (r *Recorder) UseVIPTiming() {
+	r.replay.VIPTiming = true
+	r.replay.CyclesPerFrame = VIPCyclesPerFrame
+}
+
+// UseVIPRand switches c to a VIPRand with its counter at the low byte of the
+// seed and page as its page, and records both. It must be called before the
+// first frame
+func (r *Recorder) UseVIPRand(c *Chip8, page [256]byte) {
+	v := NewVIPRand(byte(r.replay.Seed))
+	v.Page = page
+	c.SetRand(v)
+
+	r.replay.Rand = replayVIPRand
+	r.replay.VIPPage = page[:]
+}
+
+// PressKey presses key on c and records it
+func (r *Recorder) PressKey(c *Chip8, key Key) {
+	r.replay.Events = append(r.replay.Events, ReplayEvent{r.replay.Frames, key, true})
+	c.PressKey(key)
+}
+
+// DePressKey releases key on c and records it
+func (r *Recorder) DePressKey(c *Chip8, key Key) {
+	r.replay.Events = append(r.replay.Events, ReplayEvent{r.replay.Frames, key, false})
+	c.DePressKey(key)
+}
+
+// EndFrame should be called after each call to Runner.RunFrame
+func (r *Recorder) EndFrame(c *Chip8) error {
+	if r.hashStates {
+		hash, err := stateHash(c)
+		if err != nil {
+			return err
+		}
+		r.replay.StateHashes = append(r.replay.StateHashes, hash)
+	}
+
+	r.replay.Frames++
+	return nil
+}
+
+// Replay returns the recording so far
+func (r *Recorder) Replay() *Replay {
+	replay := r.replay
+	return &replay
+}
+
+// Player feeds the events of a Replay into a new Chip 8, implementing FrameRunner
+type Player struct {
+	replay *Replay
+	runner *Runner
+	frame  int
+	event  int // index of next event
+}
+
+// NewPlayer creates a Player for replay, returning ErrReplayROMMismatch if
+// rom is not the rom it was recorded with
+func NewPlayer(replay *Replay, rom []byte) (*Player, error) {
+	if ROMHash(rom) != replay.ROMHash {
+		return nil, ErrReplayROMMismatch
+	}
+
+	c := New(rom, replay.Quirks)
+	switch replay.Rand {
+	case "":
+		c.SetRand(NewSeededRand(replay.Seed))
+	case replayVIPRand:
+		v := NewVIPRand(byte(replay.Seed))
+		if len(replay.VIPPage) != len(v.Page) {
+			return nil, fmt.Errorf("%w, vip page is %d bytes", ErrReplayRand, len(replay.VIPPage))
+		}
+		copy(v.Page[:], replay.VIPPage)
+		c.SetRand(v)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrReplayRand, replay.Rand)
+	}
+
+	runner := NewRunner(c, replay.CyclesPerFrame)
+	runner.VIPTiming = replay.VIPTiming
+
+	return &Player{
+		replay: replay,
+		runner: runner,
+	}, nil
+}
+
+// Chip8 returns the Chip 8 being played back
+func (p *Player) Chip8() *Chip8 {
+	return p.runner.Chip8()
+}
+
+// Frame returns the number of frames played
+func (p *Player) Frame() int {
+	return p.frame
+}
+
+// Done returns true once every recorded frame has been played
+func (p *Player) Done() bool {
+	return p.frame >= p.replay.Frames
+}
+
+// RunFrame plays the next frame, returning ErrReplayFinished once every
+// frame has been played. If the replay has state hashes a *DesyncError is
+// returned when the state differs from the recording
+func (p *Player) RunFrame() (FrameResult, error) {
+	if p.Done() {
+		return FrameResult{Frame: p.frame}, ErrReplayFinished
+	}
+
+	c := p.runner.Chip8()
+	for ; p.event < len(p.replay.Events) && p.replay.Events[p.event].Frame == p.frame; p.event++ {
+		if e := p.replay.Events[p.event]; e.Down {
+			c.PressKey(e.Key)
+		} else {
+			c.DePressKey(e.Key)
+		}
+	}
+
+	result, err := p.runner.RunFrame()
+	if err != nil {
+		return result, err
+	}
+
+	if p.frame < len(p.replay.StateHashes) {
+		hash, err := stateHash(c)
+		if err != nil {
+			return result, err
+		}
+		if hash != p.replay.StateHashes[p.frame] {
+			return result, &DesyncError{p.frame}
+		}
+	}
+
+	p.frame++
+	return result, nil
+}
+
+// stateHash returns the hex SHA-1 of the save state of c
+func stateHash(c *Chip8) (string, error) {
+	state, err := c.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum(state)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,133 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Program which alternates between random bytes and waiting for keys
+var replayROM = []byte{
+	0xC0, 0xFF, // V0 = rand
+	0xF1, 0x0A, // Wait for key in V1
+	0xC2, 0xFF, // V2 = rand
+	0x12, 0x00, // Jump to start
+}
+
+func TestReplay(t *testing.T) {
+	rec, c := NewRecorder(replayROM, 42, Quirks{}, 4, true)
+	r := NewRunner(c, 4)
+
+	keys := []Key{Key1, KeyA, Key7}
+	for frame := 0; frame < 20; frame++ {
+		if frame%5 == 0 {
+			rec.PressKey(c, keys[frame/5%len(keys)])
+		}
+		if frame%5 == 1 {
+			rec.DePressKey(c, keys[frame/5%len(keys)])
+		}
+		r.RunFrame()
+		rec.EndFrame(c)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteReplay(buf, rec.Replay()); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := ReadReplay(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPlayer(replay, replayROM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for !p.Done() {
+		if _, err := p.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := p.RunFrame(); err != ErrReplayFinished {
+		t.Errorf("expected ErrReplayFinished, actually %v", err)
+	}
+
+	if p.Chip8().v != c.v || p.Chip8().pc != c.pc {
+		t.Error("played back state differs from recording")
+	}
+}
+
+func TestReplayVIPRand(t *testing.T) {
+	rec, c := NewRecorder(replayROM, 0x142, Quirks{}, 4, true)
+	rec.UseVIPRand(c, NewVIPRand(0).Page)
+	r := NewRunner(c, 4)
+	for frame := 0; frame < 10; frame++ {
+		if frame%2 == 0 {
+			rec.PressKey(c, Key1)
+		} else {
+			rec.DePressKey(c, Key1)
+		}
+		r.RunFrame()
+		rec.EndFrame(c)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteReplay(buf, rec.Replay()); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := ReadReplay(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPlayer(replay, replayROM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := p.Chip8().rand.(*VIPRand); !ok || v.counter != 0x42 {
+		t.Fatalf("expected a VIPRand with counter 0x42, actually %#v", p.Chip8().rand)
+	}
+	for !p.Done() {
+		if _, err := p.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.Chip8().v != c.v {
+		t.Error("played back state differs from recording")
+	}
+
+	replay.Rand = "lfsr"
+	if _, err := NewPlayer(replay, replayROM); !errors.Is(err, ErrReplayRand) {
+		t.Errorf("expected ErrReplayRand, actually %v", err)
+	}
+}
+
+func TestReplayDesync(t *testing.T) {
+	rec, c := NewRecorder(replayROM, 42, Quirks{}, 4, true)
+	r := NewRunner(c, 4)
+	for frame := 0; frame < 3; frame++ {
+		rec.PressKey(c, Key1)
+		r.RunFrame()
+		rec.EndFrame(c)
+	}
+
+	replay := rec.Replay()
+	replay.Seed++
+
+	p, _ := NewPlayer(replay, replayROM)
+	var desync *DesyncError
+	if _, err := p.RunFrame(); !errors.As(err, &desync) || desync.Frame != 0 {
+		t.Errorf("expected desync at frame 0, actually %v", err)
+	}
+}
+
+func TestReplayROMMismatch(t *testing.T) {
+	rec, _ := NewRecorder(replayROM, 42, Quirks{}, 4, false)
+
+	if _, err := NewPlayer(rec.Replay(), []byte{0x00, 0xE0}); !errors.Is(err, ErrReplayROMMismatch) {
+		t.Errorf("expected ErrReplayROMMismatch, actually %v", err)
+	}
+}
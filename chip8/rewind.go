@@ -0,0 +1,181 @@
+package chip8
+
+import (
+	"encoding/binary"
+)
+
+// Rewinder records the state of a Chip 8 every frame in a bounded ring
+// buffer so execution can be stepped backwards one frame at a time.
+//
+// Only the newest state is kept in full, every older frame is stored as
+// the run length encoded XOR of its state against the following frame,
+// which is mostly zeros since little changes between frames
+type Rewinder struct {
+	maxFrames int // maximum number of frames which can be rewound
+	maxBytes  int // maximum bytes used by deltas, 0 for no limit
+
+	latest []byte        // full state of the newest frame
+	deltas []rewindDelta // ring buffer of deltas, oldest at start
+	start  int           // index of oldest delta
+	count  int           // number of deltas in buffer
+	size   int           // bytes used by deltas
+}
+
+// rewindDelta reconstructs the state of a frame from the frame following it
+type rewindDelta struct {
+	data   []byte // run length encoded XOR of the two states
+	length int    // length of the reconstructed state
+}
+
+// NewRewinder creates a Rewinder which can step back up to frames frames,
+// using at most maxBytes for the recorded deltas, 0 for no limit. Rewinding
+// is disabled with 0 or fewer frames
+func NewRewinder(frames, maxBytes int) *Rewinder {
+	if frames < 0 {
+		frames = 0
+	}
+	return &Rewinder{
+		maxFrames: frames,
+		maxBytes:  maxBytes,
+		deltas:    make([]rewindDelta, frames),
+	}
+}
+
+// Push records the current state of c as the newest frame, the oldest
+// frames are discarded when the buffer is full
+func (r *Rewinder) Push(c *Chip8) error {
+	state, err := c.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if r.latest != nil && r.maxFrames > 0 {
+		// Make room for the new delta
+		if r.count == r.maxFrames {
+			r.dropOldest()
+		}
+
+		delta := rewindDelta{
+			data:   encodeRLE(xorStates(state, r.latest)),
+			length: len(r.latest),
+		}
+		r.deltas[(r.start+r.count)%r.maxFrames] = delta
+		r.count++
+		r.size += len(delta.data)
+
+		for r.maxBytes > 0 && r.size > r.maxBytes && r.count > 0 {
+			r.dropOldest()
+		}
+	}
+
+	r.latest = state
+	return nil
+}
+
+// Rewind restores c to the frame before the newest, which is then
+// discarded. Returns false if there are no earlier frames
+func (r *Rewinder) Rewind(c *Chip8) (bool, error) {
+	if r.count == 0 {
+		return false, nil
+	}
+
+	// Pop newest delta
+	r.count--
+	delta := r.deltas[(r.start+r.count)%r.maxFrames]
+	r.deltas[(r.start+r.count)%r.maxFrames] = rewindDelta{}
+	r.size -= len(delta.data)
+
+	state := xorStates(r.latest, decodeRLE(delta.data))[:delta.length]
+	if err := c.UnmarshalBinary(state); err != nil {
+		return false, err
+	}
+
+	r.latest = state
+	return true, nil
+}
+
+// Len returns the number of frames which can currently be rewound
+func (r *Rewinder) Len() int {
+	return r.count
+}
+
+// Size returns the number of bytes used by recorded frames
+func (r *Rewinder) Size() int {
+	return r.size + len(r.latest)
+}
+
+// Reset discards all recorded frames
+func (r *Rewinder) Reset() {
+	for i := range r.deltas {
+		r.deltas[i] = rewindDelta{}
+	}
+	r.latest = nil
+	r.start, r.count, r.size = 0, 0, 0
+}
+
+// dropOldest discards the oldest delta, its frame can no longer be reached
+func (r *Rewinder) dropOldest() {
+	r.size -= len(r.deltas[r.start].data)
+	r.deltas[r.start] = rewindDelta{}
+	r.start = (r.start + 1) % r.maxFrames
+	r.count--
+}
+
+// xorStates returns a XOR b, the shorter is padded with zeros
+func xorStates(a, b []byte) []byte {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+
+	result := make([]byte, len(a))
+	copy(result, a)
+	for i := range b {
+		result[i] ^= b[i]
+	}
+
+	return result
+}
+
+// encodeRLE encodes data as pairs of a run of zero bytes followed by a run of
+// literal bytes, each run length is a uvarint and literals follow their length
+func encodeRLE(data []byte) []byte {
+	var (
+		out = make([]byte, 0, 64)
+		tmp [binary.MaxVarintLen64]byte
+	)
+
+	for i := 0; i < len(data); {
+		zeros := i
+		for i < len(data) && data[i] == 0 {
+			i++
+		}
+		literals := i
+		for i < len(data) && data[i] != 0 {
+			i++
+		}
+
+		out = append(out, tmp[:binary.PutUvarint(tmp[:], uint64(literals-zeros))]...)
+		out = append(out, tmp[:binary.PutUvarint(tmp[:], uint64(i-literals))]...)
+		out = append(out, data[literals:i]...)
+	}
+
+	return out
+}
+
+// decodeRLE reverses encodeRLE
+func decodeRLE(data []byte) []byte {
+	var out []byte
+
+	for len(data) > 0 {
+		zeros, n := binary.Uvarint(data)
+		data = data[n:]
+		literals, n := binary.Uvarint(data)
+		data = data[n:]
+
+		out = append(out, make([]byte, zeros)...)
+		out = append(out, data[:literals]...)
+		data = data[literals:]
+	}
+
+	return out
+}
@@ -0,0 +1,108 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRewind(t *testing.T) {
+	c := New([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+	r := NewRewinder(10, 0)
+
+	for frame := 0; frame < 5; frame++ {
+		if err := r.Push(c); err != nil {
+			t.Fatal(err)
+		}
+		c.Step()
+		c.Step()
+	}
+	r.Push(c)
+
+	if c.v[0] != 5 {
+		t.Fatalf("expected V0 to be 5, actually %d", c.v[0])
+	}
+
+	for expected := byte(4); ; expected-- {
+		ok, err := r.Rewind(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			if expected != 255 {
+				t.Errorf("could only rewind to V0 = %d", expected+1)
+			}
+			break
+		}
+
+		if c.v[0] != expected {
+			t.Fatalf("expected V0 to be %d after rewind, actually %d", expected, c.v[0])
+		}
+	}
+}
+
+func TestRewindLimits(t *testing.T) {
+	c := New([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+	r := NewRewinder(3, 0)
+
+	for frame := 0; frame < 10; frame++ {
+		r.Push(c)
+		c.Step()
+		c.Step()
+	}
+
+	if r.Len() != 3 {
+		t.Errorf("expected 3 frames, actually %d", r.Len())
+	}
+
+	r = NewRewinder(100, 1)
+	for frame := 0; frame < 10; frame++ {
+		r.Push(c)
+		c.Step()
+		c.Step()
+	}
+
+	if r.Len() != 0 {
+		t.Errorf("expected memory budget to discard all frames, actually %d", r.Len())
+	}
+}
+
+func TestRewindDisabled(t *testing.T) {
+	c := New([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+
+	for _, frames := range []int{0, -1} {
+		r := NewRewinder(frames, 0)
+		for frame := 0; frame < 3; frame++ {
+			if err := r.Push(c); err != nil {
+				t.Fatal(err)
+			}
+			c.Step()
+		}
+
+		if ok, err := r.Rewind(c); ok || err != nil || r.Len() != 0 {
+			t.Errorf("%d frames: expected nothing to rewind, actually %t %v with %d frames", frames, ok, err, r.Len())
+		}
+	}
+}
+
+func TestRLE(t *testing.T) {
+	for _, data := range [][]byte{
+		{},
+		{0, 0, 0},
+		{1, 2, 3},
+		{0, 0, 1, 0, 2, 2, 0, 0, 0},
+		append(make([]byte, 1000), 7),
+	} {
+		if decoded := decodeRLE(encodeRLE(data)); !bytes.Equal(decoded, data) {
+			t.Errorf("%v was decoded as %v", data, decoded)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package chip8
+
+import (
+	"context"
+	"time"
+)
+
+// FrameRate is the rate at which timers count down and frames are run
+const FrameRate = 60
+
+// FrameResult describes a frame run by RunFrame
+type FrameResult struct {
+	// Index of the frame, counting from 0
+	Frame int
+
+	// Display was updated and should be rendered
+	DisplayChanged bool
+
+	// Sound timer is active, a tone should be played
+	Buzzing bool
+
+	// Execution is paused until a key is pressed (Fx0A)
+	WaitingForKey bool
+
+	// Program has exited (00FD)
+	Exited bool
+
+	// Frame was skipped because the runner is paused
+	Paused bool
+
+	// Frame ended early because Break returned true
+	Stopped bool
+
+	// Number of instructions executed
+	Executed int
+}
+
+// FrameRunner is implemented by types which advance a Chip 8 one frame at a time
+type FrameRunner interface {
+	RunFrame() (FrameResult, error)
+}
+
+// Runner advances a Chip 8 one 60hz frame at a time, replacing the loop
+// frontends would otherwise need around UpdateTimers and Step
+type Runner struct {
+	c *Chip8
+
+	// Instructions executed per frame, or COSMAC VIP machine cycles with VIPTiming
+	CyclesPerFrame int
+
+	// Charge each instruction its COSMAC VIP cost using RunCycles
+	VIPTiming bool
+
+	// Frames are skipped while Paused is true
+	Paused bool
+
+	// Break is called before each instruction if set, returning true ends
+	// the frame early without executing it. It isn't called while paused
+	// waiting for a key or the display
+	Break func(c *Chip8) bool
+
+	frame int
+}
+
+// NewRunner creates a Runner executing cyclesPerFrame instructions per frame
+func NewRunner(c *Chip8, cyclesPerFrame int) *Runner {
+	return &Runner{
+		c:              c,
+		CyclesPerFrame: cyclesPerFrame,
+	}
+}
+
+// Chip8 returns the Chip 8 being run
+func (r *Runner) Chip8() *Chip8 {
+	return r.c
+}
+
+// RunFrame updates the timers then executes a frame worth of instructions.
+// DrawFlag is cleared, its value is returned as DisplayChanged
+func (r *Runner) RunFrame() (FrameResult, error) {
+	result := FrameResult{
+		Frame: r.frame,
+	}
+
+	if r.Paused {
+		result.Paused = true
+		return r.finishFrame(result), nil
+	}
+
+	r.c.UpdateTimers()
+
+	if r.VIPTiming {
+		executed, stopped, err := r.c.runCycles(r.CyclesPerFrame, r.Break)
+		result.Executed, result.Stopped = executed, stopped
+		if err != nil {
+			return r.finishFrame(result), err
+		}
+	} else {
+		for i := 0; i < r.CyclesPerFrame; i++ {
+			if r.Break != nil && !r.c.paused() && r.Break(r.c) {
+				result.Stopped = true
+				break
+			}
+
+			ok, err := r.c.Step()
+			if err != nil {
+				return r.finishFrame(result), err
+			}
+			if ok {
+				result.Executed++
+			}
+		}
+	}
+
+	r.frame++
+	return r.finishFrame(result), nil
+}
+
+// finishFrame fills in the state of the machine at the end of a frame
+func (r *Runner) finishFrame(result FrameResult) FrameResult {
+	result.DisplayChanged = r.c.DrawFlag
+	result.Buzzing = r.c.ShouldBuzz()
+	result.WaitingForKey = r.c.waitingForKey
+	result.Exited = r.c.exited
+
+	r.c.DrawFlag = false
+	return result
+}
+
+// Run calls RunFrame at 60hz, see RunFrames
+func (r *Runner) Run(ctx context.Context, onFrame func(FrameResult) error) error {
+	return RunFrames(ctx, r, onFrame)
+}
+
+// RunFrames calls fr.RunFrame at 60hz followed by onFrame with the result,
+// until ctx is cancelled, the program exits or either returns an error.
+// Returns nil when the program exits, or the error from ctx if cancelled
+func RunFrames(ctx context.Context, fr FrameRunner, onFrame func(FrameResult) error) error {
+	ticker := time.NewTicker(time.Second / FrameRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// A tick may be chosen over a cancellation which happened with it
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result, err := fr.RunFrame()
+		if err != nil {
+			return err
+		}
+
+		if err := onFrame(result); err != nil {
+			return err
+		}
+
+		if result.Exited {
+			return nil
+		}
+	}
+}
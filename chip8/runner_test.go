@@ -0,0 +1,108 @@
+package chip8
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunFrame(t *testing.T) {
+	c := New([]byte{
+		0xA0, 0x00, // I = 0
+		0xD0, 0x05, // Draw "0"
+		0x60, 0x10, // V0 = 16
+		0xF0, 0x18, // Sound timer = V0
+		0xF1, 0x0A, // Wait for key in V1
+	}, Quirks{})
+	r := NewRunner(c, 10)
+
+	result, err := r.RunFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Frame != 0 || result.Executed != 5 {
+		t.Errorf("expected frame 0 to execute 5 instructions, actually frame %d executed %d", result.Frame, result.Executed)
+	}
+
+	if !result.DisplayChanged || !result.Buzzing || !result.WaitingForKey {
+		t.Errorf("unexpected frame result %+v", result)
+	}
+
+	if c.DrawFlag {
+		t.Error("DrawFlag should be cleared")
+	}
+
+	r.Paused = true
+	if result, _ := r.RunFrame(); !result.Paused || result.Frame != 1 || result.Executed != 0 {
+		t.Errorf("unexpected paused frame result %+v", result)
+	}
+
+	r.Paused = false
+	if result, _ := r.RunFrame(); result.Frame != 1 || result.DisplayChanged {
+		t.Errorf("unexpected frame result %+v", result)
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	c := New([]byte{
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+	r := NewRunner(c, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	frames := 0
+	err := r.Run(ctx, func(result FrameResult) error {
+		frames++
+		return nil
+	})
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, actually %v", err)
+	}
+
+	if frames == 0 {
+		t.Error("no frames were run")
+	}
+}
+
+func TestRunnerRunExit(t *testing.T) {
+	c := New([]byte{
+		0x00, 0xFD, // Exit
+	}, Quirks{})
+	r := NewRunner(c, 10)
+
+	if err := r.Run(context.Background(), func(FrameResult) error { return nil }); err != nil {
+		t.Errorf("expected nil error after exit, actually %v", err)
+	}
+}
+
+func TestRunnerBreak(t *testing.T) {
+	for _, vipTiming := range []bool{false, true} {
+		c := New([]byte{
+			0x60, 0x01, // V0 = 1
+			0x61, 0x02, // V1 = 2
+			0x12, 0x00, // Jump to start
+		}, Quirks{})
+		r := NewRunner(c, 10)
+		if vipTiming {
+			r.VIPTiming = true
+			r.CyclesPerFrame = VIPCyclesPerFrame
+		}
+
+		r.Break = func(c *Chip8) bool {
+			return c.pc == 0x204
+		}
+
+		result, err := r.RunFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !result.Stopped || result.Executed != 2 || c.pc != 0x204 {
+			t.Errorf("VIPTiming %v: expected to stop at 0x204 after 2 instructions, actually %+v at %#04x", vipTiming, result, c.pc)
+		}
+	}
+}
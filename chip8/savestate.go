@@ -0,0 +1,225 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Save states are produced by MarshalBinary, all values are big endian
+// and booleans are a single byte of 0 or 1.
+//
+// Version 2 layout:
+//
+//	size   field
+//	4      magic "GC8S"
+//	2      version
+//	65536  memory
+//	2      pc
+//	2      sp
+//	32     stack, 16 uint16
+//	16     V0 to VF
+//	2      I
+//	8192   display, 128 columns of 64 pixels, each a bitmask of planes
+//	1      hires
+//	1      selected planes
+//	16     RPL user flags
+//	1      exited
+//	1      DrawFlag
+//	16     keys
+//	1      waiting for key
+//	1      waiting key register (signed, -1 when not waiting)
+//	1      sound timer
+//	1      delay timer
+//	1      waiting for vertical blank
+//	16     audio pattern
+//	1      pitch
+//	1      quirks, bit 0 ShiftVy, 1 IncrementI, 2 JumpVx, 3 VFReset, 4 ClipSprites, 5 DisplayWait,
+//	       6 IncrementIByX
+//	4      COSMAC VIP machine cycles left to run (signed)
+//	4      length of random source state
+//	n      random source state, only present if the RandSource implements encoding.BinaryMarshaler
+//
+// Version 1 is the same without the machine cycles, which are migrated as 0.
+const (
+	stateMagic   = "GC8S"
+	StateVersion = 2
+)
+
+var (
+	// ErrInvalidState is returned when unmarshaling data which is not a save state
+	ErrInvalidState = errors.New("chip8: invalid save state")
+
+	// ErrStateVersion is returned when unmarshaling a save state from a newer version
+	ErrStateVersion = errors.New("chip8: unsupported save state version")
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding the whole
+// machine state in the current version of the save state format
+func (c *Chip8) MarshalBinary() ([]byte, error) {
+	var randState []byte
+	if m, ok := c.rand.(encoding.BinaryMarshaler); ok {
+		var err error
+		if randState, err = m.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(stateMagic)
+
+	fields := []interface{}{
+		uint16(StateVersion),
+		c.memory,
+		c.pc,
+		c.sp,
+		c.stack,
+		c.v,
+		c.i,
+		c.display,
+		c.hires,
+		c.planes,
+		c.rpl,
+		c.exited,
+		c.DrawFlag,
+		c.keys,
+		c.waitingForKey,
+		c.waitingKeyRegister,
+		c.sound,
+		c.delay,
+		c.waitingForVBlank,
+		c.audioPattern,
+		c.pitch,
+		quirksToByte(c.quirks),
+		int32(c.cycles),
+		uint32(len(randState)),
+	}
+	for _, field := range fields {
+		// Writes to a bytes.Buffer can't fail
+		binary.Write(buf, binary.BigEndian, field)
+	}
+	buf.Write(randState)
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, restoring the
+// machine state from a save state produced by MarshalBinary. Save states
+// from older versions are migrated, newer versions return ErrStateVersion
+func (c *Chip8) UnmarshalBinary(data []byte) error {
+	if len(data) < len(stateMagic)+2 || string(data[:len(stateMagic)]) != stateMagic {
+		return ErrInvalidState
+	}
+
+	version := binary.BigEndian.Uint16(data[len(stateMagic):])
+	r := bytes.NewReader(data[len(stateMagic)+2:])
+
+	if version < 1 || version > StateVersion {
+		return fmt.Errorf("%w %d, newest supported is %d", ErrStateVersion, version, StateVersion)
+	}
+
+	// Decode into a copy so c is unchanged on error
+	state := *c
+
+	if err := state.unmarshalFields(r, version); err != nil {
+		return err
+	}
+
+	*c = state
+	return nil
+}
+
+// unmarshalFields decodes the fields of a save state following the version,
+// migrating older versions to the current state
+func (c *Chip8) unmarshalFields(r *bytes.Reader, version uint16) error {
+	var (
+		quirks       byte
+		cycles       int32
+		randStateLen uint32
+	)
+
+	fields := []interface{}{
+		&c.memory,
+		&c.pc,
+		&c.sp,
+		&c.stack,
+		&c.v,
+		&c.i,
+		&c.display,
+		&c.hires,
+		&c.planes,
+		&c.rpl,
+		&c.exited,
+		&c.DrawFlag,
+		&c.keys,
+		&c.waitingForKey,
+		&c.waitingKeyRegister,
+		&c.sound,
+		&c.delay,
+		&c.waitingForVBlank,
+		&c.audioPattern,
+		&c.pitch,
+		&quirks,
+	}
+	if version >= 2 {
+		fields = append(fields, &cycles)
+	}
+	fields = append(fields, &randStateLen)
+
+	for _, field := range fields {
+		if err := binary.Read(r, binary.BigEndian, field); err != nil {
+			return ErrInvalidState
+		}
+	}
+
+	if int(randStateLen) != r.Len() || c.sp > StackSize {
+		return ErrInvalidState
+	}
+
+	// The register waiting for a key is -1 when not waiting, otherwise a V register
+	if c.waitingKeyRegister < -1 || c.waitingKeyRegister >= VRegisterCount || (c.waitingForKey && c.waitingKeyRegister < 0) {
+		return ErrInvalidState
+	}
+
+	c.quirks = quirksFromByte(quirks)
+	c.cycles = int(cycles)
+
+	// Random source state is only restored when the current source supports it
+	if randStateLen > 0 {
+		if u, ok := c.rand.(encoding.BinaryUnmarshaler); ok {
+			randState := make([]byte, randStateLen)
+			r.Read(randState)
+			if err := u.UnmarshalBinary(randState); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// quirksToByte packs quirks into a bitmask in the order of the Quirks fields
+func quirksToByte(q Quirks) byte {
+	var b byte
+	for i, set := range []bool{q.ShiftVy, q.IncrementI, q.JumpVx, q.VFReset, q.ClipSprites, q.DisplayWait, q.IncrementIByX} {
+		if set {
+			b |= 1 << uint(i)
+		}
+	}
+	return b
+}
+
+// quirksFromByte unpacks a bitmask produced by quirksToByte
+func quirksFromByte(b byte) Quirks {
+	return Quirks{
+		ShiftVy:       b&(1<<0) != 0,
+		IncrementI:    b&(1<<1) != 0,
+		JumpVx:        b&(1<<2) != 0,
+		VFReset:       b&(1<<3) != 0,
+		ClipSprites:   b&(1<<4) != 0,
+		DisplayWait:   b&(1<<5) != 0,
+		IncrementIByX: b&(1<<6) != 0,
+	}
+}
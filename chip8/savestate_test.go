@@ -0,0 +1,142 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestSaveStateRoundTrip(t *testing.T) {
+	c := New([]byte{
+		0x60, 0x05, // V0 = 5
+		0x22, 0x08, // Call 0x208
+		0x00, 0xE0,
+		0x00, 0xE0,
+		0xA0, 0x00, // I = 0
+		0xD0, 0x05, // Draw "0"
+		0xF1, 0x0A, // Wait for key in V1
+	}, QuirksVIP)
+	c.delay = 30
+	c.PressKey(Key3)
+
+	for i := 0; i < 5; i++ {
+		c.Step()
+	}
+	c.UpdateTimers()
+	c.Step()
+
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New([]byte{}, Quirks{})
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.memory != c.memory || restored.display != c.display || restored.v != c.v ||
+		restored.stack != c.stack || restored.keys != c.keys {
+		t.Error("restored memory, display, registers, stack or keys differ")
+	}
+
+	if restored.pc != c.pc || restored.sp != c.sp || restored.i != c.i || restored.delay != c.delay {
+		t.Error("restored pc, sp, I or timers differ")
+	}
+
+	if !restored.waitingForKey || restored.waitingKeyRegister != 1 {
+		t.Error("restored key wait state differs")
+	}
+
+	if restored.quirks != QuirksVIP {
+		t.Error("restored quirks differ")
+	}
+}
+
+func TestSaveStateInvalid(t *testing.T) {
+	c := New([]byte{}, Quirks{})
+
+	if err := c.UnmarshalBinary([]byte("not a save state")); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState, actually %v", err)
+	}
+
+	data, _ := c.MarshalBinary()
+	if err := c.UnmarshalBinary(data[:len(data)-10]); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected ErrInvalidState for truncated state, actually %v", err)
+	}
+}
+
+func TestSaveStateInvalidKeyWait(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		waiting  bool
+		register int8
+	}{
+		{"register below -1", false, -2},
+		{"register past VF", true, VRegisterCount},
+		{"waiting without a register", true, -1},
+	} {
+		c := New([]byte{}, Quirks{})
+		c.waitingForKey = test.waiting
+		c.waitingKeyRegister = test.register
+		data, _ := c.MarshalBinary()
+
+		restored := New([]byte{}, Quirks{})
+		if err := restored.UnmarshalBinary(data); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%s: expected ErrInvalidState, actually %v", test.name, err)
+		}
+
+		// Rejected states leave the machine as it was, so keys can still be pressed
+		restored.PressKey(Key0)
+	}
+}
+
+func TestSaveStateNewerVersion(t *testing.T) {
+	c := New([]byte{}, Quirks{})
+
+	data, _ := c.MarshalBinary()
+	binary.BigEndian.PutUint16(data[len(stateMagic):], StateVersion+1)
+
+	if err := c.UnmarshalBinary(data); !errors.Is(err, ErrStateVersion) {
+		t.Errorf("expected ErrStateVersion, actually %v", err)
+	}
+}
+
+func TestSaveStateMigrateV1(t *testing.T) {
+	c := New([]byte{
+		0x60, 0x05,
+	}, Quirks{})
+	c.Step()
+	c.cycles = 100
+
+	data, _ := c.MarshalBinary()
+
+	// Version 1 has no cycles, which are the 4 bytes before the random source state
+	randStateLen := int(binary.BigEndian.Uint32(data[len(data)-9-4:]))
+	cyclesOffset := len(data) - randStateLen - 4 - 4
+	v1 := append([]byte{}, data[:cyclesOffset]...)
+	v1 = append(v1, data[cyclesOffset+4:]...)
+	binary.BigEndian.PutUint16(v1[len(stateMagic):], 1)
+
+	restored := New([]byte{}, Quirks{})
+	if err := restored.UnmarshalBinary(v1); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.v[0] != 5 || restored.pc != c.pc {
+		t.Error("version 1 state was not restored")
+	}
+
+	if restored.cycles != 0 {
+		t.Error("cycles should be migrated as 0")
+	}
+}
+
+func TestQuirksByte(t *testing.T) {
+	for _, name := range QuirksPresetNames() {
+		q := QuirksPresets[name]
+		if restored := quirksFromByte(quirksToByte(q)); restored != q {
+			t.Errorf("%s: expected %+v, actually %+v", name, q, restored)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package chip8
+
+import (
+	"time"
+)
+
+const (
+	// COSMAC VIP clock speed, the 1802 takes 8 clock cycles per machine cycle
+	VIPClockHz        = 1760640
+	VIPMachineCycleHz = VIPClockHz / 8
+
+	// Machine cycles available to the interpreter in each 60hz frame, the
+	// display DMA takes 1024 of the 3668 cycles per frame and the interrupt
+	// routine roughly 50 more
+	VIPCyclesPerFrame = VIPMachineCycleHz/60 - 1024 - 50
+
+	// Machine cycles taken by the interpreter to fetch and decode an opcode
+	vipFetchCycles = 20
+
+	// Machine cycles charged for instructions the VIP interpreter does not have
+	vipExtendedCycles = 20
+)
+
+// RunCycles executes instructions until n COSMAC VIP machine cycles have
+// been used, with each instruction charged its cost on the VIP. Cycles
+// used beyond n are deducted from the next call, while waiting for a key
+// or vertical blank the remaining cycles pass without executing anything.
+// Returns the number of instructions executed
+func (c *Chip8) RunCycles(n int) (int, error) {
+	executed, _, err := c.runCycles(n, nil)
+	return executed, err
+}
+
+// runCycles is RunCycles, stopping early when stop returns true before an
+// instruction. The remaining cycles are discarded when stopped
+func (c *Chip8) runCycles(n int, stop func(*Chip8) bool) (executed int, stopped bool, err error) {
+	c.cycles += n
+
+	for c.cycles > 0 {
+		if stop != nil && !c.paused() && stop(c) {
+			c.cycles = 0
+			return executed, true, nil
+		}
+
+		// Cost depends on state before execution
+		var cost int
+		if c.pc >= ProgramStartAddress && int(c.pc) <= MemorySize-2 {
+			cost = c.vipCycles(GetOpcode(c.memory[c.pc], c.memory[c.pc+1]))
+		}
+
+		ok, err := c.Step()
+		if err != nil {
+			return executed, false, err
+		}
+		if !ok {
+			c.cycles = 0
+			break
+		}
+
+		c.cycles -= cost
+		executed++
+	}
+
+	return executed, false, nil
+}
+
+// RunFor executes instructions for d of emulated COSMAC VIP time, see RunCycles
+func (c *Chip8) RunFor(d time.Duration) (int, error) {
+	return c.RunCycles(int(int64(d) * VIPMachineCycleHz / int64(time.Second)))
+}
+
+// vipCycles returns the machine cycles op takes to execute on the COSMAC
+// VIP, including fetch and decode. Costs are approximations from published
+// measurements of the original interpreter
+func (c *Chip8) vipCycles(op uint16) int {
+	x := getX(op)
+
+	switch op >> 12 {
+	case 0x0:
+		switch op {
+		case 0x00E0:
+			return vipFetchCycles + 24
+		case 0x00EE:
+			return vipFetchCycles + 10
+		}
+		if op&0xFFF0 == 0x00C0 || op&0xFFF0 == 0x00D0 || (op >= 0x00FB && op <= 0x00FF) {
+			return vipExtendedCycles
+		}
+		// Machine code subroutine, cost unknown
+		return vipFetchCycles + 23
+	case 0x1, 0x2, 0xB:
+		return vipFetchCycles + 12
+	case 0x3, 0x4:
+		return vipFetchCycles + 10
+	case 0x5, 0x9:
+		if op&0xF != 0 {
+			return vipExtendedCycles
+		}
+		return vipFetchCycles + 14
+	case 0x6:
+		return vipFetchCycles + 6
+	case 0x7:
+		return vipFetchCycles + 10
+	case 0x8:
+		return vipFetchCycles + 44
+	case 0xA:
+		return vipFetchCycles + 12
+	case 0xC:
+		return vipFetchCycles + 36
+	case 0xD:
+		return vipFetchCycles + c.vipDrawCycles(op)
+	case 0xE:
+		return vipFetchCycles + 14
+	}
+
+	// 0xF???
+	switch op & 0xFF {
+	case 0x07, 0x0A, 0x15, 0x18:
+		return vipFetchCycles + 10
+	case 0x1E:
+		return vipFetchCycles + 16
+	case 0x29:
+		return vipFetchCycles + 16
+	case 0x33:
+		// Digits are found by repeated subtraction
+		val := c.v[x]
+		return vipFetchCycles + 80 + 16*int(val/100+val/10%10+val%10)
+	case 0x55, 0x65:
+		return vipFetchCycles + 14 + 14*int(x+1)
+	}
+
+	return vipExtendedCycles
+}
+
+// vipDrawCycles returns the machine cycles to draw a sprite. Each row of a
+// sprite which is not aligned to a byte of display memory is shifted into
+// two bytes, costing more than an aligned row
+func (c *Chip8) vipDrawCycles(op uint16) int {
+	rows := int(op & 0xF)
+	if rows == 0 {
+		// SUPER-CHIP 16x16 sprite, 2 bytes per row
+		rows = 32
+	}
+
+	perRow := 20
+	if c.v[getX(op)]%8 != 0 {
+		perRow = 34
+	}
+
+	return 68 + rows*perRow
+}
@@ -0,0 +1,111 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunCycles(t *testing.T) {
+	c := New([]byte{
+		0x60, 0x01, // V0 = 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+
+	perLoop := c.vipCycles(0x6001) + c.vipCycles(0x1200)
+
+	executed, err := c.RunCycles(10 * perLoop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if executed != 20 {
+		t.Errorf("expected 20 instructions to execute, actually %d", executed)
+	}
+}
+
+func TestRunCyclesCarry(t *testing.T) {
+	c := New([]byte{
+		0x60, 0x01, // V0 = 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+
+	// First instruction overspends
+	if executed, _ := c.RunCycles(1); executed != 1 {
+		t.Fatalf("expected 1 instruction to execute, actually %d", executed)
+	}
+
+	// Overspent cycles are paid back before anything else executes
+	if executed, _ := c.RunCycles(1); executed != 0 {
+		t.Errorf("expected no instructions to execute, actually %d", executed)
+	}
+}
+
+func TestRunCyclesWaiting(t *testing.T) {
+	c := New([]byte{
+		0xF0, 0x0A, // Wait for key
+	}, Quirks{})
+
+	c.RunCycles(VIPCyclesPerFrame)
+
+	if c.cycles != 0 {
+		t.Error("cycles should pass while waiting for a key")
+	}
+}
+
+func TestVIPDrawCycles(t *testing.T) {
+	c := New([]byte{}, Quirks{})
+
+	c.v[0] = 8
+	aligned := c.vipCycles(0xD005)
+
+	c.v[0] = 9
+	unaligned := c.vipCycles(0xD005)
+
+	if aligned >= unaligned {
+		t.Error("unaligned sprites should take longer to draw")
+	}
+
+	if c.vipCycles(0xD00F) <= unaligned {
+		t.Error("taller sprites should take longer to draw")
+	}
+}
+
+func TestVIPCycles(t *testing.T) {
+	machineCode := vipFetchCycles + 23
+	for _, test := range []struct {
+		op     uint16
+		cycles int
+	}{
+		{0x00E0, vipFetchCycles + 24},
+		{0x00EE, vipFetchCycles + 10},
+		{0x00C4, vipExtendedCycles},
+		{0x00D4, vipExtendedCycles},
+		{0x00FB, vipExtendedCycles},
+		{0x00FF, vipExtendedCycles},
+		{0x00FA, machineCode},
+		{0x0123, machineCode},
+		{0x0ABC, machineCode},
+	} {
+		if cycles := New([]byte{}, Quirks{}).vipCycles(test.op); cycles != test.cycles {
+			t.Errorf("%04X: expected %d cycles, actually %d", test.op, test.cycles, cycles)
+		}
+	}
+}
+
+func TestRunFor(t *testing.T) {
+	a := New([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+	b := New([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Jump to start
+	}, Quirks{})
+
+	a.RunFor(time.Second / 100)
+	b.RunCycles(VIPMachineCycleHz / 100)
+
+	if a.v[0] != b.v[0] {
+		t.Error("RunFor should run the same cycles as RunCycles")
+	}
+}
@@ -14,3 +14,19 @@ func getX(opcode uint16) byte {
 func getY(opcode uint16) byte {
 	return byte((opcode & 0x00F0) >> 4)
 }
+
+// registerRange returns the number of registers between x and y, excluding the first
+func registerRange(x, y byte) int {
+	if x > y {
+		return int(x - y)
+	}
+	return int(y - x)
+}
+
+// registerInRange returns the nth register counting from x towards y
+func registerInRange(x, y byte, n int) byte {
+	if x > y {
+		return x - byte(n)
+	}
+	return x + byte(n)
+}
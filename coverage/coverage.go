@@ -0,0 +1,237 @@
+// Package coverage records which bytes of a rom are executed as code, which
+// are accessed as data and which are never touched, to tell code from sprites
+// and find code a play session didn't reach.
+//
+// Coverage files are JSON, identified by the SHA-1 of the rom so only
+// coverage of the same rom is merged. Ranges are inclusive memory addresses:
+//
+//	{
+//		"rom": "a6b8a3f6b5d1e0e1f4c1d9f8c3b2a1e0d9c8b7a6",
+//		"size": 246,
+//		"executed": [[512, 745]],
+//		"data": [[746, 757]]
+//	}
+package coverage
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// ErrDifferentROM is returned when merging coverage of different roms
+var ErrDifferentROM = errors.New("coverage: coverage is of a different rom")
+
+// Mark is how a byte of a rom has been used, a combination of Executed and Data
+type Mark byte
+
+const (
+	Executed Mark = 1 << iota // part of an executed instruction
+	Data                      // read or written by an instruction, e.g. sprites drawn by Dxyn
+)
+
+// Coverage is the use of each byte of a rom
+type Coverage struct {
+	ROM   string // SHA-1 of the rom, lower case hex
+	Marks []Mark // by byte of the rom, the first at chip8.ProgramStartAddress
+}
+
+// New creates Coverage of rom with every byte untouched
+func New(rom []byte) *Coverage {
+	sum := sha1.Sum(rom)
+	return &Coverage{
+		ROM:   hex.EncodeToString(sum[:]),
+		Marks: make([]Mark, len(rom)),
+	}
+}
+
+// Mark marks the byte at a memory address, addresses outside the rom are ignored
+func (cov *Coverage) Mark(address uint16, m Mark) {
+	if i := int(address) - chip8.ProgramStartAddress; i >= 0 && i < len(cov.Marks) {
+		cov.Marks[i] |= m
+	}
+}
+
+// At returns the marks of the byte at a memory address
+func (cov *Coverage) At(address uint16) Mark {
+	if i := int(address) - chip8.ProgramStartAddress; i >= 0 && i < len(cov.Marks) {
+		return cov.Marks[i]
+	}
+	return 0
+}
+
+// Merge adds the marks of other, which must be coverage of the same rom
+func (cov *Coverage) Merge(other *Coverage) error {
+	if other.ROM != cov.ROM || len(other.Marks) != len(cov.Marks) {
+		return ErrDifferentROM
+	}
+	for i, m := range other.Marks {
+		cov.Marks[i] |= m
+	}
+	return nil
+}
+
+// Count returns the number of bytes executed, used only as data and untouched
+func (cov *Coverage) Count() (executed, data, untouched int) {
+	for _, m := range cov.Marks {
+		switch {
+		case m&Executed != 0:
+			executed++
+		case m&Data != 0:
+			data++
+		default:
+			untouched++
+		}
+	}
+	return
+}
+
+// file is the JSON encoding of Coverage
+type file struct {
+	ROM      string   `json:"rom"`
+	Size     int      `json:"size"`
+	Executed [][2]int `json:"executed"`
+	Data     [][2]int `json:"data"`
+}
+
+// MarshalJSON encodes the marks as ranges of addresses
+func (cov *Coverage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(file{
+		ROM:      cov.ROM,
+		Size:     len(cov.Marks),
+		Executed: cov.ranges(Executed),
+		Data:     cov.ranges(Data),
+	})
+}
+
+// UnmarshalJSON decodes coverage encoded by MarshalJSON
+func (cov *Coverage) UnmarshalJSON(data []byte) error {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if f.Size < 0 || f.Size > chip8.MemorySize-chip8.ProgramStartAddress {
+		return fmt.Errorf("invalid rom size %d", f.Size)
+	}
+
+	cov.ROM = f.ROM
+	cov.Marks = make([]Mark, f.Size)
+	for _, r := range [...]struct {
+		ranges [][2]int
+		mark   Mark
+	}{{f.Executed, Executed}, {f.Data, Data}} {
+		for _, rng := range r.ranges {
+			start, end := rng[0]-chip8.ProgramStartAddress, rng[1]-chip8.ProgramStartAddress
+			if start < 0 || end < start || end >= f.Size {
+				return fmt.Errorf("invalid range %#04x-%#04x", rng[0], rng[1])
+			}
+			for i := start; i <= end; i++ {
+				cov.Marks[i] |= r.mark
+			}
+		}
+	}
+	return nil
+}
+
+// ranges returns the inclusive ranges of addresses with mark m
+func (cov *Coverage) ranges(m Mark) [][2]int {
+	ranges := [][2]int{}
+	for i := 0; i < len(cov.Marks); i++ {
+		if cov.Marks[i]&m == 0 {
+			continue
+		}
+		start := i
+		for i+1 < len(cov.Marks) && cov.Marks[i+1]&m != 0 {
+			i++
+		}
+		ranges = append(ranges, [2]int{start + chip8.ProgramStartAddress, i + chip8.ProgramStartAddress})
+	}
+	return ranges
+}
+
+// Read decodes Coverage written by Write
+func Read(r io.Reader) (*Coverage, error) {
+	cov := &Coverage{}
+	if err := json.NewDecoder(r).Decode(cov); err != nil {
+		return nil, fmt.Errorf("coverage: %w", err)
+	}
+	return cov, nil
+}
+
+// ReadFile reads the Coverage at path
+func ReadFile(path string) (*Coverage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Write encodes cov as JSON
+func (cov *Coverage) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(cov)
+}
+
+// WriteFile writes cov to path
+func (cov *Coverage) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := cov.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recorder marks the bytes of a rom used by a Chip 8
+type Recorder struct {
+	cov   *Coverage
+	c     *chip8.Chip8
+	hooks *chip8.Hooks
+}
+
+// NewRecorder marks the bytes used by c in cov until Close
+func NewRecorder(c *chip8.Chip8, cov *Coverage) *Recorder {
+	r := &Recorder{cov: cov, c: c}
+	r.hooks = &chip8.Hooks{
+		BeforeInstruction: r.instruction,
+		MemoryRead: func(address uint16, value byte, access chip8.MemoryAccess) {
+			cov.Mark(address, Data)
+		},
+		MemoryWrite: func(address uint16, value byte, access chip8.MemoryAccess) {
+			cov.Mark(address, Data)
+		},
+	}
+	c.AddHooks(r.hooks)
+	return r
+}
+
+// Close stops recording
+func (r *Recorder) Close() {
+	r.c.RemoveHooks(r.hooks)
+}
+
+// instruction is the BeforeInstruction hook, marking both bytes of the
+// instruction at pc and the address following F000 long loads
+func (r *Recorder) instruction(pc uint16, inst chip8.Instruction) {
+	size := uint16(2)
+	if inst.Opcode == 0xF000 {
+		size = 4
+	}
+	for i := uint16(0); i < size; i++ {
+		r.cov.Mark(pc+i, Executed)
+	}
+}
@@ -0,0 +1,126 @@
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// Draws a sprite, then loops skipping the code after it
+var program = []byte{
+	0xA2, 0x08, // 0x200 I = 0x208
+	0xD0, 0x02, // 0x202 Draw 2 rows at V0, V0
+	0x12, 0x04, // 0x204 Jump to itself
+	0x00, 0xE0, // 0x206 Clear, never executed
+	0xF0, 0x81, // 0x208 Sprite
+	0xFF, // 0x20A Never touched
+}
+
+// record returns the coverage of running program for a frame
+func record(t *testing.T, program []byte) *Coverage {
+	c := chip8.New(program, chip8.Quirks{})
+	cov := New(program)
+	r := NewRecorder(c, cov)
+	defer r.Close()
+
+	if _, err := chip8.NewRunner(c, 10).RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	return cov
+}
+
+func TestRecord(t *testing.T) {
+	cov := record(t, program)
+
+	expected := []Mark{Executed, Executed, Executed, Executed, Executed, Executed, 0, 0, Data, Data, 0}
+	for i, m := range expected {
+		if cov.Marks[i] != m {
+			t.Errorf("%#04x: expected %s, actually %s", 0x200+i, kind(m), kind(cov.Marks[i]))
+		}
+	}
+
+	if executed, data, untouched := cov.Count(); executed != 6 || data != 2 || untouched != 3 {
+		t.Errorf("expected 6 executed, 2 data and 3 untouched, actually %d, %d and %d", executed, data, untouched)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	cov := record(t, program)
+
+	buf := &bytes.Buffer{}
+	if err := cov.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"size": 11`, `"executed": [`, "512,\n\t\t\t517"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, buf)
+		}
+	}
+
+	read, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.ROM != cov.ROM || !bytes.Equal(marksBytes(read.Marks), marksBytes(cov.Marks)) {
+		t.Errorf("expected %+v, actually %+v", cov, read)
+	}
+
+	if _, err := Read(strings.NewReader(`{"rom": "", "size": 2, "executed": [[512, 514]]}`)); err == nil {
+		t.Error("expected an error for a range past the end of the rom")
+	}
+}
+
+func marksBytes(marks []Mark) []byte {
+	b := make([]byte, len(marks))
+	for i, m := range marks {
+		b[i] = byte(m)
+	}
+	return b
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(program), New(program)
+	a.Mark(0x200, Executed)
+	b.Mark(0x200, Data)
+	b.Mark(0x20A, Executed)
+	b.Mark(0x100, Executed) // outside the rom
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.At(0x200) != Executed|Data || a.At(0x20A) != Executed || a.At(0x100) != 0 {
+		t.Errorf("unexpected marks after merging %v", a.Marks)
+	}
+
+	if err := a.Merge(New(program[:4])); err != ErrDifferentROM {
+		t.Errorf("expected ErrDifferentROM, actually %v", err)
+	}
+}
+
+func TestListing(t *testing.T) {
+	cov := record(t, program)
+
+	buf := &bytes.Buffer{}
+	if err := cov.WriteListing(buf, program); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `; 11 bytes: 6 executed (54.5%), 2 data (18.2%), 3 untouched (27.3%)
+0x0200  A2 08        code       LD I, 0x208
+0x0202  D0 02        code       DRW V0, V0, 2
+0x0204  12 04        code       JP 0x204
+0x0206  00 E0        untouched
+0x0208  F0           data       DB 0xF0  ####....
+0x0209  81           data       DB 0x81  #......#
+0x020a  FF           untouched
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nactually\n%s", expected, buf)
+	}
+
+	if err := cov.WriteListing(buf, program[:4]); err != ErrDifferentROM {
+		t.Errorf("expected ErrDifferentROM for another rom, actually %v", err)
+	}
+}
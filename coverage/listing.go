@@ -0,0 +1,121 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// Untouched bytes listed per line
+const untouchedPerLine = 4
+
+// WriteListing writes rom annotated with its coverage. Executed bytes are
+// disassembled an instruction per line, data a byte per line drawn as a row
+// of a sprite and untouched bytes in hex, with a summary line first
+func (cov *Coverage) WriteListing(w io.Writer, rom []byte) error {
+	if New(rom).ROM != cov.ROM {
+		return ErrDifferentROM
+	}
+
+	executed, data, untouched := cov.Count()
+	fmt.Fprintf(w, "; %d bytes: %d executed (%s), %d data (%s), %d untouched (%s)\n",
+		len(rom), executed, percent(executed, len(rom)), data, percent(data, len(rom)), untouched, percent(untouched, len(rom)))
+
+	for i := 0; i < len(rom); {
+		address := chip8.ProgramStartAddress + i
+		m := cov.Marks[i]
+
+		// Bytes on the line and the text after them
+		n, text := 1, ""
+		switch {
+		case m&Executed != 0:
+			n = cov.instructionSize(rom, i)
+			text = instruction(rom[i : i+n])
+		case m&Data != 0:
+			text = fmt.Sprintf("DB 0x%02X  %s", rom[i], sprite(rom[i]))
+		default:
+			for n < untouchedPerLine && i+n < len(rom) && cov.Marks[i+n] == 0 {
+				n++
+			}
+		}
+
+		// Columns are wide enough for 4 bytes and the longest kind
+		line := fmt.Sprintf("%#04x  %-11s  %-9s  %s", address, hexBytes(rom[i:i+n]), kind(m), text)
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+		i += n
+	}
+	return nil
+}
+
+// instructionSize returns the size of the executed instruction at index i
+// of rom, 4 for F000 long loads and less at the end of the rom
+func (cov *Coverage) instructionSize(rom []byte, i int) int {
+	n := 2
+	if i+1 < len(rom) && rom[i] == 0xF0 && rom[i+1] == 0x00 {
+		n = 4
+	}
+	if i+n > len(rom) {
+		n = len(rom) - i
+	}
+	return n
+}
+
+// instruction disassembles an executed instruction
+func instruction(data []byte) string {
+	if len(data) < 2 {
+		return fmt.Sprintf("DB 0x%02X", data[0])
+	}
+	op := chip8.GetOpcode(data[0], data[1])
+	if op == 0xF000 && len(data) == 4 {
+		return fmt.Sprintf("LD I, 0x%04X", chip8.GetOpcode(data[2], data[3]))
+	}
+	return disassembler.Mnemonic(op)
+}
+
+// kind names the marks of a byte
+func kind(m Mark) string {
+	switch m {
+	case Executed:
+		return "code"
+	case Data:
+		return "data"
+	case Executed | Data:
+		return "code+data"
+	}
+	return "untouched"
+}
+
+// hexBytes formats data as space separated hex
+func hexBytes(data []byte) string {
+	s := make([]string, len(data))
+	for i, b := range data {
+		s[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(s, " ")
+}
+
+// sprite draws b as a row of pixels, # for set bits
+func sprite(b byte) string {
+	var s strings.Builder
+	for bit := 7; bit >= 0; bit-- {
+		if b>>bit&1 != 0 {
+			s.WriteByte('#')
+		} else {
+			s.WriteByte('.')
+		}
+	}
+	return s.String()
+}
+
+// percent formats n as a percentage of total
+func percent(n, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
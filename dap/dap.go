@@ -0,0 +1,385 @@
+// Package dap serves the Debug Adapter Protocol for a Chip 8 driven by a
+// chip8.Runner, so editors can launch and debug roms.
+//
+// The client launches a rom with a launch request giving its path as
+// program. Breakpoints can be set on instruction addresses, on labels or
+// addresses as function breakpoints and, when the rom has a symbol map, on
+// source lines. Stepping is by instruction, with next running CALLs until
+// they return. The variables pane shows the registers, the call stack and
+// the timers, and memory can be read.
+//
+// Requests are handled between frames on the goroutine running the Runner,
+// which must call EndFrame after each frame, Fault when the Runner returns
+// a *chip8.Error, and pause the Runner while Stopped returns true.
+package dap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/symbols"
+)
+
+// The only thread
+const threadID = 1
+
+// Variable references of the scopes
+const (
+	registersReference = iota + 1
+	stackReference
+	timersReference
+)
+
+// Reasons for stopping
+const (
+	reasonEntry      = "entry"
+	reasonBreakpoint = "breakpoint"
+	reasonStep       = "step"
+	reasonPause      = "pause"
+	reasonException  = "exception"
+)
+
+// LaunchArguments are the arguments of the launch request
+type LaunchArguments struct {
+	// Path of the rom to run
+	Program string `json:"program"`
+
+	// Path of the symbol map, defaults to symbols.DefaultPath of Program
+	// if it exists
+	Symbols string `json:"symbols"`
+
+	// Stop before the first instruction
+	StopOnEntry bool `json:"stopOnEntry"`
+}
+
+// Server is a debug adapter for a single client
+type Server struct {
+	r *bufio.Reader
+
+	mu  sync.Mutex // guards writing and seq
+	w   io.Writer
+	seq int
+
+	args   LaunchArguments
+	launch request
+
+	runner *chip8.Runner
+	c      *chip8.Chip8
+
+	// Symbol map of the rom, nil without one, and the directory source paths
+	// in it are relative to
+	syms      *symbols.Map
+	sourceDir string
+
+	// Functions run on the emulator goroutine by EndFrame
+	requests chan func()
+
+	// Only accessed on the emulator goroutine
+	running      bool
+	resuming     bool // skip breakpoints for the instruction at PC when continuing from it
+	until        func(r chip8.Registers) bool
+	configured   bool
+	disconnected bool
+
+	// Breakpoints set by each request, source breakpoints by path
+	sourceBreakpoints      map[string][]breakpoint
+	instructionBreakpoints []breakpoint
+	functionBreakpoints    []breakpoint
+	nextBreakpointID       int
+
+	// Verified breakpoint IDs by address
+	breakpoints map[uint16][]int
+}
+
+// NewServer creates a Server reading requests from r and writing responses
+// and events to w
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		r:                 bufio.NewReader(r),
+		w:                 w,
+		requests:          make(chan func()),
+		sourceBreakpoints: make(map[string][]breakpoint),
+		breakpoints:       make(map[uint16][]int),
+	}
+}
+
+// Launch handles requests until the client sends a launch request, returning
+// its arguments. Start or Fail must then be called to answer it
+func (s *Server) Launch() (LaunchArguments, error) {
+	for {
+		req, err := readMessage(s.r)
+		if err != nil {
+			return LaunchArguments{}, err
+		}
+
+		switch req.Command {
+		case "initialize":
+			s.respond(req, capabilities{
+				SupportsConfigurationDoneRequest: true,
+				SupportsFunctionBreakpoints:      true,
+				SupportsInstructionBreakpoints:   true,
+				SupportsReadMemoryRequest:        true,
+				SupportsDisassembleRequest:       true,
+				SupportsSteppingGranularity:      true,
+				SupportsTerminateRequest:         true,
+			})
+		case "launch":
+			s.launch = req
+			if err := unmarshal(req, &s.args); err != nil {
+				s.Fail(err)
+				continue
+			}
+			if s.args.Program == "" {
+				s.Fail(errors.New("launch requires a program"))
+				continue
+			}
+			return s.args, nil
+		case "disconnect", "terminate":
+			s.respond(req, nil)
+			return LaunchArguments{}, io.EOF
+		default:
+			s.fail(req, fmt.Errorf("%s before launch", req.Command))
+		}
+	}
+}
+
+// Fail answers the launch request with an error
+func (s *Server) Fail(err error) {
+	s.fail(s.launch, err)
+}
+
+// SetSymbols sets the symbol map of a rom compiled from source when it was
+// launched, with source paths relative to dir. It must be called before
+// Start, and is replaced by the symbols file given in the launch request
+func (s *Server) SetSymbols(syms *symbols.Map, dir string) {
+	s.syms, s.sourceDir = syms, dir
+}
+
+// Start answers the launch request and debugs r, which is paused until the
+// client has set its breakpoints. The Runner's Break is set to the server
+// and should not be changed
+func (s *Server) Start(r *chip8.Runner) error {
+	path := s.args.Symbols
+	if path == "" && s.syms == nil {
+		if _, err := os.Stat(symbols.DefaultPath(s.args.Program)); err == nil {
+			path = symbols.DefaultPath(s.args.Program)
+		}
+	}
+	if path != "" {
+		syms, err := symbols.ReadFile(path)
+		if err != nil {
+			s.Fail(err)
+			return err
+		}
+		s.syms, s.sourceDir = syms, filepath.Dir(path)
+	}
+
+	s.runner = r
+	s.c = r.Chip8()
+	r.Break = s.shouldBreak
+	r.Paused = true
+
+	s.respond(s.launch, nil)
+	s.send("initialized", nil)
+	switch {
+	case path != "":
+		s.output("console", fmt.Sprintf("loaded symbols from %s\n", path))
+	case s.syms != nil:
+		s.output("console", fmt.Sprintf("loaded symbols from compiling %s\n", s.args.Program))
+	}
+
+	go s.serve()
+	return nil
+}
+
+// Stopped returns true while execution is stopped, the Runner should be
+// paused until the client continues it
+func (s *Server) Stopped() bool {
+	return !s.running
+}
+
+// Disconnected returns true once the client has disconnected or asked for
+// the rom to be terminated
+func (s *Server) Disconnected() bool {
+	return s.disconnected
+}
+
+// EndFrame should be called on the emulator goroutine after each frame, it
+// reports breakpoints to the client and handles a pending request
+func (s *Server) EndFrame(result chip8.FrameResult) {
+	if result.Stopped && s.running {
+		s.stopAtBreak()
+	}
+
+	select {
+	case fn := <-s.requests:
+		fn()
+	default:
+	}
+}
+
+// Fault should be called on the emulator goroutine when the Runner returns
+// a *chip8.Error, it stops with an exception so the client can inspect the
+// state. Returns false if the client has disconnected
+func (s *Server) Fault(err *chip8.Error) bool {
+	if s.disconnected {
+		return false
+	}
+	s.stop(reasonException, err.Error())
+	return true
+}
+
+// Exited tells the client the rom has finished, err is the error which
+// ended it if any
+func (s *Server) Exited(err error) {
+	code := 0
+	if err != nil {
+		code = 1
+		s.output("stderr", err.Error()+"\n")
+	}
+
+	s.send("exited", map[string]int{"exitCode": code})
+	s.send("terminated", nil)
+}
+
+// serve reads requests until the client disconnects, handling them on the
+// emulator goroutine
+func (s *Server) serve() {
+	for {
+		req, err := readMessage(s.r)
+		if err != nil {
+			s.do(func() {
+				s.disconnected = true
+			})
+			return
+		}
+
+		s.do(func() {
+			s.handle(req)
+		})
+		if req.Command == "disconnect" {
+			return
+		}
+	}
+}
+
+// do runs fn on the emulator goroutine
+func (s *Server) do(fn func()) {
+	done := make(chan struct{})
+	s.requests <- func() {
+		fn()
+		close(done)
+	}
+	<-done
+}
+
+// shouldBreak is the Runner's Break, returning true before an instruction
+// where execution should stop
+func (s *Server) shouldBreak(c *chip8.Chip8) bool {
+	if s.resuming {
+		s.resuming = false
+		return false
+	}
+
+	r := c.Registers()
+	if s.until != nil && s.until(r) {
+		return true
+	}
+	return len(s.breakpoints[r.PC]) > 0
+}
+
+// stopAtBreak stops after shouldBreak returned true, giving the reason
+func (s *Server) stopAtBreak() {
+	if s.until != nil && s.until(s.c.Registers()) {
+		s.stop(reasonStep, "")
+		return
+	}
+	s.stop(reasonBreakpoint, "")
+}
+
+// stop stops execution, telling the client why
+func (s *Server) stop(reason, text string) {
+	s.running = false
+	s.until = nil
+
+	body := stoppedEvent{
+		Reason:            reason,
+		Text:              text,
+		ThreadID:          threadID,
+		AllThreadsStopped: true,
+	}
+	if reason == reasonBreakpoint {
+		body.HitBreakpointIDs = s.breakpoints[s.c.Registers().PC]
+	}
+	if reason == reasonException {
+		body.Description = "error"
+	}
+	s.send("stopped", body)
+}
+
+// resume continues execution, stopping at breakpoints or when until returns true
+func (s *Server) resume(until func(r chip8.Registers) bool) {
+	s.running = true
+	s.resuming = true
+	s.until = until
+}
+
+// respond sends a successful response to req
+func (s *Server) respond(req request, body interface{}) {
+	s.write(&response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    true,
+		Command:    req.Command,
+		Body:       body,
+	})
+}
+
+// fail sends an error response to req
+func (s *Server) fail(req request, err error) {
+	s.write(&response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Command:    req.Command,
+		Message:    err.Error(),
+		Body:       map[string]interface{}{"error": map[string]interface{}{"id": 1, "format": err.Error()}},
+	})
+}
+
+// send sends an event
+func (s *Server) send(name string, body interface{}) {
+	s.write(&event{
+		Type:  "event",
+		Event: name,
+		Body:  body,
+	})
+}
+
+// output sends text to be shown in a category such as console or stderr
+func (s *Server) output(category, text string) {
+	s.send("output", outputEvent{Category: category, Output: text})
+}
+
+// write numbers and writes a response or event
+func (s *Server) write(msg interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+
+	// Nothing can be done about a failed write, the client has gone and
+	// reading will fail too
+	writeMessage(s.w, msg)
+}
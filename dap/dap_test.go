@@ -0,0 +1,401 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/symbols"
+)
+
+// message is any message from the server
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client is a scripted debug adapter client
+type client struct {
+	t        *testing.T
+	w        io.Writer
+	messages chan message
+	seq      int
+
+	// Set when the emulator loop ends after disconnecting
+	done chan bool
+}
+
+// request sends a request and returns its response, events before it are discarded
+func (c *client) request(command string, args interface{}) message {
+	c.t.Helper()
+	c.seq++
+	if err := writeMessage(c.w, map[string]interface{}{
+		"seq": c.seq, "type": "request", "command": command, "arguments": args,
+	}); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		m := c.next()
+		if m.Type == "response" {
+			if m.RequestSeq != c.seq || m.Command != command {
+				c.t.Fatalf("%s: unexpected response %+v", command, m)
+			}
+			return m
+		}
+	}
+}
+
+// expect sends a request which should succeed, decoding the response body into body
+func (c *client) expect(command string, args, body interface{}) {
+	c.t.Helper()
+	m := c.request(command, args)
+	if !m.Success {
+		c.t.Fatalf("%s failed: %s", command, m.Message)
+	}
+	if body != nil {
+		if err := json.Unmarshal(m.Body, body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+// event waits for an event, decoding its body into body
+func (c *client) event(name string, body interface{}) {
+	c.t.Helper()
+	for {
+		m := c.next()
+		if m.Type == "event" && m.Event == name {
+			if body != nil {
+				if err := json.Unmarshal(m.Body, body); err != nil {
+					c.t.Fatal(err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// stopped waits for a stopped event with reason
+func (c *client) stopped(reason string) stoppedEvent {
+	c.t.Helper()
+	var e stoppedEvent
+	c.event("stopped", &e)
+	if e.Reason != reason {
+		c.t.Errorf("expected to stop for %s, actually %+v", reason, e)
+	}
+	return e
+}
+
+// stack returns the stack trace
+func (c *client) stack() []stackFrame {
+	c.t.Helper()
+	var body struct {
+		StackFrames []stackFrame `json:"stackFrames"`
+	}
+	c.expect("stackTrace", map[string]int{"threadId": threadID}, &body)
+	return body.StackFrames
+}
+
+func (c *client) next() message {
+	c.t.Helper()
+	select {
+	case m, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("server closed")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a message")
+	}
+	return message{}
+}
+
+// startServer writes program and its symbol map to a temporary directory
+// and returns a client connected to a Server for them. The server is
+// launched and runs frames as fast as possible once the client launches.
+// If compiled is true the symbol map is given with SetSymbols instead, as
+// for a rom compiled when launched
+func startServer(t *testing.T, program []byte, syms *symbols.Map, compiled bool) (*client, string) {
+	dir := t.TempDir()
+	rom := filepath.Join(dir, "game.ch8")
+	if err := os.WriteFile(rom, program, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !compiled {
+		if err := syms.WriteFile(symbols.DefaultPath(rom)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests, clientW := io.Pipe()
+	clientR, responses := io.Pipe()
+	s := NewServer(requests, responses)
+	if compiled {
+		s.SetSymbols(syms, dir)
+	}
+	t.Cleanup(func() { clientW.Close() })
+
+	cl := &client{t: t, w: clientW, messages: make(chan message, 100), done: make(chan bool)}
+	go func() {
+		r := bufio.NewReader(clientR)
+		for {
+			data, err := readMessageData(r)
+			if err != nil {
+				close(cl.messages)
+				return
+			}
+			var m message
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Error(err)
+			}
+			cl.messages <- m
+		}
+	}()
+
+	go func() {
+		defer close(cl.done)
+		if _, err := s.Launch(); err != nil {
+			t.Error(err)
+			return
+		}
+
+		c := chip8.New(program, chip8.Quirks{})
+		r := chip8.NewRunner(c, 10)
+		if err := s.Start(r); err != nil {
+			t.Error(err)
+			return
+		}
+
+		// Emulator loop, pausing at the end of each frame as the session does
+		for !s.Disconnected() {
+			result, err := r.RunFrame()
+			var fault *chip8.Error
+			if errors.As(err, &fault) && s.Fault(fault) {
+				err = nil
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			s.EndFrame(result)
+			r.Paused = s.Stopped()
+		}
+	}()
+
+	return cl, rom
+}
+
+// readMessageData reads the JSON of a message framed by a Content-Length header
+func readMessageData(r *bufio.Reader) ([]byte, error) {
+	var length int
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == "\r\n" {
+			break
+		}
+		if _, err := fmt.Sscanf(line, "Content-Length: %d", &length); err != nil {
+			return nil, err
+		}
+	}
+
+	data := make([]byte, length)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func TestSession(t *testing.T) {
+	syms := symbols.New()
+	syms.Labels = map[string]uint16{"main": 0x200, "loop": 0x204, "sub": 0x208}
+	syms.Lines = []symbols.Line{
+		{File: "game.asm", Line: 3, Address: 0x200},
+		{File: "game.asm", Line: 4, Address: 0x202},
+		{File: "game.asm", Line: 5, Address: 0x204},
+		{File: "game.asm", Line: 6, Address: 0x206},
+		{File: "game.asm", Line: 9, Address: 0x208},
+		{File: "game.asm", Line: 10, Address: 0x20A},
+	}
+
+	cl, rom := startServer(t, []byte{
+		0x60, 0x05, // 0x200 V0 = 5
+		0xA3, 0x00, // 0x202 I = 0x300
+		0x22, 0x08, // 0x204 Call 0x208
+		0x12, 0x04, // 0x206 Jump to 0x204
+		0x71, 0x01, // 0x208 V1 += 1
+		0x00, 0xEE, // 0x20A Return
+	}, syms, false)
+	source := map[string]string{"path": filepath.Join(filepath.Dir(rom), "game.asm")}
+
+	var caps capabilities
+	cl.expect("initialize", map[string]string{"adapterID": "gochip8"}, &caps)
+	if !caps.SupportsReadMemoryRequest || !caps.SupportsInstructionBreakpoints {
+		t.Errorf("unexpected capabilities %+v", caps)
+	}
+
+	if m := cl.request("threads", nil); m.Success {
+		t.Error("expected requests before launch to fail")
+	}
+
+	cl.expect("launch", map[string]interface{}{"program": rom, "stopOnEntry": true}, nil)
+	cl.event("initialized", nil)
+	cl.expect("configurationDone", nil, nil)
+	cl.stopped(reasonEntry)
+
+	if frames := cl.stack(); len(frames) != 1 || frames[0].Name != "main" || frames[0].Line != 3 || frames[0].Source.Path != source["path"] {
+		t.Errorf("unexpected stack at entry %+v", frames)
+	}
+
+	cl.expect("next", map[string]int{"threadId": threadID}, nil)
+	cl.stopped(reasonStep)
+	if frames := cl.stack(); frames[0].InstructionPointerReference != "0x0202" || frames[0].Line != 4 {
+		t.Errorf("expected to step to 0x0202 line 4, actually %+v", frames[0])
+	}
+
+	// Line 7 has no instruction, the breakpoint moves to line 9
+	var set struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}
+	cl.expect("setBreakpoints", map[string]interface{}{"source": source, "breakpoints": []map[string]int{{"line": 7}}}, &set)
+	if len(set.Breakpoints) != 1 || !set.Breakpoints[0].Verified || set.Breakpoints[0].Line != 9 {
+		t.Fatalf("unexpected breakpoints %+v", set.Breakpoints)
+	}
+
+	cl.expect("continue", map[string]int{"threadId": threadID}, nil)
+	if e := cl.stopped(reasonBreakpoint); len(e.HitBreakpointIDs) != 1 || e.HitBreakpointIDs[0] != set.Breakpoints[0].ID {
+		t.Errorf("expected to hit breakpoint %d, actually %v", set.Breakpoints[0].ID, e.HitBreakpointIDs)
+	}
+
+	// In the subroutine, called from line 5
+	frames := cl.stack()
+	if len(frames) != 2 || frames[0].Name != "sub" || frames[1].Name != "loop" || frames[1].Line != 5 {
+		t.Errorf("unexpected stack in subroutine %+v", frames)
+	}
+
+	cl.expect("stepOut", map[string]int{"threadId": threadID}, nil)
+	cl.stopped(reasonStep)
+	if frames := cl.stack(); len(frames) != 1 || frames[0].Line != 6 {
+		t.Errorf("expected to return to line 6, actually %+v", frames)
+	}
+
+	// Replace the source breakpoint with an instruction breakpoint after the call
+	cl.expect("setBreakpoints", map[string]interface{}{"source": source, "breakpoints": []map[string]int{}}, nil)
+	cl.expect("setInstructionBreakpoints", map[string]interface{}{"breakpoints": []map[string]string{{"instructionReference": "0x206"}}}, nil)
+	cl.expect("continue", map[string]int{"threadId": threadID}, nil)
+	cl.stopped(reasonBreakpoint)
+
+	var vars struct {
+		Variables []variable `json:"variables"`
+	}
+	cl.expect("variables", map[string]int{"variablesReference": registersReference}, &vars)
+	if len(vars.Variables) != 19 || vars.Variables[1].Value != "0x02 (2)" || vars.Variables[16].Value != "0x0300" {
+		t.Errorf("unexpected registers %+v", vars.Variables)
+	}
+	cl.expect("variables", map[string]int{"variablesReference": timersReference}, &vars)
+	if len(vars.Variables) != 3 || vars.Variables[0].Name != "delay" {
+		t.Errorf("unexpected timers %+v", vars.Variables)
+	}
+
+	var mem readMemoryResponse
+	cl.expect("readMemory", map[string]interface{}{"memoryReference": "0x200", "count": 4}, &mem)
+	if mem.Address != "0x0200" || base64.StdEncoding.EncodeToString(mem.Data) != base64.StdEncoding.EncodeToString([]byte{0x60, 0x05, 0xA3, 0x00}) {
+		t.Errorf("unexpected memory %+v", mem)
+	}
+	cl.expect("readMemory", map[string]interface{}{"memoryReference": "0xFFFE", "count": 4}, &mem)
+	if len(mem.Data) != 2 || mem.UnreadableBytes != 2 {
+		t.Errorf("expected 2 readable bytes at the end of memory, actually %+v", mem)
+	}
+
+	// Pause a running rom
+	cl.expect("setInstructionBreakpoints", map[string]interface{}{"breakpoints": []map[string]string{}}, nil)
+	cl.expect("continue", map[string]int{"threadId": threadID}, nil)
+	if m := cl.request("next", map[string]int{"threadId": threadID}); m.Success {
+		t.Error("expected next while running to fail")
+	}
+	cl.expect("pause", map[string]int{"threadId": threadID}, nil)
+	cl.stopped(reasonPause)
+
+	cl.expect("disconnect", nil, nil)
+	select {
+	case <-cl.done:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the emulator to stop after disconnecting")
+	}
+}
+
+func TestFault(t *testing.T) {
+	cl, rom := startServer(t, []byte{
+		0x60, 0x05, // 0x200 V0 = 5
+		0x00, 0xEE, // 0x202 Return with an empty stack
+	}, symbols.New(), false)
+
+	cl.expect("initialize", map[string]string{"adapterID": "gochip8"}, nil)
+	cl.expect("launch", map[string]interface{}{"program": rom}, nil)
+	cl.event("initialized", nil)
+	cl.expect("configurationDone", nil, nil)
+
+	// Stops at the faulting instruction instead of exiting
+	if e := cl.stopped(reasonException); e.Text == "" {
+		t.Error("expected the fault as the stop text")
+	}
+	if frames := cl.stack(); frames[0].InstructionPointerReference != "0x0202" {
+		t.Errorf("expected to stop at 0x0202, actually %+v", frames[0])
+	}
+
+	// Continuing faults again
+	cl.expect("continue", map[string]int{"threadId": threadID}, nil)
+	cl.stopped(reasonException)
+
+	cl.expect("disconnect", nil, nil)
+}
+
+func TestCompiledSymbols(t *testing.T) {
+	syms := symbols.New()
+	syms.Labels = map[string]uint16{"main": 0x200}
+	syms.Lines = []symbols.Line{
+		{File: "game.8o", Line: 2, Address: 0x200},
+		{File: "game.8o", Line: 3, Address: 0x202},
+	}
+
+	cl, rom := startServer(t, []byte{
+		0x60, 0x05, // 0x200 V0 = 5
+		0x12, 0x02, // 0x202 Jump to 0x202
+	}, syms, true)
+	source := map[string]string{"path": filepath.Join(filepath.Dir(rom), "game.8o")}
+
+	cl.expect("initialize", map[string]string{"adapterID": "gochip8"}, nil)
+	cl.expect("launch", map[string]interface{}{"program": rom, "stopOnEntry": true}, nil)
+	cl.event("initialized", nil)
+
+	var set struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}
+	cl.expect("setBreakpoints", map[string]interface{}{"source": source, "breakpoints": []map[string]int{{"line": 3}}}, &set)
+	if len(set.Breakpoints) != 1 || !set.Breakpoints[0].Verified {
+		t.Fatalf("expected a verified breakpoint from the compiled symbols, actually %+v", set.Breakpoints)
+	}
+
+	cl.expect("configurationDone", nil, nil)
+	cl.stopped(reasonEntry)
+	if frames := cl.stack(); frames[0].Name != "main" || frames[0].Source.Path != source["path"] {
+		t.Errorf("unexpected stack %+v", frames)
+	}
+
+	cl.expect("disconnect", nil, nil)
+}
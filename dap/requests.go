@@ -0,0 +1,472 @@
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// handle answers a request, on the emulator goroutine
+func (s *Server) handle(req request) {
+	var body interface{}
+	var err error
+
+	// Stopped events are sent after the response
+	var then func()
+
+	switch req.Command {
+	case "configurationDone":
+		then = s.configurationDone()
+	case "setBreakpoints":
+		body, err = s.setBreakpoints(req)
+	case "setInstructionBreakpoints":
+		body, err = s.setInstructionBreakpoints(req)
+	case "setFunctionBreakpoints":
+		body, err = s.setFunctionBreakpoints(req)
+	case "setExceptionBreakpoints":
+		body = map[string]interface{}{}
+	case "threads":
+		body = map[string][]thread{"threads": {{ID: threadID, Name: "chip8"}}}
+	case "stackTrace":
+		body, err = s.stackTrace(req)
+	case "scopes":
+		body = map[string][]scope{"scopes": {
+			{Name: "Registers", VariablesReference: registersReference},
+			{Name: "Stack", VariablesReference: stackReference},
+			{Name: "Timers", VariablesReference: timersReference},
+		}}
+	case "variables":
+		body, err = s.variables(req)
+	case "readMemory":
+		body, err = s.readMemory(req)
+	case "disassemble":
+		body, err = s.disassemble(req)
+	case "continue":
+		if err = s.whileStopped(); err == nil {
+			s.resume(nil)
+			body = map[string]bool{"allThreadsContinued": true}
+		}
+	case "next":
+		then, err = s.next()
+	case "stepIn":
+		then, err = s.stepIn()
+	case "stepOut":
+		err = s.stepOut()
+	case "pause":
+		if s.running {
+			then = func() { s.stop(reasonPause, "") }
+		}
+	case "disconnect", "terminate":
+		s.disconnected = true
+	default:
+		err = fmt.Errorf("unsupported request %s", req.Command)
+	}
+
+	if err != nil {
+		s.fail(req, err)
+		return
+	}
+	s.respond(req, body)
+
+	if then != nil {
+		then()
+	}
+}
+
+// unmarshal decodes the arguments of req into args
+func unmarshal(req request, args interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Arguments, args); err != nil {
+		return fmt.Errorf("invalid %s arguments: %w", req.Command, err)
+	}
+	return nil
+}
+
+// whileStopped returns an error if execution is running
+func (s *Server) whileStopped() error {
+	if s.running {
+		return errors.New("running, pause first")
+	}
+	return nil
+}
+
+// configurationDone starts running, or stops on entry
+func (s *Server) configurationDone() func() {
+	if s.configured {
+		return nil
+	}
+	s.configured = true
+
+	if s.args.StopOnEntry {
+		return func() { s.stop(reasonEntry, "") }
+	}
+	s.running = true
+	return nil
+}
+
+// newBreakpoint returns a verified breakpoint at address
+func (s *Server) newBreakpoint(address uint16) breakpoint {
+	s.nextBreakpointID++
+	b := breakpoint{
+		ID:                   s.nextBreakpointID,
+		Verified:             true,
+		InstructionReference: formatAddress(int(address)),
+	}
+	if s.syms != nil {
+		if l, ok := s.syms.Line(address); ok {
+			b.Source = s.source(l.File)
+			b.Line = l.Line
+		}
+	}
+	return b
+}
+
+// unverifiedBreakpoint returns a breakpoint which couldn't be set
+func (s *Server) unverifiedBreakpoint(err error) breakpoint {
+	s.nextBreakpointID++
+	return breakpoint{
+		ID:      s.nextBreakpointID,
+		Message: err.Error(),
+	}
+}
+
+func (s *Server) setBreakpoints(req request) (interface{}, error) {
+	var args setBreakpointsArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	var set []breakpoint
+	for _, b := range args.Breakpoints {
+		if s.syms == nil {
+			set = append(set, s.unverifiedBreakpoint(fmt.Errorf("no symbol map for %s", filepath.Base(s.args.Program))))
+			continue
+		}
+
+		l, ok := s.syms.Address(args.Source.Path, b.Line)
+		if !ok {
+			set = append(set, s.unverifiedBreakpoint(fmt.Errorf("no instruction at or after line %d", b.Line)))
+			continue
+		}
+		set = append(set, s.newBreakpoint(l.Address))
+	}
+
+	s.sourceBreakpoints[args.Source.Path] = set
+	s.updateBreakpoints()
+	return map[string][]breakpoint{"breakpoints": set}, nil
+}
+
+func (s *Server) setInstructionBreakpoints(req request) (interface{}, error) {
+	var args setInstructionBreakpointsArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	var set []breakpoint
+	for _, b := range args.Breakpoints {
+		address, err := parseAddress(b.InstructionReference)
+		if err != nil {
+			set = append(set, s.unverifiedBreakpoint(err))
+			continue
+		}
+		set = append(set, s.newBreakpoint(address+uint16(b.Offset)))
+	}
+
+	s.instructionBreakpoints = set
+	s.updateBreakpoints()
+	return map[string][]breakpoint{"breakpoints": set}, nil
+}
+
+func (s *Server) setFunctionBreakpoints(req request) (interface{}, error) {
+	var args setFunctionBreakpointsArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	var set []breakpoint
+	for _, b := range args.Breakpoints {
+		address, ok := uint16(0), false
+		if s.syms != nil {
+			address, ok = s.syms.Labels[b.Name]
+		}
+		if !ok {
+			var err error
+			if address, err = parseAddress(b.Name); err != nil {
+				set = append(set, s.unverifiedBreakpoint(fmt.Errorf("no label or address %q", b.Name)))
+				continue
+			}
+		}
+		set = append(set, s.newBreakpoint(address))
+	}
+
+	s.functionBreakpoints = set
+	s.updateBreakpoints()
+	return map[string][]breakpoint{"breakpoints": set}, nil
+}
+
+// updateBreakpoints rebuilds the breakpoint addresses from every request
+func (s *Server) updateBreakpoints() {
+	s.breakpoints = make(map[uint16][]int)
+
+	add := func(set []breakpoint) {
+		for _, b := range set {
+			if !b.Verified {
+				continue
+			}
+			address, _ := parseAddress(b.InstructionReference)
+			s.breakpoints[address] = append(s.breakpoints[address], b.ID)
+		}
+	}
+
+	for _, set := range s.sourceBreakpoints {
+		add(set)
+	}
+	add(s.instructionBreakpoints)
+	add(s.functionBreakpoints)
+}
+
+// source returns the source of a file in the symbol map
+func (s *Server) source(file string) *source {
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.sourceDir, path)
+	}
+	return &source{Name: filepath.Base(file), Path: path}
+}
+
+// frame returns stack frame id at address
+func (s *Server) frame(id int, address uint16) stackFrame {
+	f := stackFrame{
+		ID:                          id,
+		Name:                        formatAddress(int(address)),
+		InstructionPointerReference: formatAddress(int(address)),
+	}
+
+	if s.syms != nil {
+		f.Name = s.syms.Symbolize(address)
+		if l, ok := s.syms.Line(address); ok {
+			f.Source = s.source(l.File)
+			f.Line = l.Line
+			f.Column = 1
+		}
+	}
+	return f
+}
+
+func (s *Server) stackTrace(req request) (interface{}, error) {
+	var args stackTraceArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	// The current instruction, then the CALL before each return address,
+	// newest first
+	r := s.c.Registers()
+	frames := []stackFrame{s.frame(0, r.PC)}
+	for i := int(r.SP) - 1; i >= 0; i-- {
+		frames = append(frames, s.frame(int(r.SP)-i, r.Stack[i]-2))
+	}
+	total := len(frames)
+
+	if args.StartFrame > len(frames) {
+		args.StartFrame = len(frames)
+	}
+	frames = frames[args.StartFrame:]
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+
+	return map[string]interface{}{
+		"stackFrames": frames,
+		"totalFrames": total,
+	}, nil
+}
+
+func (s *Server) variables(req request) (interface{}, error) {
+	var args variablesArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	r := s.c.Registers()
+	vars := []variable{}
+	switch args.VariablesReference {
+	case registersReference:
+		for i, v := range r.V {
+			vars = append(vars, variable{Name: fmt.Sprintf("V%X", i), Value: fmt.Sprintf("%#02x (%d)", v, v), Type: "byte"})
+		}
+		vars = append(vars,
+			variable{Name: "I", Value: formatAddress(int(r.I)), Type: "address", MemoryReference: formatAddress(int(r.I))},
+			variable{Name: "PC", Value: formatAddress(int(r.PC)), Type: "address", MemoryReference: formatAddress(int(r.PC))},
+			variable{Name: "SP", Value: strconv.Itoa(int(r.SP)), Type: "int"},
+		)
+	case stackReference:
+		// Return addresses, newest first
+		for i := int(r.SP) - 1; i >= 0; i-- {
+			value := formatAddress(int(r.Stack[i]))
+			if s.syms != nil {
+				value += " " + s.syms.Symbolize(r.Stack[i])
+			}
+			vars = append(vars, variable{Name: fmt.Sprintf("#%d", i), Value: value, Type: "address", MemoryReference: formatAddress(int(r.Stack[i]))})
+		}
+	case timersReference:
+		vars = append(vars,
+			variable{Name: "delay", Value: strconv.Itoa(int(r.Delay)), Type: "byte"},
+			variable{Name: "sound", Value: strconv.Itoa(int(r.Sound)), Type: "byte"},
+			variable{Name: "waiting for key", Value: strconv.FormatBool(s.c.WaitingForKey()), Type: "bool"},
+		)
+	default:
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+
+	return map[string][]variable{"variables": vars}, nil
+}
+
+func (s *Server) readMemory(req request) (interface{}, error) {
+	var args readMemoryArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	address, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+
+	start := int(address) + args.Offset
+	if start < 0 || start >= chip8.MemorySize || args.Count < 0 {
+		return readMemoryResponse{Address: formatAddress(start), UnreadableBytes: args.Count, Data: []byte{}}, nil
+	}
+
+	// Memory past the end is unreadable
+	n := args.Count
+	if start+n > chip8.MemorySize {
+		n = chip8.MemorySize - start
+	}
+	data, err := s.c.ReadMemory(uint16(start), n)
+	if err != nil {
+		return nil, err
+	}
+
+	return readMemoryResponse{
+		Address:         formatAddress(start),
+		UnreadableBytes: args.Count - n,
+		Data:            data,
+	}, nil
+}
+
+func (s *Server) disassemble(req request) (interface{}, error) {
+	var args disassembleArguments
+	if err := unmarshal(req, &args); err != nil {
+		return nil, err
+	}
+
+	address, err := parseAddress(args.MemoryReference)
+	if err != nil {
+		return nil, err
+	}
+
+	var instructions []disassembledInstruction
+	start := int(address) + args.Offset + args.InstructionOffset*2
+	for i := 0; i < args.InstructionCount; i++ {
+		a := start + i*2
+		inst := disassembledInstruction{Address: formatAddress(a)}
+
+		if a < 0 || a > chip8.MemorySize-2 {
+			inst.Instruction = "??"
+			inst.PresentationHint = "invalid"
+			instructions = append(instructions, inst)
+			continue
+		}
+
+		data, err := s.c.ReadMemory(uint16(a), 2)
+		if err != nil {
+			return nil, err
+		}
+
+		op := chip8.GetOpcode(data[0], data[1])
+		inst.InstructionBytes = fmt.Sprintf("%02x %02x", data[0], data[1])
+		inst.Instruction = fmt.Sprintf("%#04x ; %s", op, chip8.DecodeOpcode(op).Description)
+		if s.syms != nil {
+			for name, labelAddress := range s.syms.Labels {
+				if int(labelAddress) == a {
+					inst.Symbol = name
+				}
+			}
+			if l, ok := s.syms.Line(uint16(a)); ok {
+				inst.Location = s.source(l.File)
+				inst.Line = l.Line
+			}
+		}
+		instructions = append(instructions, inst)
+	}
+
+	return map[string][]disassembledInstruction{"instructions": instructions}, nil
+}
+
+// step executes an instruction, returning a function to stop after it.
+// An instruction which can't execute, waiting for a key or the display,
+// runs frames until it has and stops before the next
+func (s *Server) step() func() {
+	ok, err := s.c.Step()
+	switch {
+	case err != nil:
+		return func() { s.stop(reasonException, err.Error()) }
+	case !ok:
+		s.resume(func(chip8.Registers) bool { return true })
+		s.resuming = false
+		return nil
+	}
+	return func() { s.stop(reasonStep, "") }
+}
+
+func (s *Server) stepIn() (func(), error) {
+	if err := s.whileStopped(); err != nil {
+		return nil, err
+	}
+	return s.step(), nil
+}
+
+func (s *Server) next() (func(), error) {
+	if err := s.whileStopped(); err != nil {
+		return nil, err
+	}
+
+	r := s.c.Registers()
+	data, err := s.c.ReadMemory(r.PC, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anything other than a CALL is a single step
+	if data[0]>>4 != 0x2 {
+		return s.step(), nil
+	}
+
+	s.resume(func(now chip8.Registers) bool {
+		return now.SP == r.SP && now.PC == r.PC+2
+	})
+	return nil, nil
+}
+
+func (s *Server) stepOut() error {
+	if err := s.whileStopped(); err != nil {
+		return err
+	}
+
+	sp := s.c.Registers().SP
+	if sp == 0 {
+		return errors.New("not in a subroutine")
+	}
+
+	s.resume(func(now chip8.Registers) bool {
+		return now.SP < sp
+	})
+	return nil
+}
@@ -0,0 +1,354 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+const (
+	// Bytes shown by mem without a length
+	defaultDumpLength = 64
+
+	// Instructions listed before and after the address by list
+	listBefore = 5
+	listAfter  = 6
+)
+
+// command is a debugger command, run with the words following its name
+type command struct {
+	names []string
+	usage string
+	help  string
+
+	// Commands which change execution can only run while stopped
+	stopped bool
+
+	run func(d *Debugger, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{[]string{"help", "h", "?"}, "", "show this help", false, (*Debugger).help},
+		{[]string{"break", "b"}, "<addr> | op <pattern>", "break at an address, or an opcode pattern such as Dxyn or 00EE", false, (*Debugger).breakpoint},
+		{[]string{"delete", "d"}, "[n]", "delete breakpoint n, or all breakpoints", false, (*Debugger).delete},
+		{[]string{"breaks", "info"}, "", "list breakpoints", false, (*Debugger).breaks},
+		{[]string{"step", "s"}, "[n]", "execute n instructions, default 1", true, (*Debugger).step},
+		{[]string{"next", "n"}, "", "execute an instruction, running CALLs until they return", true, (*Debugger).next},
+		{[]string{"finish", "out"}, "", "run until the current subroutine returns", true, (*Debugger).finish},
+		{[]string{"continue", "c"}, "", "run until a breakpoint", true, (*Debugger).cont},
+		{[]string{"stop"}, "", "stop running", false, (*Debugger).interrupt},
+		{[]string{"regs", "r"}, "", "show V0-VF, I, PC, SP and the timers", false, (*Debugger).regs},
+		{[]string{"stack", "bt"}, "", "show the call stack", false, (*Debugger).stack},
+		{[]string{"keys", "k"}, "", "show the pressed keys", false, (*Debugger).keys},
+		{[]string{"mem", "x"}, "<addr> [len]", "hex dump len bytes of memory, default 64", false, (*Debugger).mem},
+		{[]string{"list", "l"}, "[addr]", "disassemble around addr, default PC", false, (*Debugger).list},
+		{[]string{"quit", "q"}, "", "quit", false, func(*Debugger, []string) error { return ErrQuit }},
+	}
+}
+
+// Exec runs a command line, an empty line repeats the last command.
+// Errors from commands are printed, only ErrQuit is returned
+func (d *Debugger) Exec(line string) error {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		words = d.last
+	}
+	if len(words) == 0 {
+		d.prompt()
+		return nil
+	}
+	d.last = words
+
+	err := d.exec(words)
+	if err == ErrQuit {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(d.out, "error:", err.Error())
+	}
+
+	d.prompt()
+	return nil
+}
+
+// exec runs the command named by the first word
+func (d *Debugger) exec(words []string) error {
+	for _, cmd := range commands {
+		for _, name := range cmd.names {
+			if name != words[0] {
+				continue
+			}
+
+			if cmd.stopped && d.running {
+				return errors.New("running, stop first")
+			}
+			return cmd.run(d, words[1:])
+		}
+	}
+	return fmt.Errorf("unknown command %q, try help", words[0])
+}
+
+// prompt writes the prompt while stopped, output while running would
+// interleave with it
+func (d *Debugger) prompt() {
+	if !d.running {
+		d.Prompt()
+	}
+}
+
+func (d *Debugger) help(args []string) error {
+	for _, cmd := range commands {
+		fmt.Fprintf(d.out, "  %-28s %s\n", strings.Join(cmd.names, ", ")+" "+cmd.usage, cmd.help)
+	}
+	return nil
+}
+
+func (d *Debugger) breakpoint(args []string) error {
+	var b Breakpoint
+	switch {
+	case len(args) == 2 && args[0] == "op":
+		var err error
+		if b, err = ParsePattern(args[1]); err != nil {
+			return err
+		}
+	case len(args) == 1:
+		address, err := parseAddress(args[0])
+		if err != nil {
+			return err
+		}
+		b.Address = address
+	default:
+		return errors.New("usage: break <addr> | op <pattern>")
+	}
+
+	d.Breakpoints = append(d.Breakpoints, b)
+	fmt.Fprintf(d.out, "breakpoint %d at %s\n", len(d.Breakpoints)-1, b)
+	return nil
+}
+
+func (d *Debugger) delete(args []string) error {
+	if len(args) == 0 {
+		d.Breakpoints = nil
+		fmt.Fprintln(d.out, "deleted all breakpoints")
+		return nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 || n >= len(d.Breakpoints) {
+		return fmt.Errorf("no breakpoint %s", args[0])
+	}
+	d.Breakpoints = append(d.Breakpoints[:n], d.Breakpoints[n+1:]...)
+	return nil
+}
+
+func (d *Debugger) breaks(args []string) error {
+	if len(d.Breakpoints) == 0 {
+		fmt.Fprintln(d.out, "no breakpoints")
+	}
+	for i, b := range d.Breakpoints {
+		fmt.Fprintf(d.out, "%d: %s\n", i, b)
+	}
+	return nil
+}
+
+func (d *Debugger) step(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("invalid count %q", args[0])
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		ok, err := d.c.Step()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(d.out, "not executed, waiting for a key or the display, continue to run frames")
+			break
+		}
+	}
+
+	d.printLocation()
+	return nil
+}
+
+func (d *Debugger) next(args []string) error {
+	r := d.c.Registers()
+	op, err := d.opcode(r.PC)
+	if err != nil {
+		return err
+	}
+
+	// Anything other than a CALL is a single step
+	if op>>12 != 0x2 {
+		return d.step(nil)
+	}
+
+	d.resume(func(now chip8.Registers) bool {
+		return now.SP == r.SP && now.PC == r.PC+2
+	})
+	return nil
+}
+
+func (d *Debugger) finish(args []string) error {
+	sp := d.c.Registers().SP
+	if sp == 0 {
+		return errors.New("not in a subroutine")
+	}
+
+	d.resume(func(now chip8.Registers) bool {
+		return now.SP < sp
+	})
+	return nil
+}
+
+func (d *Debugger) cont(args []string) error {
+	d.resume(nil)
+	return nil
+}
+
+func (d *Debugger) interrupt(args []string) error {
+	if !d.running {
+		return errors.New("not running")
+	}
+	d.running = false
+	d.until = nil
+	d.printLocation()
+	return nil
+}
+
+func (d *Debugger) regs(args []string) error {
+	r := d.c.Registers()
+	fmt.Fprintf(d.out, "PC %#04x  I %#04x  SP %d  DT %#02x  ST %#02x\n", r.PC, r.I, r.SP, r.Delay, r.Sound)
+	for i, v := range r.V {
+		fmt.Fprintf(d.out, "V%X %02x", i, v)
+		if i%8 == 7 {
+			fmt.Fprintln(d.out)
+		} else {
+			fmt.Fprint(d.out, "  ")
+		}
+	}
+	return nil
+}
+
+func (d *Debugger) stack(args []string) error {
+	r := d.c.Registers()
+	fmt.Fprintf(d.out, "#0 %s\n", d.disassemble(r.PC))
+
+	// Return addresses, newest first
+	for i := int(r.SP) - 1; i >= 0; i-- {
+		fmt.Fprintf(d.out, "#%d %s\n", int(r.SP)-i, d.disassemble(r.Stack[i]))
+	}
+	return nil
+}
+
+func (d *Debugger) keys(args []string) error {
+	var pressed []string
+	for k, down := range d.c.Keys() {
+		if down {
+			pressed = append(pressed, fmt.Sprintf("%X", k))
+		}
+	}
+
+	if len(pressed) == 0 {
+		fmt.Fprintln(d.out, "no keys pressed")
+	} else {
+		fmt.Fprintln(d.out, "pressed:", strings.Join(pressed, " "))
+	}
+
+	if d.c.WaitingForKey() {
+		fmt.Fprintln(d.out, "waiting for a key (Fx0A)")
+	}
+	return nil
+}
+
+func (d *Debugger) mem(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: mem <addr> [len]")
+	}
+
+	address, err := parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	n := defaultDumpLength
+	if len(args) > 1 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			return fmt.Errorf("invalid length %q", args[1])
+		}
+	}
+
+	// Truncate at the end of memory
+	if int(address)+n > chip8.MemorySize {
+		n = chip8.MemorySize - int(address)
+	}
+	data, err := d.c.ReadMemory(address, n)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(data); i += 16 {
+		line := data[i:]
+		if len(line) > 16 {
+			line = line[:16]
+		}
+
+		fmt.Fprintf(d.out, "%#04x ", int(address)+i)
+		for j := 0; j < 16; j++ {
+			if j < len(line) {
+				fmt.Fprintf(d.out, " %02x", line[j])
+			} else {
+				fmt.Fprint(d.out, "   ")
+			}
+		}
+
+		fmt.Fprint(d.out, "  |")
+		for _, b := range line {
+			if b < 0x20 || b > 0x7E {
+				b = '.'
+			}
+			fmt.Fprintf(d.out, "%c", b)
+		}
+		fmt.Fprintln(d.out, "|")
+	}
+	return nil
+}
+
+func (d *Debugger) list(args []string) error {
+	pc := d.c.Registers().PC
+	address := pc
+	if len(args) > 0 {
+		var err error
+		if address, err = parseAddress(args[0]); err != nil {
+			return err
+		}
+	}
+
+	start := int(address) - 2*listBefore
+	if start < 0 {
+		start = int(address) % 2
+	}
+	end := int(address) + 2*listAfter
+	if end > chip8.MemorySize-2 {
+		end = chip8.MemorySize - 2
+	}
+
+	for a := start; a <= end; a += 2 {
+		marker := "  "
+		if uint16(a) == pc {
+			marker = "=>"
+		}
+		fmt.Fprintf(d.out, "%s %s\n", marker, d.disassemble(uint16(a)))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Package debugger is an interactive debugger for a Chip 8 driven by a
+// chip8.Runner. Commands are executed between frames so the frontend keeps
+// running while the program is stopped
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// ErrQuit is returned by Exec for the quit command
+var ErrQuit = errors.New("debugger: quit")
+
+// Characters matching any hex digit in an opcode pattern, e.g. Dxyn
+const wildcards = "?xXyYnNkK"
+
+// Breakpoint stops execution before an instruction at Address, or an
+// instruction matching an opcode pattern
+type Breakpoint struct {
+	Address uint16
+
+	// Opcode patterns match when op&Mask == Value
+	Opcode      bool
+	Mask, Value uint16
+	Pattern     string
+}
+
+// ParsePattern creates a Breakpoint for an opcode pattern of 4 hex digits,
+// any of which may be a wildcard such as x, y, n, k or ?
+func ParsePattern(pattern string) (Breakpoint, error) {
+	if len(pattern) != 4 {
+		return Breakpoint{}, fmt.Errorf("opcode pattern %q must be 4 digits", pattern)
+	}
+
+	b := Breakpoint{Opcode: true, Pattern: pattern}
+	for _, r := range pattern {
+		b.Mask <<= 4
+		b.Value <<= 4
+		if strings.ContainsRune(wildcards, r) {
+			continue
+		}
+
+		digit, err := strconv.ParseUint(string(r), 16, 4)
+		if err != nil {
+			return Breakpoint{}, fmt.Errorf("opcode pattern %q has invalid digit %q", pattern, r)
+		}
+		b.Mask |= 0xF
+		b.Value |= uint16(digit)
+	}
+	return b, nil
+}
+
+// Matches returns true if the instruction op at pc hits the breakpoint
+func (b Breakpoint) Matches(pc, op uint16) bool {
+	if b.Opcode {
+		return op&b.Mask == b.Value
+	}
+	return pc == b.Address
+}
+
+func (b Breakpoint) String() string {
+	if b.Opcode {
+		return "opcode " + b.Pattern
+	}
+	return fmt.Sprintf("address %#04x", b.Address)
+}
+
+// Debugger controls a Runner, stopping it at breakpoints and executing
+// commands while stopped. The Runner's Break is set to the debugger and
+// should not be changed
+type Debugger struct {
+	runner *chip8.Runner
+	c      *chip8.Chip8
+	out    io.Writer
+
+	Breakpoints []Breakpoint
+
+	running bool
+
+	// Skip breakpoints for the instruction at PC when continuing from it
+	resuming bool
+
+	// Condition for next and finish to stop, nil when not stepping over
+	until func(r chip8.Registers) bool
+
+	// Words of the last command, repeated by an empty line
+	last []string
+}
+
+// New creates a Debugger for r writing output to out, execution is stopped
+// before the first instruction with r paused
+func New(r *chip8.Runner, out io.Writer) *Debugger {
+	d := &Debugger{
+		runner: r,
+		c:      r.Chip8(),
+		out:    out,
+	}
+	r.Break = d.shouldBreak
+	r.Paused = true
+
+	d.printLocation()
+	d.Prompt()
+	return d
+}
+
+// Stopped returns true while execution is stopped, the runner should be
+// paused until a command continues it
+func (d *Debugger) Stopped() bool {
+	return !d.running
+}
+
+// EndFrame should be called after each frame with its result
+func (d *Debugger) EndFrame(result chip8.FrameResult) {
+	if result.Stopped {
+		d.stop()
+	}
+}
+
+// Prompt writes the command prompt
+func (d *Debugger) Prompt() {
+	fmt.Fprint(d.out, "(gochip8) ")
+}
+
+// shouldBreak is the Runner's Break, returning true before an instruction
+// where execution should stop
+func (d *Debugger) shouldBreak(c *chip8.Chip8) bool {
+	if d.resuming {
+		d.resuming = false
+		return false
+	}
+
+	r := c.Registers()
+	if d.until != nil && d.until(r) {
+		return true
+	}
+
+	op, err := d.opcode(r.PC)
+	if err != nil {
+		return true
+	}
+	for _, b := range d.Breakpoints {
+		if b.Matches(r.PC, op) {
+			fmt.Fprintf(d.out, "\nbreakpoint %s\n", b)
+			return true
+		}
+	}
+	return false
+}
+
+// stop stops execution and shows where
+func (d *Debugger) stop() {
+	d.running = false
+	d.until = nil
+	d.printLocation()
+	d.Prompt()
+}
+
+// resume continues execution, stopping at breakpoints or when until returns true
+func (d *Debugger) resume(until func(r chip8.Registers) bool) {
+	d.running = true
+	d.resuming = true
+	d.until = until
+}
+
+// opcode returns the opcode at address
+func (d *Debugger) opcode(address uint16) (uint16, error) {
+	data, err := d.c.ReadMemory(address, 2)
+	if err != nil {
+		return 0, err
+	}
+	return chip8.GetOpcode(data[0], data[1]), nil
+}
+
+// printLocation prints the instruction at PC
+func (d *Debugger) printLocation() {
+	pc := d.c.Registers().PC
+	fmt.Fprintf(d.out, "stopped at %s\n", d.disassemble(pc))
+}
+
+// disassemble formats the instruction at address
+func (d *Debugger) disassemble(address uint16) string {
+	op, err := d.opcode(address)
+	if err != nil {
+		return fmt.Sprintf("%#04x: %s", address, err)
+	}
+	return fmt.Sprintf("%#04x: %#04x ; %s", address, op, chip8.DecodeOpcode(op).Description)
+}
+
+// parseAddress parses a hex address, with or without a 0x prefix
+func parseAddress(s string) (uint16, error) {
+	address, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return uint16(address), nil
+}
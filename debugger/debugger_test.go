@@ -0,0 +1,163 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// program calls a subroutine which draws, then loops
+var program = []byte{
+	0x60, 0x05, // 0x200 V0 = 5
+	0x22, 0x08, // 0x202 Call 0x208
+	0x61, 0x01, // 0x204 V1 = 1
+	0x12, 0x04, // 0x206 Jump to 0x204
+	0xA0, 0x00, // 0x208 I = 0
+	0xD0, 0x05, // 0x20A Draw
+	0x00, 0xEE, // 0x20C Return
+}
+
+func newTestDebugger() (*Debugger, *chip8.Runner, *bytes.Buffer) {
+	c := chip8.New(program, chip8.Quirks{})
+	r := chip8.NewRunner(c, 10)
+	out := &bytes.Buffer{}
+	return New(r, out), r, out
+}
+
+// run runs frames until the debugger stops
+func run(t *testing.T, d *Debugger, r *chip8.Runner) {
+	for i := 0; i < 10 && !d.Stopped(); i++ {
+		r.Paused = d.Stopped()
+		result, err := r.RunFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.EndFrame(result)
+	}
+
+	if !d.Stopped() {
+		t.Fatal("debugger did not stop")
+	}
+}
+
+func exec(t *testing.T, d *Debugger, lines ...string) {
+	for _, line := range lines {
+		if err := d.Exec(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	b, err := ParsePattern("Dxyn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !b.Matches(0, 0xD015) || b.Matches(0, 0xC015) {
+		t.Errorf("unexpected matches for mask %#04x value %#04x", b.Mask, b.Value)
+	}
+
+	if _, err := ParsePattern("DG00"); err == nil {
+		t.Error("expected error for invalid digit")
+	}
+}
+
+func TestStopsOnStart(t *testing.T) {
+	d, r, _ := newTestDebugger()
+
+	// Nothing runs until a command continues
+	result, err := r.RunFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.EndFrame(result)
+	if reg := r.Chip8().Registers(); result.Executed != 0 || reg.PC != 0x200 || reg.V[0] != 0 {
+		t.Errorf("expected the first frame to execute nothing, actually %d instructions to %+v", result.Executed, reg)
+	}
+}
+
+func TestBreakAddress(t *testing.T) {
+	d, r, out := newTestDebugger()
+
+	exec(t, d, "break 0x20a", "continue")
+	run(t, d, r)
+
+	if pc := r.Chip8().Registers().PC; pc != 0x20A {
+		t.Errorf("expected to stop at 0x20a, actually %#04x", pc)
+	}
+
+	// Continuing from a breakpoint doesn't stop at it again
+	exec(t, d, "delete", "break op 1NNN", "continue")
+	run(t, d, r)
+
+	if pc := r.Chip8().Registers().PC; pc != 0x206 {
+		t.Errorf("expected to stop at 0x206, actually %#04x", pc)
+	}
+
+	if !strings.Contains(out.String(), "breakpoint opcode 1NNN") {
+		t.Errorf("expected breakpoint to be reported, actually %q", out.String())
+	}
+}
+
+func TestStepping(t *testing.T) {
+	d, r, _ := newTestDebugger()
+	c := r.Chip8()
+
+	// Step over the call
+	exec(t, d, "step", "next")
+	run(t, d, r)
+	if reg := c.Registers(); reg.PC != 0x204 || reg.I != 0 {
+		t.Errorf("expected next to stop at 0x204 after the call, actually %+v", reg)
+	}
+
+	// Step into a call and out again
+	c.Reset()
+	c.LoadProgram(program)
+	exec(t, d, "step 3")
+	if reg := c.Registers(); reg.PC != 0x20A || reg.SP != 1 {
+		t.Fatalf("expected step 3 to stop at 0x20a in the call, actually %+v", reg)
+	}
+
+	exec(t, d, "finish")
+	run(t, d, r)
+	if reg := c.Registers(); reg.PC != 0x204 || reg.SP != 0 {
+		t.Errorf("expected finish to stop at 0x204, actually %+v", reg)
+	}
+}
+
+func TestDumps(t *testing.T) {
+	d, _, out := newTestDebugger()
+
+	exec(t, d, "step 3")
+
+	out.Reset()
+	exec(t, d, "regs")
+	if !strings.Contains(out.String(), "PC 0x020a") || !strings.Contains(out.String(), "V0 05") {
+		t.Errorf("unexpected regs output %q", out.String())
+	}
+
+	out.Reset()
+	exec(t, d, "stack")
+	if !strings.Contains(out.String(), "#1 0x0204: 0x6101") {
+		t.Errorf("unexpected stack output %q", out.String())
+	}
+
+	out.Reset()
+	exec(t, d, "mem 200 4")
+	if !strings.HasPrefix(out.String(), "0x0200  60 05 22 08") {
+		t.Errorf("unexpected mem output %q", out.String())
+	}
+
+	out.Reset()
+	exec(t, d, "list")
+	if !strings.Contains(out.String(), "=> 0x020a: 0xd005") {
+		t.Errorf("unexpected list output %q", out.String())
+	}
+
+	if err := d.Exec("quit"); err != ErrQuit {
+		t.Errorf("expected ErrQuit, actually %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package disassembler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// Data bytes listed per line
+const dataPerLine = 8
+
+// Width of the text of Octo listings, before the address comment
+const octoTextWidth = 24
+
+// Program is a rom split into instructions and data
+type Program struct {
+	Syntax Syntax
+	Labels map[uint16]string // by address
+	Lines  []Line
+}
+
+// Line is an instruction, or a run of data bytes
+type Line struct {
+	Address uint16 `json:"address"`
+	Bytes   []byte `json:"-"`
+	Code    bool   `json:"code"`
+	Label   string `json:"label,omitempty"` // label of Address, if any
+	Text    string `json:"text"`            // mnemonic, or data directive
+}
+
+// MarshalJSON encodes Bytes as hex
+func (l Line) MarshalJSON() ([]byte, error) {
+	type line Line
+	return json.Marshal(struct {
+		line
+		Bytes string `json:"bytes"`
+	}{line(l), strings.ToUpper(hex.EncodeToString(l.Bytes))})
+}
+
+// Disassemble splits rom into code and data by following every path from
+// the first instruction through jumps, calls and skips. Bytes which can't be
+// reached are data. Targets within the rom are labelled, sub_ for
+// subroutines, L for other jumps and data_ for I loads of data, followed by
+// the hex address. In Octo syntax the start of the rom is labelled main,
+// where Octo starts execution
+func Disassemble(rom []byte, syntax Syntax) *Program {
+	code, labels := trace(rom)
+	if syntax == Octo && len(rom) > 0 {
+		labels[chip8.ProgramStartAddress] = "main"
+	}
+
+	p := &Program{Syntax: syntax, Labels: labels}
+	address := func(a uint16) string {
+		if label, ok := labels[a]; ok {
+			return label
+		}
+		return hexAddress(a)
+	}
+
+	for i := 0; i < len(rom); {
+		a := uint16(chip8.ProgramStartAddress + i)
+		line := Line{Address: a, Label: labels[a]}
+
+		if size := code[i]; size > 0 {
+			op := chip8.GetOpcode(rom[i], rom[i+1])
+			line.Code = true
+			line.Bytes = rom[i : i+size]
+			line.Text = mnemonic(syntax, op, address)
+			if op == 0xF000 {
+				line.Text += " " + address(chip8.GetOpcode(rom[i+2], rom[i+3]))
+			}
+			i += size
+		} else {
+			// Data up to the next code or label
+			n := 1
+			for n < dataPerLine && i+n < len(rom) && code[i+n] == 0 && labels[a+uint16(n)] == "" {
+				n++
+			}
+			line.Bytes = rom[i : i+n]
+			line.Text = dataDirective(syntax, line.Bytes)
+			i += n
+		}
+		p.Lines = append(p.Lines, line)
+	}
+	return p
+}
+
+// mnemonic formats op in syntax
+func mnemonic(syntax Syntax, op uint16, address func(uint16) string) string {
+	if syntax == Octo {
+		return octo(op, address)
+	}
+	return cowgod(op, address)
+}
+
+// dataDirective formats data bytes in syntax
+func dataDirective(syntax Syntax, data []byte) string {
+	s := make([]string, len(data))
+	for i, b := range data {
+		s[i] = fmt.Sprintf("0x%02X", b)
+	}
+	if syntax == Octo {
+		return strings.Join(s, " ")
+	}
+	return "DB " + strings.Join(s, ", ")
+}
+
+// WriteText writes p as a listing, a line of address, bytes and text for
+// each Line with labels on the line before. In Octo syntax the listing is
+// source Octo compiles back to the rom, with the address and bytes in a
+// comment after the text
+func (p *Program) WriteText(w io.Writer) error {
+	for _, line := range p.Lines {
+		if line.Label != "" {
+			label := line.Label + ":"
+			if p.Syntax == Octo {
+				label = ": " + line.Label
+			}
+			if _, err := fmt.Fprintln(w, label); err != nil {
+				return err
+			}
+		}
+
+		bytes := strings.ToUpper(hex.EncodeToString(line.Bytes))
+		var err error
+		if p.Syntax == Octo {
+			_, err = fmt.Fprintf(w, "\t%-*s  # %#04x  %s\n", octoTextWidth, line.Text, line.Address, bytes)
+		} else {
+			_, err = fmt.Fprintf(w, "%#04x  %-*s  %s\n", line.Address, 2*dataPerLine, bytes, line.Text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes p as JSON, with the syntax by name, labels as a map of
+// names to addresses as in symbol maps, and the bytes of lines in hex:
+//
+//	{
+//		"syntax": "cowgod",
+//		"labels": {"L204": 516},
+//		"lines": [
+//			{"address": 512, "code": true, "text": "LD V0, 0x05", "bytes": "6005"},
+//			{"address": 514, "code": false, "text": "DB 0x80", "bytes": "80"}
+//		]
+//	}
+func (p *Program) WriteJSON(w io.Writer) error {
+	labels := make(map[string]uint16, len(p.Labels))
+	for a, label := range p.Labels {
+		labels[label] = a
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(struct {
+		Syntax string            `json:"syntax"`
+		Labels map[string]uint16 `json:"labels"`
+		Lines  []Line            `json:"lines"`
+	}{p.Syntax.String(), labels, p.Lines})
+}
+
+// Label kinds in priority order, a subroutine which is also jumped to is
+// labelled as a subroutine
+const (
+	dataLabel = iota
+	jumpLabel
+	subLabel
+)
+
+var labelPrefixes = [...]string{"data_", "L", "sub_"}
+
+// trace follows every path from the start of rom, returning the size of
+// the instruction at each index of rom, 0 for data, and labels of targets
+func trace(rom []byte) ([]int, map[uint16]string) {
+	end := chip8.ProgramStartAddress + len(rom)
+	code := make([]int, len(rom))
+	kinds := make(map[uint16]int)
+
+	// label records a target within the rom
+	label := func(a uint16, kind int) {
+		if int(a) >= chip8.ProgramStartAddress && int(a) < end {
+			if k, ok := kinds[a]; !ok || kind > k {
+				kinds[a] = kind
+			}
+		}
+	}
+
+	// opcode returns the opcode at a, false if it's past the end of the rom
+	opcode := func(a int) (uint16, bool) {
+		i := a - chip8.ProgramStartAddress
+		if i < 0 || i+1 >= len(rom) {
+			return 0, false
+		}
+		return chip8.GetOpcode(rom[i], rom[i+1]), true
+	}
+
+	pending := []int{chip8.ProgramStartAddress}
+	for len(pending) > 0 {
+		a := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		op, ok := opcode(a)
+		if !ok || code[a-chip8.ProgramStartAddress] > 0 || chip8.DecodeOpcode(op).Opcode == 0xFFFF {
+			continue
+		}
+
+		size := Size(op)
+		if a+size > end {
+			continue
+		}
+		code[a-chip8.ProgramStartAddress] = size
+
+		next := a + size
+		nnn := op & 0xFFF
+		switch {
+		case op == 0x00EE, op == 0x00FD:
+			// Returns and exits end the path
+		case op>>12 == 0x1:
+			label(nnn, jumpLabel)
+			pending = append(pending, int(nnn))
+		case op>>12 == 0xB:
+			// The target depends on V0, follow the start of the jump table
+			label(nnn, jumpLabel)
+			pending = append(pending, int(nnn))
+		case op>>12 == 0x2:
+			label(nnn, subLabel)
+			pending = append(pending, next, int(nnn))
+		case op>>12 == 0xA:
+			label(nnn, dataLabel)
+			pending = append(pending, next)
+		case op == 0xF000:
+			if long, ok := opcode(a + 2); ok {
+				label(long, dataLabel)
+			}
+			pending = append(pending, next)
+		case IsSkip(op):
+			// The skipped instruction may be a long load
+			skipped := 2
+			if following, ok := opcode(next); ok {
+				skipped = Size(following)
+			}
+			pending = append(pending, next, next+skipped)
+		default:
+			pending = append(pending, next)
+		}
+	}
+
+	// I loads of code are labelled like jumps
+	labels := make(map[uint16]string, len(kinds))
+	for a, kind := range kinds {
+		if kind == dataLabel && code[int(a)-chip8.ProgramStartAddress] > 0 {
+			kind = jumpLabel
+		}
+		labels[a] = fmt.Sprintf("%s%03X", labelPrefixes[kind], a)
+	}
+
+	// Labels in the middle of an instruction can't be placed
+	for i, size := range code {
+		for j := 1; j < size; j++ {
+			delete(labels, uint16(chip8.ProgramStartAddress+i+j))
+		}
+	}
+	return code, labels
+}
+
+// Size returns the size of the instruction op in bytes, 4 for F000 long
+// loads and 2 for everything else
+func Size(op uint16) int {
+	if op == 0xF000 {
+		return 4
+	}
+	return 2
+}
+
+// IsSkip returns true if op conditionally skips the following instruction
+func IsSkip(op uint16) bool {
+	switch op >> 12 {
+	case 0x3, 0x4:
+		return true
+	case 0x5, 0x9:
+		return op&0xF == 0
+	case 0xE:
+		return op&0xFF == 0x9E || op&0xFF == 0xA1
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package disassembler
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+var program = []byte{
+	0xA2, 0x0C, // 0x200 LD I, data_20C
+	0x22, 0x08, // 0x202 CALL sub_208
+	0x30, 0x00, // 0x204 SE V0, 0x00
+	0x12, 0x04, // 0x206 JP L204, skipped if V0 is 0
+	0xD0, 0x12, // 0x208 sub_208: DRW V0, V1, 2
+	0x00, 0xEE, // 0x20A RET
+	0xF0, 0x81, // 0x20C data_20C: sprite
+	0xFF, // 0x20E odd byte at the end
+}
+
+func TestDisassemble(t *testing.T) {
+	p := Disassemble(program, Cowgod)
+
+	expected := []Line{
+		{Address: 0x200, Code: true, Text: "LD I, data_20C"},
+		{Address: 0x202, Code: true, Text: "CALL sub_208"},
+		{Address: 0x204, Code: true, Label: "L204", Text: "SE V0, 0x00"},
+		{Address: 0x206, Code: true, Text: "JP L204"},
+		{Address: 0x208, Code: true, Label: "sub_208", Text: "DRW V0, V1, 2"},
+		{Address: 0x20A, Code: true, Text: "RET"},
+		{Address: 0x20C, Label: "data_20C", Text: "DB 0xF0, 0x81, 0xFF"},
+	}
+	if len(p.Lines) != len(expected) {
+		t.Fatalf("expected %d lines, actually %+v", len(expected), p.Lines)
+	}
+	for i, line := range p.Lines {
+		e := expected[i]
+		if line.Address != e.Address || line.Code != e.Code || line.Label != e.Label || line.Text != e.Text {
+			t.Errorf("expected %+v, actually %+v", e, line)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	expectedText := `0x0200  A20C              LD I, data_20C
+0x0202  2208              CALL sub_208
+L204:
+0x0204  3000              SE V0, 0x00
+0x0206  1204              JP L204
+sub_208:
+0x0208  D012              DRW V0, V1, 2
+0x020a  00EE              RET
+data_20C:
+0x020c  F081FF            DB 0xF0, 0x81, 0xFF
+`
+	if buf.String() != expectedText {
+		t.Errorf("expected\n%s\nactually\n%s", expectedText, buf)
+	}
+}
+
+func TestDisassembleOcto(t *testing.T) {
+	p := Disassemble(program, Octo)
+
+	buf := &bytes.Buffer{}
+	if err := p.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `: main
+	i := data_20C             # 0x0200  A20C
+	sub_208                   # 0x0202  2208
+: L204
+	if v0 != 0x00 then        # 0x0204  3000
+	jump L204                 # 0x0206  1204
+: sub_208
+	sprite v0 v1 2            # 0x0208  D012
+	return                    # 0x020a  00EE
+: data_20C
+	0xF0 0x81 0xFF            # 0x020c  F081FF
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nactually\n%s", expected, buf)
+	}
+}
+
+func TestDisassembleJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Disassemble(program, Cowgod).WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Syntax string
+		Labels map[string]uint16
+		Lines  []struct {
+			Address uint16
+			Bytes   string
+			Code    bool
+			Label   string
+			Text    string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Syntax != "cowgod" || decoded.Labels["sub_208"] != 0x208 || len(decoded.Labels) != 3 {
+		t.Errorf("unexpected syntax or labels %+v", decoded)
+	}
+	if line := decoded.Lines[6]; line.Address != 0x20C || line.Bytes != "F081FF" || line.Code || line.Label != "data_20C" {
+		t.Errorf("unexpected data line %+v", line)
+	}
+}
+
+func TestDisassembleTraversal(t *testing.T) {
+	for name, tc := range map[string]struct {
+		rom  []byte
+		code []int
+	}{
+		"long load skipped": {
+			[]byte{0x30, 0x00, 0xF0, 0x00, 0x02, 0x00, 0x00, 0xFD},
+			[]int{2, 0, 4, 0, 0, 0, 2, 0},
+		},
+		"unknown opcode": {
+			[]byte{0x60, 0x00, 0x51, 0x21, 0x00, 0xE0},
+			[]int{2, 0, 0, 0, 0, 0},
+		},
+		"jump table": {
+			[]byte{0xB2, 0x04, 0xFF, 0xFF, 0x12, 0x04},
+			[]int{2, 0, 0, 0, 2, 0},
+		},
+		"instruction past the end": {
+			[]byte{0x60, 0x00, 0xF0},
+			[]int{2, 0, 0},
+		},
+	} {
+		code, _ := trace(tc.rom)
+		for i := range code {
+			if code[i] != tc.code[i] {
+				t.Errorf("%s: expected %v, actually %v", name, tc.code, code)
+				break
+			}
+		}
+	}
+}
+
+func TestOctoMnemonic(t *testing.T) {
+	for op, expected := range map[uint16]string{
+		0x00C4: "scroll-down 4",
+		0x00D2: "scroll-up 2",
+		0x00E0: "clear",
+		0x00EE: "return",
+		0x0123: "native 0x123",
+		0x1204: "jump 0x204",
+		0x2ABC: ":call 0xABC",
+		0x3A05: "if va != 0x05 then",
+		0x4B10: "if vb == 0x10 then",
+		0x5132: "save v1 - v3",
+		0x5121: "0x51 0x21",
+		0x7101: "v1 += 0x01",
+		0x8127: "v1 =- v2",
+		0x812E: "v1 <<= v2",
+		0x9AB0: "if va == vb then",
+		0xB200: "jump0 0x200",
+		0xC10F: "v1 := random 0x0F",
+		0xD125: "sprite v1 v2 5",
+		0xE19E: "if v1 -key then",
+		0xE1A1: "if v1 key then",
+		0xF000: "i := long",
+		0xF201: "plane 2",
+		0xF30A: "v3 := key",
+		0xF318: "buzzer := v3",
+		0xF330: "i := bighex v3",
+		0xF355: "save v3",
+	} {
+		if got := OctoMnemonic(op); got != expected {
+			t.Errorf("%#04x: expected %q, actually %q", op, expected, got)
+		}
+	}
+}
+
+func TestParseSyntax(t *testing.T) {
+	if s, err := ParseSyntax("Octo"); err != nil || s != Octo {
+		t.Errorf("expected Octo, actually %v %v", s, err)
+	}
+	if _, err := ParseSyntax("intel"); err == nil {
+		t.Error("expected an error for an unknown syntax")
+	}
+}
@@ -0,0 +1,135 @@
+// Package disassembler formats Chip 8 opcodes as assembly
+package disassembler
+
+import "fmt"
+
+// Mnemonic returns op in the assembly syntax of Cowgod's Chip-8 Technical
+// Reference, with the SUPER-CHIP and XO-CHIP extensions. Opcodes which
+// don't decode are returned as data, e.g. DW 0x5AB1. The address of F000
+// long loads is the following word, which isn't included
+func Mnemonic(op uint16) string {
+	return cowgod(op, hexAddress)
+}
+
+// hexAddress formats an address operand as hex
+func hexAddress(address uint16) string {
+	return fmt.Sprintf("0x%03X", address)
+}
+
+// cowgod returns op in Cowgod's syntax with address operands formatted by address
+func cowgod(op uint16, address func(uint16) string) string {
+	x, y := op>>8&0xF, op>>4&0xF
+	n, kk, nnn := op&0xF, op&0xFF, op&0xFFF
+
+	switch op >> 12 {
+	case 0x0:
+		switch {
+		case op&0xFFF0 == 0x00C0:
+			return fmt.Sprintf("SCD %d", n)
+		case op&0xFFF0 == 0x00D0:
+			return fmt.Sprintf("SCU %d", n)
+		case op == 0x00E0:
+			return "CLS"
+		case op == 0x00EE:
+			return "RET"
+		case op == 0x00FB:
+			return "SCR"
+		case op == 0x00FC:
+			return "SCL"
+		case op == 0x00FD:
+			return "EXIT"
+		case op == 0x00FE:
+			return "LOW"
+		case op == 0x00FF:
+			return "HIGH"
+		}
+		return fmt.Sprintf("SYS 0x%03X", nnn)
+	case 0x1:
+		return "JP " + address(nnn)
+	case 0x2:
+		return "CALL " + address(nnn)
+	case 0x3:
+		return fmt.Sprintf("SE V%X, 0x%02X", x, kk)
+	case 0x4:
+		return fmt.Sprintf("SNE V%X, 0x%02X", x, kk)
+	case 0x5:
+		switch n {
+		case 0x0:
+			return fmt.Sprintf("SE V%X, V%X", x, y)
+		case 0x2:
+			return fmt.Sprintf("SAVE V%X - V%X", x, y)
+		case 0x3:
+			return fmt.Sprintf("LOAD V%X - V%X", x, y)
+		}
+	case 0x6:
+		return fmt.Sprintf("LD V%X, 0x%02X", x, kk)
+	case 0x7:
+		return fmt.Sprintf("ADD V%X, 0x%02X", x, kk)
+	case 0x8:
+		if name, ok := aluMnemonics[n]; ok {
+			return fmt.Sprintf("%s V%X, V%X", name, x, y)
+		}
+	case 0x9:
+		if n == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", x, y)
+		}
+	case 0xA:
+		return "LD I, " + address(nnn)
+	case 0xB:
+		return "JP V0, " + address(nnn)
+	case 0xC:
+		return fmt.Sprintf("RND V%X, 0x%02X", x, kk)
+	case 0xD:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n)
+	case 0xE:
+		switch kk {
+		case 0x9E:
+			return fmt.Sprintf("SKP V%X", x)
+		case 0xA1:
+			return fmt.Sprintf("SKNP V%X", x)
+		}
+	case 0xF:
+		if op == 0xF000 {
+			return "LD I, LONG"
+		}
+		if op == 0xF002 {
+			return "AUDIO"
+		}
+		if format, ok := miscMnemonics[kk]; ok {
+			return fmt.Sprintf(format, x)
+		}
+	}
+
+	return fmt.Sprintf("DW 0x%04X", op)
+}
+
+// 8xyn mnemonics by n
+var aluMnemonics = map[uint16]string{
+	0x0: "LD",
+	0x1: "OR",
+	0x2: "AND",
+	0x3: "XOR",
+	0x4: "ADD",
+	0x5: "SUB",
+	0x6: "SHR",
+	0x7: "SUBN",
+	0xE: "SHL",
+}
+
+// Fxkk mnemonics by kk, formatted with x
+var miscMnemonics = map[uint16]string{
+	0x01: "PLANE %d",
+	0x07: "LD V%X, DT",
+	0x0A: "LD V%X, K",
+	0x15: "LD DT, V%X",
+	0x18: "LD ST, V%X",
+	0x1E: "ADD I, V%X",
+	0x29: "LD F, V%X",
+	0x30: "LD HF, V%X",
+	0x33: "LD B, V%X",
+	0x3A: "PITCH V%X",
+	0x55: "LD [I], V%X",
+	0x65: "LD V%X, [I]",
+	0x75: "LD R, V%X",
+	0x85: "LD V%X, R",
+}
@@ -0,0 +1,49 @@
+package disassembler
+
+import "testing"
+
+func TestMnemonic(t *testing.T) {
+	for op, expected := range map[uint16]string{
+		0x00C4: "SCD 4",
+		0x00D2: "SCU 2",
+		0x00E0: "CLS",
+		0x00EE: "RET",
+		0x00FD: "EXIT",
+		0x0123: "SYS 0x123",
+		0x1204: "JP 0x204",
+		0x2ABC: "CALL 0xABC",
+		0x3A05: "SE VA, 0x05",
+		0x4B10: "SNE VB, 0x10",
+		0x5120: "SE V1, V2",
+		0x5132: "SAVE V1 - V3",
+		0x5133: "LOAD V1 - V3",
+		0x5121: "DW 0x5121",
+		0x60FF: "LD V0, 0xFF",
+		0x7101: "ADD V1, 0x01",
+		0x8124: "ADD V1, V2",
+		0x8126: "SHR V1, V2",
+		0x812E: "SHL V1, V2",
+		0x8128: "DW 0x8128",
+		0x9AB0: "SNE VA, VB",
+		0x9AB1: "DW 0x9AB1",
+		0xA300: "LD I, 0x300",
+		0xB200: "JP V0, 0x200",
+		0xC10F: "RND V1, 0x0F",
+		0xD125: "DRW V1, V2, 5",
+		0xE59E: "SKP V5",
+		0xE5A1: "SKNP V5",
+		0xE5A2: "DW 0xE5A2",
+		0xF000: "LD I, LONG",
+		0xF002: "AUDIO",
+		0xF201: "PLANE 2",
+		0xF30A: "LD V3, K",
+		0xF433: "LD B, V4",
+		0xF555: "LD [I], V5",
+		0xF665: "LD V6, [I]",
+		0xF6FF: "DW 0xF6FF",
+	} {
+		if got := Mnemonic(op); got != expected {
+			t.Errorf("%#04x: expected %q, actually %q", op, expected, got)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package disassembler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Syntax is the assembly language instructions are written in
+type Syntax int
+
+const (
+	// Cowgod's Chip-8 Technical Reference, as used by Chipper, e.g. LD VA, 0x02
+	Cowgod Syntax = iota
+
+	// Octo, e.g. va := 0x02
+	Octo
+)
+
+// syntaxNames are the names of each Syntax, in order
+var syntaxNames = []string{"cowgod", "octo"}
+
+// ParseSyntax returns the Syntax called name, cowgod or octo
+func ParseSyntax(name string) (Syntax, error) {
+	for i, n := range syntaxNames {
+		if strings.EqualFold(name, n) {
+			return Syntax(i), nil
+		}
+	}
+	return 0, fmt.Errorf("disassembler: unknown syntax %q, expected %s", name, strings.Join(syntaxNames, " or "))
+}
+
+func (s Syntax) String() string {
+	if int(s) < len(syntaxNames) {
+		return syntaxNames[s]
+	}
+	return fmt.Sprintf("Syntax(%d)", int(s))
+}
+
+// OctoMnemonic returns op in the syntax of Octo. Opcodes which don't decode
+// are returned as bytes, e.g. 0x5A 0xB1. As with Mnemonic the address of
+// F000 long loads isn't included
+func OctoMnemonic(op uint16) string {
+	return octo(op, hexAddress)
+}
+
+// octo returns op in Octo's syntax with address operands formatted by address
+func octo(op uint16, address func(uint16) string) string {
+	x, y := op>>8&0xF, op>>4&0xF
+	n, kk, nnn := op&0xF, op&0xFF, op&0xFFF
+
+	switch op >> 12 {
+	case 0x0:
+		switch {
+		case op&0xFFF0 == 0x00C0:
+			return fmt.Sprintf("scroll-down %d", n)
+		case op&0xFFF0 == 0x00D0:
+			return fmt.Sprintf("scroll-up %d", n)
+		case op == 0x00E0:
+			return "clear"
+		case op == 0x00EE:
+			return "return"
+		case op == 0x00FB:
+			return "scroll-right"
+		case op == 0x00FC:
+			return "scroll-left"
+		case op == 0x00FD:
+			return "exit"
+		case op == 0x00FE:
+			return "lores"
+		case op == 0x00FF:
+			return "hires"
+		}
+		return fmt.Sprintf("native 0x%03X", nnn)
+	case 0x1:
+		return "jump " + address(nnn)
+	case 0x2:
+		// Subroutines are called by name, addresses need :call
+		if target := address(nnn); !strings.HasPrefix(target, "0x") {
+			return target
+		}
+		return ":call " + address(nnn)
+
+	// Octo's conditions give when the next instruction runs, the opposite
+	// of when it is skipped
+	case 0x3:
+		return fmt.Sprintf("if v%x != 0x%02X then", x, kk)
+	case 0x4:
+		return fmt.Sprintf("if v%x == 0x%02X then", x, kk)
+	case 0x5:
+		switch n {
+		case 0x0:
+			return fmt.Sprintf("if v%x != v%x then", x, y)
+		case 0x2:
+			return fmt.Sprintf("save v%x - v%x", x, y)
+		case 0x3:
+			return fmt.Sprintf("load v%x - v%x", x, y)
+		}
+	case 0x6:
+		return fmt.Sprintf("v%x := 0x%02X", x, kk)
+	case 0x7:
+		return fmt.Sprintf("v%x += 0x%02X", x, kk)
+	case 0x8:
+		if operator, ok := octoOperators[n]; ok {
+			return fmt.Sprintf("v%x %s v%x", x, operator, y)
+		}
+	case 0x9:
+		if n == 0 {
+			return fmt.Sprintf("if v%x == v%x then", x, y)
+		}
+	case 0xA:
+		return "i := " + address(nnn)
+	case 0xB:
+		return "jump0 " + address(nnn)
+	case 0xC:
+		return fmt.Sprintf("v%x := random 0x%02X", x, kk)
+	case 0xD:
+		return fmt.Sprintf("sprite v%x v%x %d", x, y, n)
+	case 0xE:
+		switch kk {
+		case 0x9E:
+			return fmt.Sprintf("if v%x -key then", x)
+		case 0xA1:
+			return fmt.Sprintf("if v%x key then", x)
+		}
+	case 0xF:
+		if op == 0xF000 {
+			return "i := long"
+		}
+		if op == 0xF002 {
+			return "audio"
+		}
+		if kk == 0x01 {
+			return fmt.Sprintf("plane %d", x)
+		}
+		if format, ok := octoMisc[kk]; ok {
+			return fmt.Sprintf(format, x)
+		}
+	}
+
+	return fmt.Sprintf("0x%02X 0x%02X", op>>8, op&0xFF)
+}
+
+// 8xyn operators by n
+var octoOperators = map[uint16]string{
+	0x0: ":=",
+	0x1: "|=",
+	0x2: "&=",
+	0x3: "^=",
+	0x4: "+=",
+	0x5: "-=",
+	0x6: ">>=",
+	0x7: "=-",
+	0xE: "<<=",
+}
+
+// Fxkk statements by kk, formatted with x
+var octoMisc = map[uint16]string{
+	0x07: "v%x := delay",
+	0x0A: "v%x := key",
+	0x15: "delay := v%x",
+	0x18: "buzzer := v%x",
+	0x1E: "i += v%x",
+	0x29: "i := hex v%x",
+	0x30: "i := bighex v%x",
+	0x33: "bcd v%x",
+	0x3A: "pitch := v%x",
+	0x55: "save v%x",
+	0x65: "load v%x",
+	0x75: "saveflags v%x",
+	0x85: "loadflags v%x",
+}
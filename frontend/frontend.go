@@ -0,0 +1,155 @@
+// Package frontend defines the interfaces between the emulator and the
+// display, sound and input of the host, and a registry of the frontends
+// built into the binary.
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/keymap"
+)
+
+// ErrUnknown is returned by Open for a frontend which isn't registered
+var ErrUnknown = errors.New("frontend: unknown frontend")
+
+// Display is the Chip 8 display, indexed by x then y, each pixel a bitmask
+// of the XO-CHIP planes it is on in
+type Display = [chip8.HiResDisplayWidth][chip8.HiResDisplayHeight]byte
+
+// WindowSize returns the size of a window showing the display at scale times
+// the low resolution, at least a window pixel for each high resolution pixel
+func WindowSize(scale int) (int, int) {
+	w, h := scale*chip8.DisplayWidth, scale*chip8.DisplayHeight
+	if w < chip8.HiResDisplayWidth {
+		w, h = chip8.HiResDisplayWidth, chip8.HiResDisplayHeight
+	}
+	return w, h
+}
+
+// Video renders the display
+type Video interface {
+	// Draw renders the top left width by height pixels of display
+	Draw(display *Display, width, height int)
+}
+
+// Audio plays the buzzer
+type Audio interface {
+	// Buzz is called once per frame, while on pattern is played at rate bits per second
+	Buzz(on bool, pattern [chip8.AudioPatternSize]byte, rate float64)
+}
+
+// Input reports key presses and hotkeys
+type Input interface {
+	// Poll returns the events since the last call, it is called once per frame
+	Poll() []Event
+}
+
+// Frontend is a complete frontend, opened with Open
+type Frontend interface {
+	Video
+	Audio
+	Input
+
+	// Close releases the frontend, restoring the host to its previous state
+	Close()
+}
+
+// EventType is the type of an input event
+type EventType int
+
+const (
+	// KeyDown and KeyUp press and release Event.Key
+	KeyDown EventType = iota
+	KeyUp
+
+	// Quit stops the emulator
+	Quit
+
+	// SaveState and LoadState save and load the selected save state slot,
+	// NextSaveSlot selects the next one
+	SaveState
+	LoadState
+	NextSaveSlot
+
+	// RewindStart and RewindStop begin and end stepping backwards a frame at a time
+	RewindStart
+	RewindStop
+
+	// PauseStart and PauseStop begin and end pausing emulation while the
+	// frontend shows something in place of the display, e.g. a menu
+	PauseStart
+	PauseStop
+)
+
+// Event is an input event
+type Event struct {
+	Type EventType
+	Key  chip8.Key
+}
+
+// Options configures a frontend, each frontend uses the options which apply to it
+type Options struct {
+	// Title of the window
+	Title string
+
+	// Scale factor from Chip 8 resolution (64x32) to window size
+	Scale int
+
+	// Characters used by text frontends, e.g. half blocks or braille
+	Chars string
+
+	// Frames to run before quitting for frontends without input, 0 to run until closed
+	Frames int
+
+	// Colours of pixels by the XO-CHIP planes they're on in, the frontend's
+	// defaults for those missing
+	Palette []color.RGBA
+
+	// Host keys bound to the keypad, keymap.Default() if nil
+	Keys keymap.Map
+
+	// File the SDL frontend's remapping screen saves Keys to, empty to not save
+	KeysFile string
+
+	// Chip 8 keys of a game's controls by name, up, down, left, right, a and b,
+	// bound to the host keys in keymap.GameKeyHosts unless Keys binds them
+	GameKeys map[string]chip8.Key
+}
+
+// Opener creates a frontend
+type Opener func(Options) (Frontend, error)
+
+var frontends = map[string]Opener{}
+
+// Register makes a frontend available to Open as name, it is called from
+// the init function of the frontend's package
+func Register(name string, open Opener) {
+	if _, ok := frontends[name]; ok {
+		panic("frontend: Register called twice for " + name)
+	}
+	frontends[name] = open
+}
+
+// Open creates the frontend registered as name
+func Open(name string, opts Options) (Frontend, error) {
+	open, ok := frontends[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s, available: %s", ErrUnknown, name, strings.Join(Names(), ", "))
+	}
+	return open(opts)
+}
+
+// Names returns the names of the registered frontends in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(frontends))
+	for name := range frontends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,25 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+func TestWindowSize(t *testing.T) {
+	for _, test := range []struct {
+		scale, w, h int
+	}{
+		{10, 640, 320},
+		{2, 128, 64},
+		{1, 128, 64}, // too small for a pixel per high resolution pixel
+	} {
+		w, h := WindowSize(test.scale)
+		if w != test.w || h != test.h {
+			t.Errorf("scale %d: expected %dx%d, actually %dx%d", test.scale, test.w, test.h, w, h)
+		}
+		if size := w / chip8.HiResDisplayWidth; size < 1 {
+			t.Errorf("scale %d: high resolution pixels are %d window pixels", test.scale, size)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package headless is a frontend with no output and scripted input, for
+// running without a display and as a test double
+package headless
+
+import (
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/frontend"
+)
+
+func init() {
+	frontend.Register("headless", func(opts frontend.Options) (frontend.Frontend, error) {
+		f := New()
+		f.QuitAfter = opts.Frames
+		return f, nil
+	})
+}
+
+// Frontend implements frontend.Frontend, recording what was output
+type Frontend struct {
+	// Events returned by Poll, keyed by the number of previous calls
+	Events map[int][]frontend.Event
+
+	// Quit is sent after this many frames, 0 to run until quit otherwise
+	QuitAfter int
+
+	// Number of frames polled
+	Frames int
+
+	// Last display drawn and its resolution
+	Display       frontend.Display
+	Width, Height int
+
+	Draws   int
+	Buzzing bool
+	Pattern [chip8.AudioPatternSize]byte
+	Rate    float64
+
+	Closed bool
+}
+
+// New creates a headless Frontend
+func New() *Frontend {
+	return &Frontend{
+		Events: make(map[int][]frontend.Event),
+	}
+}
+
+// Send queues events to be returned by Poll after frame frames
+func (f *Frontend) Send(frame int, events ...frontend.Event) {
+	f.Events[frame] = append(f.Events[frame], events...)
+}
+
+func (f *Frontend) Poll() []frontend.Event {
+	events := f.Events[f.Frames]
+	delete(f.Events, f.Frames)
+	f.Frames++
+
+	if f.QuitAfter > 0 && f.Frames >= f.QuitAfter {
+		events = append(events, frontend.Event{Type: frontend.Quit})
+	}
+	return events
+}
+
+func (f *Frontend) Draw(display *frontend.Display, width, height int) {
+	f.Display = *display
+	f.Width, f.Height = width, height
+	f.Draws++
+}
+
+func (f *Frontend) Buzz(on bool, pattern [chip8.AudioPatternSize]byte, rate float64) {
+	f.Buzzing = on
+	f.Pattern = pattern
+	f.Rate = rate
+}
+
+func (f *Frontend) Close() {
+	f.Closed = true
+}
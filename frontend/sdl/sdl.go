@@ -0,0 +1,406 @@
+//go:build cgo
+
+// Package sdl is a frontend displaying in a window and playing sound with SDL2
+package sdl
+
+// typedef unsigned char Uint8;
+// void AudioCallback(void *userdata, Uint8 *stream, int len);
+import "C"
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/keymap"
+	sdl2 "github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	sampleHz     = 48000
+	audioSamples = 2048
+
+	// Output levels for pattern bits, unsigned 8 bit samples are silent at 128
+	sampleHigh = 192
+	sampleLow  = 64
+
+	defaultScale = 10
+)
+
+var (
+	// Hotkeys
+	hotkeys = map[sdl2.Keycode]frontend.EventType{
+		sdl2.K_ESCAPE: frontend.Quit,
+		sdl2.K_F5:     frontend.SaveState,
+		sdl2.K_F7:     frontend.NextSaveSlot,
+		sdl2.K_F9:     frontend.LoadState,
+	}
+
+	// Default colours for each combination of XO-CHIP planes a pixel is on in
+	defaultPalette = [1 << chip8.PlaneCount][3]uint8{
+		{0, 0, 0},       // off
+		{255, 255, 255}, // plane 1
+		{170, 170, 170}, // plane 2
+		{85, 85, 85},    // both planes
+	}
+
+	// Audio pattern being played, shared with the SDL audio thread
+	audio struct {
+		sync.Mutex
+		pattern  [chip8.AudioPatternSize]byte
+		rate     float64 // pattern bits per output sample
+		position float64 // current bit in pattern
+	}
+)
+
+func init() {
+	frontend.Register("sdl", func(opts frontend.Options) (frontend.Frontend, error) {
+		return Open(opts)
+	})
+}
+
+// Frontend implements frontend.Frontend with SDL, SDL calls must be made
+// from the main thread
+type Frontend struct {
+	window   *sdl2.Window
+	renderer *sdl2.Renderer
+	scale    int
+	width    int // of the window
+
+	// Reuse pixel for drawing
+	pixel *sdl2.Rect
+
+	palette [1 << chip8.PlaneCount][3]uint8
+	title   string
+
+	// Last display drawn, repainted when the remapping screen changes or closes
+	display       frontend.Display
+	displayWidth  int
+	displayHeight int
+
+	// Host keys bound to the keypad, without a game's controls
+	keys      keymap.Map
+	keysFile  string
+	gameKeys  map[string]chip8.Key
+	scancodes map[sdl2.Scancode]chip8.Key
+	keycodes  map[sdl2.Keycode]chip8.Key
+
+	// Remapping screen, nil unless it's open
+	remap *remapping
+}
+
+// remapping is the state of the remapping screen, which binds host keys to
+// each Chip 8 key in turn
+type remapping struct {
+	key   chip8.Key
+	keys  keymap.Map
+	bound bool // keys has been pressed for key
+}
+
+// Open creates the window and opens the audio device, only one may be open at a time
+func Open(opts frontend.Options) (*Frontend, error) {
+	f := &Frontend{
+		scale:    opts.Scale,
+		pixel:    &sdl2.Rect{},
+		palette:  defaultPalette,
+		title:    opts.Title,
+		keys:     opts.Keys,
+		keysFile: opts.KeysFile,
+		gameKeys: opts.GameKeys,
+
+		displayWidth:  chip8.DisplayWidth,
+		displayHeight: chip8.DisplayHeight,
+	}
+	if f.scale <= 0 {
+		f.scale = defaultScale
+	}
+
+	for i, c := range opts.Palette {
+		if i < len(f.palette) {
+			f.palette[i] = [3]uint8{c.R, c.G, c.B}
+		}
+	}
+
+	if f.keys == nil {
+		f.keys = keymap.Default()
+	}
+	if err := f.bind(); err != nil {
+		return nil, err
+	}
+
+	var (
+		w, h = frontend.WindowSize(f.scale)
+		err  error
+	)
+	f.width = w
+
+	sdl2.Init(sdl2.INIT_VIDEO | sdl2.INIT_AUDIO)
+	f.window, err = sdl2.CreateWindow(opts.Title, sdl2.WINDOWPOS_CENTERED, sdl2.WINDOWPOS_CENTERED, w, h, 0)
+	if err != nil {
+		sdl2.Quit()
+		return nil, err
+	}
+
+	f.renderer, err = sdl2.CreateRenderer(f.window, -1, 0)
+	if err != nil {
+		f.window.Destroy()
+		sdl2.Quit()
+		return nil, err
+	}
+
+	// audio spec
+	spec := &sdl2.AudioSpec{
+		Freq:     sampleHz,
+		Format:   sdl2.AUDIO_U8,
+		Channels: 2,
+		Samples:  audioSamples,
+		Callback: sdl2.AudioCallback(C.AudioCallback),
+	}
+	sdl2.OpenAudio(spec, nil)
+
+	return f, nil
+}
+
+func (f *Frontend) Close() {
+	f.renderer.Destroy()
+	f.window.Destroy()
+	sdl2.Quit()
+}
+
+// bind resolves the names of the bound host keys, and a game's controls, to
+// SDL's scancodes and keycodes
+func (f *Frontend) bind() error {
+	f.scancodes = make(map[sdl2.Scancode]chip8.Key)
+	f.keycodes = make(map[sdl2.Keycode]chip8.Key)
+	for host, k := range f.keys.WithGameKeys(f.gameKeys) {
+		if name := strings.TrimPrefix(host, keymap.ScancodePrefix); name != host {
+			code := sdl2.GetScancodeFromName(name)
+			if code == sdl2.SCANCODE_UNKNOWN {
+				return fmt.Errorf("sdl: unknown scancode %q bound to %X", name, k)
+			}
+			f.scancodes[code] = k
+		} else {
+			code := sdl2.GetKeyFromName(host)
+			if code == sdl2.K_UNKNOWN {
+				return fmt.Errorf("sdl: unknown key %q bound to %X", host, k)
+			}
+			f.keycodes[code] = k
+		}
+	}
+	return nil
+}
+
+// key returns the Chip 8 key bound to a host key, by position before label
+func (f *Frontend) key(sym sdl2.Keysym) (chip8.Key, bool) {
+	if k, ok := f.scancodes[sym.Scancode]; ok {
+		return k, true
+	}
+	k, ok := f.keycodes[sym.Sym]
+	return k, ok
+}
+
+// Poll converts pending SDL events to frontend events
+func (f *Frontend) Poll() []frontend.Event {
+	var events []frontend.Event
+	for event := sdl2.PollEvent(); event != nil; event = sdl2.PollEvent() {
+		switch e := event.(type) {
+		case *sdl2.QuitEvent:
+			events = append(events, frontend.Event{Type: frontend.Quit})
+		case *sdl2.KeyDownEvent:
+			if f.remap != nil {
+				f.remapKey(e.Keysym)
+				if f.remap == nil {
+					events = append(events, frontend.Event{Type: frontend.PauseStop})
+				}
+			} else if e.Keysym.Sym == sdl2.K_F1 {
+				f.startRemap()
+				events = append(events, frontend.Event{Type: frontend.PauseStart})
+			} else if t, ok := hotkeys[e.Keysym.Sym]; ok {
+				events = append(events, frontend.Event{Type: t})
+			} else if e.Keysym.Sym == sdl2.K_BACKSPACE {
+				events = append(events, frontend.Event{Type: frontend.RewindStart})
+			} else if k, ok := f.key(e.Keysym); ok {
+				events = append(events, frontend.Event{Type: frontend.KeyDown, Key: k})
+			}
+		case *sdl2.KeyUpEvent:
+			if e.Keysym.Sym == sdl2.K_BACKSPACE {
+				events = append(events, frontend.Event{Type: frontend.RewindStop})
+			} else if k, ok := f.key(e.Keysym); ok {
+				events = append(events, frontend.Event{Type: frontend.KeyUp, Key: k})
+			}
+		}
+	}
+	return events
+}
+
+// startRemap opens the remapping screen at the first key of the keypad,
+// emulation is paused until it closes
+func (f *Frontend) startRemap() {
+	f.remap = &remapping{key: keymap.Layout[0][0], keys: f.keys.Clone()}
+	f.remapTitle()
+	f.paint()
+}
+
+// remapTitle shows what to press in the window title
+func (f *Frontend) remapTitle() {
+	f.window.SetTitle(fmt.Sprintf("%s - press keys for %X, F1 for the next key, Escape to cancel", f.title, f.remap.key))
+}
+
+// remapKey handles a key pressed on the remapping screen. The first press for
+// a Chip 8 key replaces its bindings, F1 moves on to the next key keeping
+// them, and Escape closes the screen discarding every change
+func (f *Frontend) remapKey(sym sdl2.Keysym) {
+	switch sym.Sym {
+	case sdl2.K_ESCAPE:
+		f.remap = nil
+		f.window.SetTitle(f.title)
+	case sdl2.K_F1:
+		f.nextRemapKey()
+	default:
+		if !f.remap.bound {
+			f.remap.keys.Unbind(f.remap.key)
+			f.remap.bound = true
+		}
+		f.remap.keys[keymap.ScancodePrefix+sdl2.GetScancodeName(sym.Scancode)] = f.remap.key
+	}
+	f.paint()
+}
+
+// nextRemapKey moves the remapping screen to the next key of the keypad in
+// layout order, or closes it after the last and saves the bindings
+func (f *Frontend) nextRemapKey() {
+	for i, row := range keymap.Layout {
+		for j, k := range row {
+			if k != f.remap.key {
+				continue
+			}
+			if next := i*4 + j + 1; next < 16 {
+				f.remap.key = keymap.Layout[next/4][next%4]
+				f.remap.bound = false
+				f.remapTitle()
+				return
+			}
+		}
+	}
+
+	f.keys = f.remap.keys
+	f.remap = nil
+	f.window.SetTitle(f.title)
+	if err := f.bind(); err != nil {
+		fmt.Println("error binding keys:", err.Error())
+	}
+	if f.keysFile != "" {
+		if err := f.keys.File().WriteFile(f.keysFile); err != nil {
+			fmt.Println("error saving key bindings:", err.Error())
+		} else {
+			fmt.Println("Saved key bindings to", f.keysFile)
+		}
+	}
+}
+
+// Buzz copies the audio pattern and pitch for the audio thread
+func (f *Frontend) Buzz(on bool, pattern [chip8.AudioPatternSize]byte, rate float64) {
+	if on {
+		audio.Lock()
+		audio.pattern = pattern
+		audio.rate = rate / sampleHz
+		audio.Unlock()
+	}
+	sdl2.PauseAudio(!on)
+}
+
+//export AudioCallback
+func AudioCallback(userdata unsafe.Pointer, stream *C.Uint8, length C.int) {
+	n := int(length)
+	buf := unsafe.Slice(stream, n)
+
+	audio.Lock()
+	defer audio.Unlock()
+
+	patternBits := float64(8 * len(audio.pattern))
+	for i := 0; i < n; i += 2 {
+		bit := int(audio.position)
+		sample := C.Uint8(sampleLow)
+		if audio.pattern[bit/8]&(0x80>>uint(bit%8)) != 0 {
+			sample = sampleHigh
+		}
+		buf[i] = sample
+		buf[i+1] = sample
+
+		audio.position += audio.rate
+		if audio.position >= patternBits {
+			audio.position -= patternBits
+		}
+	}
+}
+
+func (f *Frontend) Draw(display *frontend.Display, width, height int) {
+	f.display, f.displayWidth, f.displayHeight = *display, width, height
+	f.paint()
+}
+
+// paint renders the remapping screen while it's open, otherwise the last
+// display drawn
+func (f *Frontend) paint() {
+	background := f.palette[0]
+	f.renderer.SetDrawColor(background[0], background[1], background[2], 1)
+	f.renderer.Clear()
+
+	if f.remap != nil {
+		f.drawRemap()
+		f.renderer.Present()
+		return
+	}
+
+	// Window size is fixed, high resolution pixels are smaller
+	size := f.width / f.displayWidth
+	f.pixel.W = int32(size)
+	f.pixel.H = int32(size)
+
+	for y := 0; y < f.displayHeight; y++ {
+		for x := 0; x < f.displayWidth; x++ {
+			// Only draw if on in a plane
+			if f.display[x][y] != 0 {
+				colour := f.palette[f.display[x][y]]
+				f.renderer.SetDrawColor(colour[0], colour[1], colour[2], 1)
+				f.pixel.X = int32(size * x)
+				f.pixel.Y = int32(size * y)
+				f.renderer.FillRect(f.pixel)
+			}
+
+		}
+	}
+
+	f.renderer.Present()
+}
+
+// drawRemap draws the keypad in place of the display, the key being remapped
+// in the colour of plane 1 and the others in the colour of both planes, with
+// a bar under those bound to a host key
+func (f *Frontend) drawRemap() {
+	// The keypad is 4x4 cells of 3 by 2 pixels, in the middle 12 by 8 of 16 by 8
+	cell := f.width / 16
+	for i, row := range keymap.Layout {
+		for j, k := range row {
+			colour := f.palette[3]
+			if k == f.remap.key {
+				colour = f.palette[1]
+			}
+			f.renderer.SetDrawColor(colour[0], colour[1], colour[2], 1)
+			f.pixel.X = int32(cell*(2+3*j) + cell/2)
+			f.pixel.Y = int32(cell*2*i + cell/2)
+			f.pixel.W = int32(cell * 2)
+			f.pixel.H = int32(cell)
+			f.renderer.FillRect(f.pixel)
+
+			if len(f.remap.keys.Hosts(k)) > 0 {
+				f.pixel.Y += int32(cell + cell/4)
+				f.pixel.H = int32(cell / 4)
+				f.renderer.FillRect(f.pixel)
+			}
+		}
+	}
+}
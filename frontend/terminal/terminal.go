@@ -0,0 +1,373 @@
+// Package terminal is a frontend drawing the display with Unicode block or
+// braille characters and reading the keypad from a raw TTY, it needs
+// neither cgo nor a display server
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/keymap"
+)
+
+const (
+	// Terminals don't report key releases, a key is released once this many
+	// frames pass without it being repeated
+	KeyHoldFrames = 10
+
+	// Frames between rings of the bell while buzzing
+	bellFrames = 15
+
+	brailleBase = 0x2800
+)
+
+var (
+	// Input of host keys which aren't a single character, as escape sequences
+	// without the leading escape or characters
+	hostInput = map[string]string{
+		"Up": "[A", "Down": "[B", "Right": "[C", "Left": "[D",
+		"Space": " ", "Return": "\r", "Tab": "\t", "Keypad Enter": "\r",
+	}
+
+	// Hotkeys sent as escape sequences, without the leading escape
+	escapeHotkeys = map[string]frontend.EventType{
+		"[15~": frontend.SaveState,    // F5
+		"[18~": frontend.NextSaveSlot, // F7
+		"[20~": frontend.LoadState,    // F9
+	}
+
+	// Dot bits of a braille character, indexed by row then column in the cell
+	brailleDots = [4][2]rune{
+		{0x01, 0x08},
+		{0x02, 0x10},
+		{0x04, 0x20},
+		{0x40, 0x80},
+	}
+)
+
+func init() {
+	frontend.Register("terminal", func(opts frontend.Options) (frontend.Frontend, error) {
+		return Open(opts)
+	})
+}
+
+// Frontend implements frontend.Frontend in a terminal. Only cells which
+// changed since the last draw are written
+type Frontend struct {
+	out *bufio.Writer
+
+	// Cell size in pixels
+	cellWidth, cellHeight int
+
+	// Characters on screen, nil until the first draw or after a resolution change
+	cells [][]rune
+
+	input chan []byte
+
+	// Chip 8 keys by the escape sequence or lower case character of the host
+	// key bound to them
+	keys map[string]chip8.Key
+
+	// Frames left until a held key or rewind is released
+	held      map[chip8.Key]int
+	rewinding int
+
+	// Frames buzzed since the bell last rang
+	buzzFrames int
+
+	sttyState string
+}
+
+// Open switches stdin to raw mode and stdout to the alternate screen.
+// opts.Chars selects half (blocks, the default) or braille characters
+func Open(opts frontend.Options) (*Frontend, error) {
+	f := newFrontend(os.Stdout)
+	switch opts.Chars {
+	case "", "half":
+	case "braille":
+		f.cellWidth, f.cellHeight = 2, 4
+	default:
+		return nil, fmt.Errorf("terminal: unknown characters %s", opts.Chars)
+	}
+	keys := opts.Keys
+	if keys == nil {
+		keys = keymap.Default()
+	}
+	f.bind(keys.WithGameKeys(opts.GameKeys))
+
+	// Save the terminal settings to restore on close
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("terminal: stdin is not a tty: %w", err)
+	}
+	f.sttyState = strings.TrimSpace(state)
+
+	// Read key presses as they happen without echoing, Ctrl-C is handled as a key
+	if _, err := stty("-icanon", "-echo", "-isig", "-ixon", "min", "1", "time", "0"); err != nil {
+		return nil, err
+	}
+
+	// Switch to the alternate screen, hide the cursor and clear
+	f.out.WriteString("\x1b[?1049h\x1b[?25l\x1b[2J")
+	f.out.Flush()
+
+	go f.readInput(os.Stdin)
+
+	return f, nil
+}
+
+// newFrontend creates a Frontend drawing half blocks to w
+func newFrontend(w io.Writer) *Frontend {
+	f := &Frontend{
+		out:        bufio.NewWriter(w),
+		cellWidth:  1,
+		cellHeight: 2,
+		input:      make(chan []byte, 16),
+		held:       make(map[chip8.Key]int),
+	}
+	f.bind(keymap.Default())
+	return f
+}
+
+// bind binds the input of the host keys in m. Scancodes are bound to the
+// character on their key of a US QWERTY keyboard, and host keys which
+// terminals don't send, such as shift, are skipped
+func (f *Frontend) bind(m keymap.Map) {
+	f.keys = make(map[string]chip8.Key, len(m))
+	for host, k := range m {
+		name := strings.TrimPrefix(host, keymap.ScancodePrefix)
+		if input, ok := hostInput[name]; ok {
+			f.keys[input] = k
+		} else if name = strings.TrimPrefix(name, "Keypad "); len(name) == 1 {
+			f.keys[strings.ToLower(name)] = k
+		}
+	}
+}
+
+func (f *Frontend) Close() {
+	// Show the cursor and switch back to the main screen
+	f.out.WriteString("\x1b[?25h\x1b[?1049l")
+	f.out.Flush()
+
+	stty(f.sttyState)
+}
+
+// stty runs stty on stdin with args, returning its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readInput sends everything read from r to the input channel, each read
+// from a terminal usually holds a single key or escape sequence
+func (f *Frontend) readInput(r io.Reader) {
+	for {
+		buf := make([]byte, 64)
+		n, err := r.Read(buf)
+		if err != nil {
+			close(f.input)
+			return
+		}
+		f.input <- buf[:n]
+	}
+}
+
+// Poll returns the events for the input read since the last frame, releasing
+// keys which haven't repeated within KeyHoldFrames
+func (f *Frontend) Poll() []frontend.Event {
+	var events []frontend.Event
+	for done := false; !done; {
+		select {
+		case data, ok := <-f.input:
+			if !ok {
+				// Stdin closed
+				f.input = nil
+				return append(events, frontend.Event{Type: frontend.Quit})
+			}
+			events = f.handleInput(events, data)
+		default:
+			done = true
+		}
+	}
+
+	for k, frames := range f.held {
+		if frames <= 1 {
+			delete(f.held, k)
+			events = append(events, frontend.Event{Type: frontend.KeyUp, Key: k})
+		} else {
+			f.held[k] = frames - 1
+		}
+	}
+
+	if f.rewinding > 0 {
+		f.rewinding--
+		if f.rewinding == 0 {
+			events = append(events, frontend.Event{Type: frontend.RewindStop})
+		}
+	}
+
+	return events
+}
+
+// handleInput appends the events for a single read from stdin
+func (f *Frontend) handleInput(events []frontend.Event, data []byte) []frontend.Event {
+	s := string(data)
+
+	// A lone escape is the escape key, otherwise it starts a sequence
+	if s == "\x1b" {
+		return append(events, frontend.Event{Type: frontend.Quit})
+	}
+
+	for len(s) > 0 {
+		if s[0] == '\x1b' {
+			seq, rest := parseEscape(s[1:])
+			if t, ok := escapeHotkeys[seq]; ok {
+				events = append(events, frontend.Event{Type: t})
+			} else if k, ok := f.keys[seq]; ok {
+				events = f.press(events, k)
+			}
+			s = rest
+			continue
+		}
+
+		switch s[0] {
+		case 0x03: // Ctrl-C
+			events = append(events, frontend.Event{Type: frontend.Quit})
+		case 0x7F, 0x08: // Backspace
+			if f.rewinding == 0 {
+				events = append(events, frontend.Event{Type: frontend.RewindStart})
+			}
+			f.rewinding = KeyHoldFrames
+		default:
+			if k, ok := f.keys[strings.ToLower(s[:1])]; ok {
+				events = f.press(events, k)
+			}
+		}
+		s = s[1:]
+	}
+
+	return events
+}
+
+// press appends a key down event for k unless it's held, and holds it
+func (f *Frontend) press(events []frontend.Event, k chip8.Key) []frontend.Event {
+	if _, pressed := f.held[k]; !pressed {
+		events = append(events, frontend.Event{Type: frontend.KeyDown, Key: k})
+	}
+	f.held[k] = KeyHoldFrames
+	return events
+}
+
+// parseEscape parses the escape sequence at the start of s, returning it
+// with O of application mode cursor keys as [, and the rest of s
+func parseEscape(s string) (string, string) {
+	if len(s) == 0 || (s[0] != '[' && s[0] != 'O') {
+		return "", s
+	}
+
+	// Sequences end with a byte from @ to ~
+	end := 1
+	for end < len(s) && (s[end] < 0x40 || s[end] > 0x7E) {
+		end++
+	}
+	if end == len(s) {
+		return "", ""
+	}
+
+	seq := s[:end+1]
+	if seq[0] == 'O' {
+		seq = "[" + seq[1:]
+	}
+	return seq, s[end+1:]
+}
+
+// Buzz rings the bell when buzzing starts and every bellFrames after, the
+// pattern and pitch can't be played
+func (f *Frontend) Buzz(on bool, pattern [chip8.AudioPatternSize]byte, rate float64) {
+	if !on {
+		f.buzzFrames = 0
+		return
+	}
+
+	if f.buzzFrames%bellFrames == 0 {
+		f.out.WriteByte('\a')
+		f.out.Flush()
+	}
+	f.buzzFrames++
+}
+
+func (f *Frontend) Draw(display *frontend.Display, width, height int) {
+	columns, rows := width/f.cellWidth, height/f.cellHeight
+
+	// Redraw everything after a resolution change
+	if len(f.cells) != rows || len(f.cells[0]) != columns {
+		f.cells = make([][]rune, rows)
+		for row := range f.cells {
+			f.cells[row] = make([]rune, columns)
+		}
+		f.out.WriteString("\x1b[2J")
+	}
+
+	for row := 0; row < rows; row++ {
+		// Writing a cell advances the cursor, it only needs moving after unchanged cells
+		positioned := false
+		for column := 0; column < columns; column++ {
+			var cell rune
+			if f.cellHeight == 2 {
+				cell = halfBlock(display[column][row*2] != 0, display[column][row*2+1] != 0)
+			} else {
+				cell = braille(display, column*2, row*4)
+			}
+
+			if cell == f.cells[row][column] {
+				positioned = false
+				continue
+			}
+			f.cells[row][column] = cell
+
+			if !positioned {
+				fmt.Fprintf(f.out, "\x1b[%d;%dH", row+1, column+1)
+				positioned = true
+			}
+			f.out.WriteRune(cell)
+		}
+	}
+
+	// Park the cursor below the display so messages don't overwrite it
+	fmt.Fprintf(f.out, "\x1b[%d;1H", rows+1)
+	f.out.Flush()
+}
+
+// halfBlock returns the character for a cell with the top and bottom pixel on or off
+func halfBlock(top, bottom bool) rune {
+	switch {
+	case top && bottom:
+		return '█'
+	case top:
+		return '▀'
+	case bottom:
+		return '▄'
+	}
+	return ' '
+}
+
+// braille returns the character for the 2x4 cell at x, y
+func braille(display *frontend.Display, x, y int) rune {
+	cell := rune(brailleBase)
+	for row, dots := range brailleDots {
+		for column, dot := range dots {
+			if display[x+column][y+row] != 0 {
+				cell |= dot
+			}
+		}
+	}
+	return cell
+}
@@ -0,0 +1,131 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/keymap"
+)
+
+func TestDraw(t *testing.T) {
+	c := chip8.New([]byte{
+		0xA0, 0x00, // I = 0
+		0xD0, 0x05, // Draw "0"
+		0x00, 0xE0, // Clear
+	}, chip8.Quirks{})
+
+	buf := &bytes.Buffer{}
+	f := newFrontend(buf)
+	draw := func() {
+		display := c.Display()
+		width, height := c.Resolution()
+		f.Draw(&display, width, height)
+	}
+
+	c.Step()
+	c.Step()
+	draw()
+
+	// Top row of "0" is 0xF0, second is 0x90
+	if got := string(f.cells[0][:5]); got != "█▀▀█ " {
+		t.Errorf("expected first row %q, actually %q", "█▀▀█ ", got)
+	}
+
+	// Nothing changed, only the cursor is parked
+	buf.Reset()
+	draw()
+	if got := buf.String(); got != "\x1b[17;1H" {
+		t.Errorf("expected no cells redrawn, actually %q", got)
+	}
+
+	// Only the cells of the sprite are redrawn
+	buf.Reset()
+	c.Step()
+	draw()
+	if got := strings.Count(buf.String(), " "); got != 10 {
+		t.Errorf("expected 10 cells cleared, actually %d in %q", got, buf.String())
+	}
+}
+
+func TestBraille(t *testing.T) {
+	var display frontend.Display
+	display[0][0] = 1
+	display[1][3] = 1
+
+	if got := braille(&display, 0, 0); got != '⢁' {
+		t.Errorf("expected %q, actually %q", '⢁', got)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	f := newFrontend(&bytes.Buffer{})
+	go f.readInput(strings.NewReader("W\x1b[Ax\x1b[15~"))
+
+	// Wait for all input to be read
+	var events []frontend.Event
+	for data := range f.input {
+		events = f.handleInput(events, data)
+	}
+	f.input = make(chan []byte)
+
+	// Unknown escape sequences are skipped
+	expected := []frontend.Event{
+		{Type: frontend.KeyDown, Key: chip8.Key5},
+		{Type: frontend.KeyDown, Key: chip8.Key0},
+		{Type: frontend.SaveState},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, actually %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected event %d to be %v, actually %v", i, expected[i], events[i])
+		}
+	}
+
+	// Keys are released once they stop repeating
+	released := 0
+	for i := 0; i < KeyHoldFrames; i++ {
+		for _, e := range f.Poll() {
+			if e.Type == frontend.KeyUp {
+				released++
+			}
+		}
+	}
+	if released != 2 || len(f.held) != 0 {
+		t.Errorf("expected 2 keys released, actually %d with %v held", released, f.held)
+	}
+}
+
+func TestGameKeys(t *testing.T) {
+	f := newFrontend(&bytes.Buffer{})
+	f.bind(keymap.Default().WithGameKeys(map[string]chip8.Key{"left": chip8.Key4, "a": chip8.Key5}))
+
+	// Normal and application mode cursor keys, and space
+	events := f.handleInput(nil, []byte("\x1b[D \x1bOD"))
+	expected := []frontend.Event{
+		{Type: frontend.KeyDown, Key: chip8.Key4},
+		{Type: frontend.KeyDown, Key: chip8.Key5},
+	}
+	if len(events) != len(expected) || events[0] != expected[0] || events[1] != expected[1] {
+		t.Errorf("expected events %v, actually %v", expected, events)
+	}
+}
+
+func TestBind(t *testing.T) {
+	f := newFrontend(&bytes.Buffer{})
+	f.bind(keymap.Map{"scancode:Q": chip8.Key4, "Keypad 7": chip8.Key7, "Return": chip8.KeyE, "Left Shift": chip8.Key1})
+
+	expected := map[string]chip8.Key{"q": chip8.Key4, "7": chip8.Key7, "\r": chip8.KeyE}
+	if len(f.keys) != len(expected) {
+		t.Errorf("expected keys %v, actually %v", expected, f.keys)
+	}
+	for input, k := range expected {
+		if f.keys[input] != k {
+			t.Errorf("expected %q bound to %X, actually %v", input, k, f.keys)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+// Frontends built without cgo, see frontends_cgo.go for SDL
+import (
+	_ "github.com/pmcatominey/gochip8/frontend/headless"
+	_ "github.com/pmcatominey/gochip8/frontend/terminal"
+)
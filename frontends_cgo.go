@@ -0,0 +1,8 @@
+//go:build cgo
+
+package main
+
+// SDL needs cgo, without it only the terminal and headless frontends are available
+import (
+	_ "github.com/pmcatominey/gochip8/frontend/sdl"
+)
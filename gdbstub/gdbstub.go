@@ -0,0 +1,195 @@
+// Package gdbstub serves the GDB remote serial protocol for a Chip 8 driven
+// by a chip8.Runner, so generic debugger frontends can attach to a running
+// rom.
+//
+// Registers are numbered PC (0), I (1), SP (2), V0 to VF (3 to 18), the
+// delay timer (19) and the sound timer (20), and are sent big endian as on
+// the Chip 8. SP is the number of return addresses on the stack. The whole
+// of memory can be read and written. Software breakpoints (Z0), single-step
+// and continue are supported, other packets are answered as unsupported.
+//
+// Packets are handled between frames on the goroutine running the Runner,
+// which must call EndFrame after each frame, Fault when the Runner returns
+// a *chip8.Error, and pause the Runner while Stopped returns true.
+package gdbstub
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// Stop replies, giving the signal which stopped the target
+const (
+	stopInterrupt = "S02" // SIGINT, interrupted by the client
+	stopTrap      = "S05" // SIGTRAP, breakpoint or step
+	stopIllegal   = "S04" // SIGILL, unknown opcode
+	stopSegv      = "S0b" // SIGSEGV, invalid memory access or PC
+)
+
+// Stub is a GDB server for a single Runner, serving one client at a time
+type Stub struct {
+	runner *chip8.Runner
+	c      *chip8.Chip8
+
+	// Functions run on the emulator goroutine by EndFrame, each returning a reply
+	requests chan request
+
+	// Stop replies for continue, sent by EndFrame
+	stops chan string
+
+	// Only accessed on the emulator goroutine
+	attached    bool
+	running     bool
+	resuming    bool // skip breakpoints for the instruction at PC when continuing from it
+	breakpoints map[uint16]bool
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// request is a function to run on the emulator goroutine
+type request struct {
+	fn    func() string
+	reply chan string
+}
+
+// New creates a Stub for r, which runs normally until a client attaches.
+// The Runner's Break is set to the stub and should not be changed
+func New(r *chip8.Runner) *Stub {
+	s := &Stub{
+		runner:      r,
+		c:           r.Chip8(),
+		requests:    make(chan request),
+		stops:       make(chan string, 1),
+		running:     true,
+		breakpoints: make(map[uint16]bool),
+	}
+	r.Break = s.shouldBreak
+	return s
+}
+
+// Listen listens on a TCP address such as localhost:1234, or a Unix socket
+// given as unix:/path/to/socket
+func Listen(address string) (net.Listener, error) {
+	if path := strings.TrimPrefix(address, "unix:"); path != address {
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", address)
+}
+
+// Serve accepts clients on l one at a time until Close is called
+func (s *Stub) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		newSession(s, conn).serve()
+	}
+}
+
+// Close stops serving
+func (s *Stub) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// Stopped returns true while a client has the target stopped, the Runner
+// should be paused until it continues
+func (s *Stub) Stopped() bool {
+	return !s.running
+}
+
+// EndFrame should be called on the emulator goroutine after each frame,
+// it reports breakpoints to the client and handles a pending packet
+func (s *Stub) EndFrame(result chip8.FrameResult) {
+	if result.Stopped && s.running {
+		s.stop(stopTrap)
+	}
+
+	select {
+	case req := <-s.requests:
+		req.reply <- req.fn()
+	default:
+	}
+}
+
+// Fault should be called on the emulator goroutine when the Runner returns
+// a *chip8.Error, it stops with the signal for err so the client can
+// inspect the state. Returns false if no client is attached
+func (s *Stub) Fault(err *chip8.Error) bool {
+	if !s.attached {
+		return false
+	}
+	s.stop(stopReply(err))
+	return true
+}
+
+// do runs fn on the emulator goroutine, returning its reply
+func (s *Stub) do(fn func() string) string {
+	req := request{fn, make(chan string)}
+	s.requests <- req
+	return <-req.reply
+}
+
+// shouldBreak is the Runner's Break, returning true before an instruction
+// with a breakpoint
+func (s *Stub) shouldBreak(c *chip8.Chip8) bool {
+	if s.resuming {
+		s.resuming = false
+		return false
+	}
+	return s.breakpoints[c.Registers().PC]
+}
+
+// stop stops execution, sending reply to a client waiting on continue
+func (s *Stub) stop(reply string) {
+	s.running = false
+	select {
+	case s.stops <- reply:
+	default:
+	}
+}
+
+// resume continues execution until a breakpoint or interrupt
+func (s *Stub) resume() {
+	s.running = true
+	s.resuming = true
+}
+
+// step executes a single instruction, returning the stop reply
+func (s *Stub) step() string {
+	if _, err := s.c.Step(); err != nil {
+		return stopReply(err)
+	}
+	return stopTrap
+}
+
+// stopReply returns the stop reply for an error from Step
+func stopReply(err error) string {
+	switch {
+	case errors.Is(err, chip8.ErrUnknownOpcode):
+		return stopIllegal
+	case errors.Is(err, chip8.ErrInvalidPC), errors.Is(err, chip8.ErrMemoryOutOfBounds),
+		errors.Is(err, chip8.ErrStackOverflow), errors.Is(err, chip8.ErrStackUnderflow):
+		return stopSegv
+	}
+	return stopTrap
+}
@@ -0,0 +1,200 @@
+package gdbstub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// client is a scripted GDB client
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// request sends a packet and returns the reply
+func (c *client) request(packet string) string {
+	c.t.Helper()
+	fmt.Fprintf(c.conn, "$%s#%02x", packet, packetChecksum(packet))
+	c.expectAck()
+	return c.reply()
+}
+
+func (c *client) expectAck() {
+	c.t.Helper()
+	if b, err := c.r.ReadByte(); err != nil || b != '+' {
+		c.t.Fatalf("expected ack, actually %q %v", b, err)
+	}
+}
+
+// reply reads and acknowledges a packet
+func (c *client) reply() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if b, err := c.r.ReadByte(); err != nil || b != '$' {
+		c.t.Fatalf("expected packet, actually %q %v", b, err)
+	}
+
+	data, err := c.r.ReadString('#')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	data = data[:len(data)-1]
+
+	checksum := make([]byte, 2)
+	c.r.Read(checksum)
+	if string(checksum) != fmt.Sprintf("%02x", packetChecksum(data)) {
+		c.t.Errorf("bad checksum %s for %q", checksum, data)
+	}
+
+	c.conn.Write([]byte("+"))
+	return data
+}
+
+func (c *client) expect(packet, reply string) {
+	c.t.Helper()
+	if got := c.request(packet); got != reply {
+		c.t.Errorf("%s: expected %q, actually %q", packet, reply, got)
+	}
+}
+
+// startStub runs program with a Stub listening on loopback, returning a
+// connected client
+func startStub(t *testing.T, program []byte) *client {
+	c := chip8.New(program, chip8.Quirks{})
+	r := chip8.NewRunner(c, 10)
+	stub := New(r)
+
+	l, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go stub.Serve(l)
+	t.Cleanup(func() { stub.Close() })
+
+	// Emulator loop, frames are run as fast as possible
+	done := make(chan bool)
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			r.Paused = stub.Stopped()
+			result, err := r.RunFrame()
+			var fault *chip8.Error
+			if errors.As(err, &fault) && stub.Fault(fault) {
+				err = nil
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			stub.EndFrame(result)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &client{t, conn, bufio.NewReader(conn)}
+}
+
+func TestSession(t *testing.T) {
+	cl := startStub(t, []byte{
+		0x60, 0x05, // 0x200 V0 = 5
+		0xA3, 0x00, // 0x202 I = 0x300
+		0x71, 0x01, // 0x204 V1 += 1
+		0x12, 0x04, // 0x206 Jump to 0x204
+	})
+
+	if reply := cl.request("qSupported:multiprocess+"); !strings.Contains(reply, "qXfer:features:read+") {
+		t.Errorf("unexpected qSupported reply %q", reply)
+	}
+	if reply := cl.request("qXfer:features:read:target.xml:0,1000"); !strings.HasPrefix(reply, "l<?xml") || !strings.Contains(reply, `name="vf"`) {
+		t.Errorf("unexpected target.xml %q", reply)
+	}
+	cl.expect("?", "S05")
+
+	// The rom was running before attaching, restart it
+	cl.expect("P0=0200", "OK")
+	cl.expect("P4=00", "OK")
+
+	// Registers are PC, I, SP, V0-VF, DT, ST
+	if reply := cl.request("g"); len(reply) != 2*(3*2+16+2) || !strings.HasPrefix(reply, "0200") {
+		t.Errorf("unexpected registers %q", reply)
+	}
+
+	cl.expect("s", "S05")
+	cl.expect("p0", "0202")
+	cl.expect("p3", "05")
+
+	// Break in the loop
+	cl.expect("Z0,206,2", "OK")
+	cl.expect("c", "S05")
+	cl.expect("p0", "0206")
+	cl.expect("p1", "0300")
+	cl.expect("p4", "01")
+
+	// Continuing from a breakpoint runs to it again
+	cl.expect("c", "S05")
+	cl.expect("p4", "02")
+	cl.expect("z0,206,2", "OK")
+
+	// Memory
+	cl.expect("m200,4", "6005a300")
+	cl.expect("M300,2:abcd", "OK")
+	cl.expect("m300,2", "abcd")
+	cl.expect("mffff,2", "E01")
+
+	// Register writes
+	cl.expect("P3=7f", "OK")
+	cl.expect("p3", "7f")
+	cl.expect("P2=0011", "E01")
+
+	// Interrupt a continue
+	fmt.Fprintf(cl.conn, "$c#%02x", packetChecksum("c"))
+	cl.expectAck()
+	time.Sleep(10 * time.Millisecond)
+	cl.conn.Write([]byte{interruptByte})
+	if reply := cl.reply(); reply != "S02" {
+		t.Errorf("expected interrupt to stop with S02, actually %q", reply)
+	}
+
+	cl.expect("vMustReplyEmpty", "")
+	cl.expect("D", "OK")
+}
+
+func TestFault(t *testing.T) {
+	cl := startStub(t, []byte{
+		0x60, 0x05, // 0x200 V0 = 5
+		0x12, 0x02, // 0x202 Jump to 0x202
+		0x00, 0xEE, // 0x204 Return with an empty stack
+	})
+
+	// Jump to the fault once attached, it stops instead of ending the rom
+	cl.expect("P0=0204", "OK")
+	cl.expect("c", "S0b")
+	cl.expect("p0", "0204")
+	cl.expect("p3", "05")
+
+	// Continuing faults again
+	cl.expect("c", "S0b")
+
+	// Back to the loop, without a client a fault ends the rom
+	cl.expect("P0=0202", "OK")
+	cl.expect("D", "OK")
+}
@@ -0,0 +1,460 @@
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+const (
+	// Interrupt sent by the client outside of a packet, passed on as a packet
+	interruptByte   = 0x03
+	interruptPacket = "\x03"
+
+	// Largest packet accepted, advertised in qSupported
+	maxPacketSize = 0x4000
+
+	// Number of registers, see the package documentation
+	registerCount = 3 + chip8.VRegisterCount + 2
+
+	targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gochip8.chip8">
+    <reg name="pc" bitsize="16" type="code_ptr" regnum="0"/>
+    <reg name="i" bitsize="16" type="data_ptr"/>
+    <reg name="sp" bitsize="16" type="uint16"/>
+` + "%s" + `    <reg name="dt" bitsize="8" type="uint8"/>
+    <reg name="st" bitsize="8" type="uint8"/>
+  </feature>
+</target>
+`
+)
+
+// Replies to packets which don't return data
+const (
+	replyOK          = "OK"
+	replyUnsupported = ""
+	replyError       = "E01"
+)
+
+// session is a connection to a client
+type session struct {
+	stub *Stub
+	conn net.Conn
+	w    *bufio.Writer
+
+	// Packets and interrupts read from the client, closed when it disconnects
+	packets chan string
+
+	target string
+}
+
+func newSession(s *Stub, conn net.Conn) *session {
+	var regs strings.Builder
+	for i := 0; i < chip8.VRegisterCount; i++ {
+		fmt.Fprintf(&regs, "    <reg name=\"v%x\" bitsize=\"8\" type=\"uint8\"/>\n", i)
+	}
+
+	return &session{
+		stub:    s,
+		conn:    conn,
+		w:       bufio.NewWriter(conn),
+		packets: make(chan string),
+		target:  fmt.Sprintf(targetXML, regs.String()),
+	}
+}
+
+// serve handles packets until the client detaches or disconnects, the
+// target is stopped while attached
+func (sess *session) serve() {
+	defer sess.conn.Close()
+
+	go sess.read()
+
+	sess.stub.do(func() string {
+		sess.stub.attached = true
+		sess.stub.running = false
+		sess.drainStops()
+		return ""
+	})
+
+	// Let the rom run again once detached
+	defer sess.stub.do(func() string {
+		sess.stub.breakpoints = make(map[uint16]bool)
+		sess.stub.attached = false
+		sess.stub.running = true
+		sess.drainStops()
+		return ""
+	})
+
+	for packet := range sess.packets {
+		if packet == interruptPacket {
+			// Not running, nothing to interrupt
+			continue
+		}
+
+		reply, ok := sess.handle(packet)
+		if !ok {
+			return
+		}
+		if err := sess.send(reply); err != nil {
+			return
+		}
+	}
+}
+
+// drainStops discards an unsent stop reply
+func (sess *session) drainStops() {
+	select {
+	case <-sess.stub.stops:
+	default:
+	}
+}
+
+// read sends the packets read from the client to the packets channel,
+// acknowledging them until the client requests no ack mode
+func (sess *session) read() {
+	defer close(sess.packets)
+
+	ack := true
+	r := bufio.NewReader(sess.conn)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		switch b {
+		case interruptByte:
+			sess.packets <- interruptPacket
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				return
+			}
+			data = data[:len(data)-1]
+
+			checksum := make([]byte, 2)
+			if _, err := io.ReadFull(r, checksum); err != nil {
+				return
+			}
+
+			if ack {
+				if sum, err := strconv.ParseUint(string(checksum), 16, 8); err != nil || byte(sum) != packetChecksum(data) {
+					sess.conn.Write([]byte("-"))
+					continue
+				}
+				sess.conn.Write([]byte("+"))
+			}
+
+			data = unescape(data)
+			if data == "QStartNoAckMode" {
+				ack = false
+			}
+			sess.packets <- data
+		}
+		// Acknowledgements from the client are ignored
+	}
+}
+
+// send writes a packet with reply as its data
+func (sess *session) send(reply string) error {
+	fmt.Fprintf(sess.w, "$%s#%02x", reply, packetChecksum(reply))
+	return sess.w.Flush()
+}
+
+// packetChecksum returns the sum of the bytes of data modulo 256
+func packetChecksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// unescape removes the escaping of } # $ and * from packet data
+func unescape(data string) string {
+	if !strings.Contains(data, "}") {
+		return data
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] == '}' && i+1 < len(data) {
+			i++
+			b.WriteByte(data[i] ^ 0x20)
+		} else {
+			b.WriteByte(data[i])
+		}
+	}
+	return b.String()
+}
+
+// handle returns the reply to a packet, ok is false when the session should end
+func (sess *session) handle(packet string) (reply string, ok bool) {
+	s := sess.stub
+	if len(packet) == 0 {
+		return replyUnsupported, true
+	}
+
+	switch packet[0] {
+	case '?':
+		return stopTrap, true
+	case 'g':
+		return s.do(func() string {
+			return encodeRegisters(s.c.Registers())
+		}), true
+	case 'G':
+		return s.do(func() string {
+			return s.writeRegisters(packet[1:], -1)
+		}), true
+	case 'p':
+		n, err := strconv.ParseUint(packet[1:], 16, 8)
+		if err != nil || n >= registerCount {
+			return replyError, true
+		}
+		return s.do(func() string {
+			regs := encodeRegisters(s.c.Registers())
+			start, end := registerOffset(int(n))
+			return regs[start:end]
+		}), true
+	case 'P':
+		fields := strings.SplitN(packet[1:], "=", 2)
+		n, err := strconv.ParseUint(fields[0], 16, 8)
+		if err != nil || n >= registerCount || len(fields) != 2 {
+			return replyError, true
+		}
+		return s.do(func() string {
+			return s.writeRegisters(fields[1], int(n))
+		}), true
+	case 'm':
+		address, length, _, err := parseMemoryArgs(packet[1:])
+		if err != nil {
+			return replyError, true
+		}
+		return s.do(func() string {
+			data, err := s.c.ReadMemory(address, length)
+			if err != nil {
+				return replyError
+			}
+			return hex.EncodeToString(data)
+		}), true
+	case 'M':
+		address, length, data, err := parseMemoryArgs(packet[1:])
+		if err != nil || len(data) != length {
+			return replyError, true
+		}
+		return s.do(func() string {
+			if err := s.c.WriteMemory(address, data); err != nil {
+				return replyError
+			}
+			return replyOK
+		}), true
+	case 'Z', 'z':
+		return sess.breakpoint(packet), true
+	case 's':
+		return s.do(s.step), true
+	case 'c':
+		return sess.cont()
+	case 'D':
+		sess.send(replyOK)
+		return "", false
+	case 'k':
+		return "", false
+	case 'H':
+		// Only one thread
+		return replyOK, true
+	case 'q':
+		return sess.query(packet), true
+	case 'Q':
+		if packet == "QStartNoAckMode" {
+			return replyOK, true
+		}
+	}
+
+	return replyUnsupported, true
+}
+
+// cont continues until a breakpoint or the client interrupts
+func (sess *session) cont() (string, bool) {
+	s := sess.stub
+	s.do(func() string {
+		s.resume()
+		return ""
+	})
+
+	for {
+		select {
+		case reply := <-s.stops:
+			return reply, true
+		case packet, ok := <-sess.packets:
+			if !ok {
+				// Disconnected while running
+				return "", false
+			}
+			if packet == interruptPacket {
+				s.do(func() string {
+					if s.running {
+						s.stop(stopInterrupt)
+					}
+					return ""
+				})
+			}
+			// Other packets can't be handled while running
+		}
+	}
+}
+
+// breakpoint handles Z and z packets, only software breakpoints are supported
+func (sess *session) breakpoint(packet string) string {
+	fields := strings.Split(packet[1:], ",")
+	if len(fields) < 2 || fields[0] != "0" {
+		return replyUnsupported
+	}
+
+	address, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return replyError
+	}
+
+	s := sess.stub
+	return s.do(func() string {
+		if packet[0] == 'Z' {
+			s.breakpoints[uint16(address)] = true
+		} else {
+			delete(s.breakpoints, uint16(address))
+		}
+		return replyOK
+	})
+}
+
+// query handles q packets
+func (sess *session) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+", maxPacketSize)
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return readXfer(sess.target, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"))
+	}
+	return replyUnsupported
+}
+
+// readXfer returns the part of data requested by the offset,length argument
+// of a qXfer read
+func readXfer(data, args string) string {
+	fields := strings.Split(args, ",")
+	if len(fields) != 2 {
+		return replyError
+	}
+	offset, err1 := strconv.ParseUint(fields[0], 16, 32)
+	length, err2 := strconv.ParseUint(fields[1], 16, 32)
+	if err1 != nil || err2 != nil {
+		return replyError
+	}
+
+	if offset >= uint64(len(data)) {
+		return "l"
+	}
+	data = data[offset:]
+	if uint64(len(data)) > length {
+		return "m" + data[:length]
+	}
+	return "l" + data
+}
+
+// parseMemoryArgs parses the addr,length and optional :data of m and M packets
+func parseMemoryArgs(args string) (address uint16, length int, data []byte, err error) {
+	fields := strings.SplitN(args, ":", 2)
+	if len(fields) == 2 {
+		if data, err = hex.DecodeString(fields[1]); err != nil {
+			return
+		}
+	}
+
+	addressLength := strings.Split(fields[0], ",")
+	if len(addressLength) != 2 {
+		err = fmt.Errorf("invalid memory arguments %q", args)
+		return
+	}
+
+	a, err := strconv.ParseUint(addressLength[0], 16, 16)
+	if err != nil {
+		return
+	}
+	l, err := strconv.ParseUint(addressLength[1], 16, 32)
+	if err != nil {
+		return
+	}
+	return uint16(a), int(l), data, nil
+}
+
+// encodeRegisters returns the registers as hex in register number order
+func encodeRegisters(r chip8.Registers) string {
+	data := []byte{
+		byte(r.PC >> 8), byte(r.PC),
+		byte(r.I >> 8), byte(r.I),
+		byte(r.SP >> 8), byte(r.SP),
+	}
+	data = append(data, r.V[:]...)
+	data = append(data, r.Delay, r.Sound)
+	return hex.EncodeToString(data)
+}
+
+// registerOffset returns the start and end of register n in the hex from encodeRegisters
+func registerOffset(n int) (start, end int) {
+	switch {
+	case n < 3:
+		return n * 4, n*4 + 4
+	default:
+		start = 12 + (n-3)*2
+		return start, start + 2
+	}
+}
+
+// writeRegisters decodes value as register n, or all registers if n is -1
+func (s *Stub) writeRegisters(value string, n int) string {
+	regs := encodeRegisters(s.c.Registers())
+	if n >= 0 {
+		start, end := registerOffset(n)
+		if len(value) != end-start {
+			return replyError
+		}
+		regs = regs[:start] + value + regs[end:]
+	} else if len(value) != len(regs) {
+		return replyError
+	} else {
+		regs = value
+	}
+
+	data, err := hex.DecodeString(regs)
+	if err != nil {
+		return replyError
+	}
+
+	r := s.c.Registers()
+	r.PC = uint16(data[0])<<8 | uint16(data[1])
+	r.I = uint16(data[2])<<8 | uint16(data[3])
+	r.SP = uint16(data[4])<<8 | uint16(data[5])
+	copy(r.V[:], data[6:])
+	r.Delay, r.Sound = data[6+chip8.VRegisterCount], data[7+chip8.VRegisterCount]
+
+	if err := s.c.SetRegisters(r); err != nil {
+		return replyError
+	}
+	return replyOK
+}
@@ -0,0 +1,290 @@
+// Package keymap binds host keys to the Chip 8 keypad. Host keys are named
+// as SDL names them: a key name such as Q, Keypad 7 or Up is the key with
+// that label in the current keyboard layout, and scancode: followed by a key
+// name is the key in that position on a US QWERTY keyboard, whatever its
+// label, e.g. scancode:Q is A on an AZERTY keyboard.
+//
+// Key bindings files are JSON, they start from the union of presets and
+// bind host keys by Chip 8 key, 0 to F:
+//
+//	{
+//		"presets": ["qwerty", "numpad"],
+//		"keys": {
+//			"5": ["scancode:W", "Space"],
+//			"8": ["scancode:S", "Down"]
+//		}
+//	}
+package keymap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// ScancodePrefix starts the names of keys bound by position
+const ScancodePrefix = "scancode:"
+
+// Map is the Chip 8 key bound to each host key, several host keys may be
+// bound to the same Chip 8 key
+type Map map[string]chip8.Key
+
+// File is a key bindings file
+type File struct {
+	// Presets whose bindings are combined, none keeps the bindings the file
+	// is applied to
+	Presets []string `json:"presets,omitempty"`
+
+	// Host keys by Chip 8 key in hex, replacing the key's other bindings
+	Keys map[string][]string `json:"keys,omitempty"`
+}
+
+var (
+	// Preset layouts by name
+	Presets = map[string]Map{
+		// The keypad as a 4x4 grid on the left of the keyboard, by position
+		"qwerty": grid(ScancodePrefix,
+			"1", "2", "3", "4",
+			"Q", "W", "E", "R",
+			"A", "S", "D", "F",
+			"Z", "X", "C", "V"),
+
+		// The digits on the numeric keypad, A to F on the keys around them
+		"numpad": {
+			"Keypad 0": chip8.Key0, "Keypad 1": chip8.Key1, "Keypad 2": chip8.Key2, "Keypad 3": chip8.Key3,
+			"Keypad 4": chip8.Key4, "Keypad 5": chip8.Key5, "Keypad 6": chip8.Key6, "Keypad 7": chip8.Key7,
+			"Keypad 8": chip8.Key8, "Keypad 9": chip8.Key9, "Keypad /": chip8.KeyA, "Keypad *": chip8.KeyB,
+			"Keypad -": chip8.KeyC, "Keypad +": chip8.KeyD, "Keypad Enter": chip8.KeyE, "Keypad .": chip8.KeyF,
+		},
+
+		// Each key on the keys labelled with its hex digit, as the COSMAC VIP's keypad
+		"vip": {
+			"0": chip8.Key0, "1": chip8.Key1, "2": chip8.Key2, "3": chip8.Key3,
+			"4": chip8.Key4, "5": chip8.Key5, "6": chip8.Key6, "7": chip8.Key7,
+			"8": chip8.Key8, "9": chip8.Key9, "A": chip8.KeyA, "B": chip8.KeyB,
+			"C": chip8.KeyC, "D": chip8.KeyD, "E": chip8.KeyE, "F": chip8.KeyF,
+		},
+	}
+
+	// Host keys bound to a game's controls by name, see WithGameKeys
+	GameKeyHosts = map[string]string{
+		"up": "Up", "down": "Down", "left": "Left", "right": "Right",
+		"a": "Space", "b": "Return",
+	}
+
+	// Chip 8 keys in the layout of the keypad, by row
+	Layout = [4][4]chip8.Key{
+		{chip8.Key1, chip8.Key2, chip8.Key3, chip8.KeyC},
+		{chip8.Key4, chip8.Key5, chip8.Key6, chip8.KeyD},
+		{chip8.Key7, chip8.Key8, chip8.Key9, chip8.KeyE},
+		{chip8.KeyA, chip8.Key0, chip8.KeyB, chip8.KeyF},
+	}
+)
+
+// grid binds hosts, prefixed with prefix, to the keypad in Layout order
+func grid(prefix string, hosts ...string) Map {
+	m := make(Map, len(hosts))
+	for i, host := range hosts {
+		m[prefix+host] = Layout[i/4][i%4]
+	}
+	return m
+}
+
+// PresetNames returns the names of the presets in alphabetical order
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Default returns the bindings used without a bindings file, the qwerty preset
+func Default() Map {
+	return Presets["qwerty"].Clone()
+}
+
+// Clone returns a copy of m
+func (m Map) Clone() Map {
+	c := make(Map, len(m))
+	for host, k := range m {
+		c[host] = k
+	}
+	return c
+}
+
+// Hosts returns the host keys bound to k, sorted
+func (m Map) Hosts(k chip8.Key) []string {
+	var hosts []string
+	for host, bound := range m {
+		if bound == k {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Unbind removes the bindings of k
+func (m Map) Unbind(k chip8.Key) {
+	for host, bound := range m {
+		if bound == k {
+			delete(m, host)
+		}
+	}
+}
+
+// WithGameKeys returns a copy of m which also binds the host keys in
+// GameKeyHosts to a game's controls, keys is the Chip 8 key of each control
+// by name. Host keys bound in m keep their bindings
+func (m Map) WithGameKeys(keys map[string]chip8.Key) Map {
+	c := m.Clone()
+	for name, k := range keys {
+		if host, ok := GameKeyHosts[name]; ok {
+			if _, bound := c[host]; !bound {
+				c[host] = k
+			}
+		}
+	}
+	return c
+}
+
+// Apply returns a copy of m with the bindings of f
+func (m Map) Apply(f *File) (Map, error) {
+	c := m.Clone()
+	if len(f.Presets) > 0 {
+		c = make(Map)
+		for _, name := range f.Presets {
+			preset, ok := Presets[name]
+			if !ok {
+				return nil, fmt.Errorf("keymap: unknown preset %q, expected one of %s", name, strings.Join(PresetNames(), ", "))
+			}
+			for host, k := range preset {
+				c[host] = k
+			}
+		}
+	}
+
+	// Sorted so a host key listed under several Chip 8 keys binds the same one every time
+	digits := make([]string, 0, len(f.Keys))
+	for digit := range f.Keys {
+		digits = append(digits, digit)
+	}
+	sort.Strings(digits)
+
+	for _, digit := range digits {
+		n, err := strconv.ParseUint(digit, 16, 8)
+		if err != nil || n > uint64(chip8.KeyF) {
+			return nil, fmt.Errorf("keymap: invalid Chip 8 key %q, expected 0 to F", digit)
+		}
+		k := chip8.Key(n)
+		c.Unbind(k)
+		for _, host := range f.Keys[digit] {
+			if host == "" {
+				return nil, fmt.Errorf("keymap: empty host key bound to %X", k)
+			}
+			c[host] = k
+		}
+	}
+	return c, nil
+}
+
+// File returns a bindings file listing every binding of m
+func (m Map) File() *File {
+	f := &File{Keys: make(map[string][]string)}
+	for k := chip8.Key0; k <= chip8.KeyF; k++ {
+		if hosts := m.Hosts(k); len(hosts) > 0 {
+			f.Keys[fmt.Sprintf("%X", k)] = hosts
+		}
+	}
+	return f
+}
+
+// Read decodes a File written by Write
+func Read(r io.Reader) (*File, error) {
+	f := &File{}
+	if err := json.NewDecoder(r).Decode(f); err != nil {
+		return nil, fmt.Errorf("keymap: %w", err)
+	}
+	return f, nil
+}
+
+// ReadFile reads the File at path
+func ReadFile(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Write encodes f as JSON
+func (f *File) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(f)
+}
+
+// WriteFile writes f to path, creating its directory
+func (f *File) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	w, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Write(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// GlobalPath returns the path of the bindings file used for every rom,
+// keys.json in the gochip8 directory of the user's config directory
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gochip8", "keys.json"), nil
+}
+
+// ROMPath returns the path of the bindings file for a rom, the rom path
+// with its extension replaced by .keys.json
+func ROMPath(rom string) string {
+	return strings.TrimSuffix(rom, filepath.Ext(rom)) + ".keys.json"
+}
+
+// Load applies the bindings files at paths to the default bindings in
+// order, skipping those which don't exist
+func Load(paths ...string) (Map, error) {
+	m := Default()
+	for _, path := range paths {
+		f, err := ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if m, err = m.Apply(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,130 @@
+package keymap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+func TestPresets(t *testing.T) {
+	for _, name := range PresetNames() {
+		m := Presets[name]
+		for k := chip8.Key0; k <= chip8.KeyF; k++ {
+			if len(m.Hosts(k)) != 1 {
+				t.Errorf("%s: expected one host key for %X, actually %v", name, k, m.Hosts(k))
+			}
+		}
+	}
+
+	// The grid follows the keypad's layout
+	m := Default()
+	for host, k := range map[string]chip8.Key{"scancode:1": chip8.Key1, "scancode:4": chip8.KeyC, "scancode:X": chip8.Key0, "scancode:V": chip8.KeyF} {
+		if m[host] != k {
+			t.Errorf("expected %s bound to %X, actually %X", host, k, m[host])
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	f, err := Read(strings.NewReader(`{
+		"presets": ["qwerty", "numpad"],
+		"keys": {"5": ["scancode:W", "Space"], "a": []}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Map{"Q": chip8.Key0}.Apply(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["Q"]; ok {
+		t.Error("expected presets to replace the bindings applied to")
+	}
+	if hosts := m.Hosts(chip8.Key5); !reflect.DeepEqual(hosts, []string{"Space", "scancode:W"}) {
+		t.Errorf("expected 5 bound to Space and scancode:W, actually %v", hosts)
+	}
+	if hosts := m.Hosts(chip8.KeyA); len(hosts) != 0 {
+		t.Errorf("expected A unbound, actually %v", hosts)
+	}
+	if m["Keypad 7"] != chip8.Key7 || m["scancode:A"] != chip8.Key7 {
+		t.Error("expected both presets' bindings of 7")
+	}
+
+	// Without presets, only the listed keys change
+	m, err = Default().Apply(&File{Keys: map[string][]string{"F": {"Return"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 16 || m["Return"] != chip8.KeyF || m["scancode:Q"] != chip8.Key4 {
+		t.Errorf("expected F rebound to Return, actually %v", m)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	for _, test := range []struct {
+		f   File
+		err string
+	}{
+		{File{Presets: []string{"dvorak"}}, `keymap: unknown preset "dvorak", expected one of numpad, qwerty, vip`},
+		{File{Keys: map[string][]string{"10": {"Q"}}}, `keymap: invalid Chip 8 key "10", expected 0 to F`},
+		{File{Keys: map[string][]string{"G": {"Q"}}}, `keymap: invalid Chip 8 key "G", expected 0 to F`},
+		{File{Keys: map[string][]string{"1": {""}}}, `keymap: empty host key bound to 1`},
+	} {
+		if _, err := Default().Apply(&test.f); err == nil || err.Error() != test.err {
+			t.Errorf("expected error %q, actually %v", test.err, err)
+		}
+	}
+}
+
+func TestWithGameKeys(t *testing.T) {
+	m := Map{"Up": chip8.Key1}.WithGameKeys(map[string]chip8.Key{"up": chip8.Key2, "left": chip8.Key4, "player2Up": chip8.KeyC})
+	expected := Map{"Up": chip8.Key1, "Left": chip8.Key4}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v, actually %v", expected, m)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "keys.json")
+	rom := ROMPath(filepath.Join(dir, "game.ch8"))
+	if rom != filepath.Join(dir, "game.keys.json") {
+		t.Errorf("unexpected rom bindings path %s", rom)
+	}
+
+	// The rom's file applies on top of the global one
+	if err := (&File{Presets: []string{"vip"}}).WriteFile(global); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&File{Keys: map[string][]string{"0": {"Space"}}}).WriteFile(rom); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(global, rom, filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["A"] != chip8.KeyA || m["Space"] != chip8.Key0 || len(m) != 16 {
+		t.Errorf("expected vip with 0 on Space, actually %v", m)
+	}
+
+	// Saving every binding reads back the same
+	if err := m.File().WriteFile(rom); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := Load(rom); err != nil || !reflect.DeepEqual(loaded, m) {
+		t.Errorf("expected %v, actually %v %v", m, loaded, err)
+	}
+
+	if err := os.WriteFile(global, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(global); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
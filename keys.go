@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/keymap"
+)
+
+// loadKeys sets the key bindings in opts and the file remapping saves them
+// to. -keys selects a preset, which isn't saved, or a bindings file,
+// otherwise the global bindings file is applied then the rom's. Remapping
+// saves to the rom's file if it exists, else the global one
+func loadKeys(romFile string, opts *frontend.Options) {
+	if preset, ok := keymap.Presets[*keysName]; ok {
+		opts.Keys = preset.Clone()
+		return
+	}
+
+	var paths []string
+	if len(*keysName) > 0 {
+		paths = []string{*keysName}
+		opts.KeysFile = *keysName
+	} else {
+		if global, err := keymap.GlobalPath(); err == nil {
+			paths = append(paths, global)
+			opts.KeysFile = global
+		}
+		rom := keymap.ROMPath(romFile)
+		paths = append(paths, rom)
+		if _, err := os.Stat(rom); err == nil {
+			opts.KeysFile = rom
+		}
+	}
+
+	keys, err := keymap.Load(paths...)
+	if err != nil {
+		fmt.Println("error reading key bindings:", err.Error())
+		os.Exit(1)
+	}
+	opts.Keys = keys
+}
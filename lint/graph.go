@@ -0,0 +1,228 @@
+package lint
+
+import (
+	"sort"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// node is an instruction reachable from the start of the rom
+type node struct {
+	address uint16
+	op      uint16
+	size    int
+	long    uint16 // address loaded by F000 long loads
+	invalid bool   // the opcode doesn't decode
+
+	// Successors within the subroutine, a call continues at the return site
+	next []uint16
+
+	// Jump and call targets, which may be outside the rom
+	targets []uint16
+}
+
+func (n *node) isCall() bool {
+	return n.op>>12 == 0x2
+}
+
+func (n *node) isReturn() bool {
+	return n.op == 0x00EE
+}
+
+// function is a subroutine, or the main program from 0x200
+type function struct {
+	entry   uint16
+	body    map[uint16]bool // addresses of its instructions, without those of callees
+	returns []uint16        // addresses of RETs in the body
+	calls   []uint16        // addresses of CALLs in the body
+}
+
+// graph is the control flow graph of a rom
+type graph struct {
+	rom       []byte
+	end       int // address after the rom
+	nodes     map[uint16]*node
+	addresses []uint16 // of nodes, in order
+	functions map[uint16]*function
+
+	// Return sites of the calls to each function whose body contains a RET,
+	// by the address of the RET
+	returnSites map[uint16][]uint16
+
+	// Paths which run past the end of the rom, by the instruction they leave from
+	pastEnd map[uint16]bool
+}
+
+// newGraph follows every path from 0x200 through jumps, calls and skips
+func newGraph(rom []byte) *graph {
+	g := &graph{
+		rom:         rom,
+		end:         chip8.ProgramStartAddress + len(rom),
+		nodes:       make(map[uint16]*node),
+		functions:   make(map[uint16]*function),
+		returnSites: make(map[uint16][]uint16),
+		pastEnd:     make(map[uint16]bool),
+	}
+
+	pending := []uint16{chip8.ProgramStartAddress}
+	for len(pending) > 0 {
+		a := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, ok := g.nodes[a]; ok || !g.inROM(a) {
+			continue
+		}
+
+		n := g.decode(a)
+		if n == nil {
+			continue
+		}
+		g.nodes[a] = n
+		g.addresses = append(g.addresses, a)
+		pending = append(pending, n.next...)
+		for _, t := range n.targets {
+			if n.isCall() {
+				pending = append(pending, t)
+			}
+		}
+	}
+	sort.Slice(g.addresses, func(i, j int) bool { return g.addresses[i] < g.addresses[j] })
+
+	g.addFunction(chip8.ProgramStartAddress)
+	for _, a := range g.addresses {
+		if n := g.nodes[a]; n.isCall() && g.inROM(n.targets[0]) {
+			g.addFunction(n.targets[0])
+		}
+	}
+
+	for _, f := range g.functions {
+		for _, c := range g.callers(f.entry) {
+			for _, r := range f.returns {
+				g.returnSites[r] = append(g.returnSites[r], c+2)
+			}
+		}
+	}
+	return g
+}
+
+// inROM returns true if the instruction at a starts within the rom
+func (g *graph) inROM(a uint16) bool {
+	return int(a) >= chip8.ProgramStartAddress && int(a) < g.end
+}
+
+// opcode returns the opcode at a, false if it's past the end of the rom
+func (g *graph) opcode(a int) (uint16, bool) {
+	i := a - chip8.ProgramStartAddress
+	if i < 0 || i+1 >= len(g.rom) {
+		return 0, false
+	}
+	return chip8.GetOpcode(g.rom[i], g.rom[i+1]), true
+}
+
+// decode returns the node of the instruction at a, recording it in pastEnd
+// if it doesn't fit in the rom
+func (g *graph) decode(a uint16) *node {
+	op, ok := g.opcode(int(a))
+	if !ok || int(a)+disassembler.Size(op) > g.end {
+		g.pastEnd[a] = true
+		return nil
+	}
+
+	n := &node{address: a, op: op, size: disassembler.Size(op)}
+	if chip8.DecodeOpcode(op).Opcode == 0xFFFF {
+		n.invalid = true
+		return n
+	}
+
+	next := a + uint16(n.size)
+	nnn := op & 0xFFF
+	switch {
+	case op == 0x00EE, op == 0x00FD:
+		// Returns and exits end the path
+	case op>>12 == 0x1:
+		n.targets = []uint16{nnn}
+		n.next = []uint16{nnn}
+	case op>>12 == 0xB:
+		// The target depends on a register, follow the start of the jump table
+		n.targets = []uint16{nnn}
+		n.next = []uint16{nnn}
+	case op>>12 == 0x2:
+		n.targets = []uint16{nnn}
+		n.next = []uint16{next}
+	case op == 0xF000:
+		n.long, _ = g.opcode(int(a) + 2)
+		n.next = []uint16{next}
+	case disassembler.IsSkip(op):
+		// The skipped instruction may be a long load
+		skipped := uint16(2)
+		if following, ok := g.opcode(int(next)); ok {
+			skipped = uint16(disassembler.Size(following))
+		}
+		n.next = []uint16{next, next + skipped}
+	default:
+		n.next = []uint16{next}
+	}
+
+	// Paths which leave the rom are recorded by the instruction they leave
+	for _, a := range n.next {
+		if int(a) >= g.end && op>>12 != 0x1 && op>>12 != 0xB {
+			g.pastEnd[n.address] = true
+		}
+	}
+	return n
+}
+
+// addFunction records the body of the function at entry
+func (g *graph) addFunction(entry uint16) {
+	if _, ok := g.functions[entry]; ok {
+		return
+	}
+	f := &function{entry: entry, body: make(map[uint16]bool)}
+	g.functions[entry] = f
+
+	pending := []uint16{entry}
+	for len(pending) > 0 {
+		a := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		n, ok := g.nodes[a]
+		if !ok || f.body[a] {
+			continue
+		}
+		f.body[a] = true
+		switch {
+		case n.isReturn():
+			f.returns = append(f.returns, a)
+		case n.isCall():
+			f.calls = append(f.calls, a)
+		}
+		pending = append(pending, n.next...)
+	}
+	sort.Slice(f.returns, func(i, j int) bool { return f.returns[i] < f.returns[j] })
+	sort.Slice(f.calls, func(i, j int) bool { return f.calls[i] < f.calls[j] })
+}
+
+// callers returns the addresses of the calls to entry, in order
+func (g *graph) callers(entry uint16) []uint16 {
+	var calls []uint16
+	for _, a := range g.addresses {
+		if n := g.nodes[a]; n.isCall() && n.targets[0] == entry {
+			calls = append(calls, a)
+		}
+	}
+	return calls
+}
+
+// successors returns the instructions which can run after the node at a,
+// across calls and returns
+func (g *graph) successors(n *node) []uint16 {
+	switch {
+	case n.isCall():
+		if _, ok := g.nodes[n.targets[0]]; ok {
+			return n.targets
+		}
+		return n.next
+	case n.isReturn():
+		return g.returnSites[n.address]
+	}
+	return n.next
+}
@@ -0,0 +1,386 @@
+// Package lint finds likely bugs in roms by following every path from 0x200
+// through a control flow graph, tracking which registers have been written
+// and the values of registers and I where every path agrees on them
+package lint
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// Kind is the kind of problem a Warning reports
+type Kind string
+
+const (
+	// A register is read before any path to the instruction writes it
+	UnwrittenRegister Kind = "unwritten-register"
+
+	// A RET without a CALL, or a subroutine which is left without returning
+	UnbalancedCall Kind = "unbalanced-call"
+
+	// Calls nest deeper than chip8.StackSize, or recurse
+	CallDepth Kind = "call-depth"
+
+	// A jump or call to data, the middle of an instruction or outside the rom
+	BadJump Kind = "bad-jump"
+
+	// A jump or call between odd and even addresses
+	OddAddress Kind = "odd-address"
+
+	// An opcode which doesn't decode, or execution running past the rom
+	InvalidInstruction Kind = "invalid-instruction"
+
+	// A store to the bytes of an instruction
+	SelfModifying Kind = "self-modifying"
+
+	// A sprite or register load or store past the end of memory
+	PastMemory Kind = "past-memory"
+
+	// An instruction which behaves differently depending on quirks
+	Quirk Kind = "quirk"
+)
+
+// Warning is a problem found at an instruction
+type Warning struct {
+	Address uint16 `json:"address"`
+	Kind    Kind   `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Report is the result of linting a rom
+type Report struct {
+	Warnings []Warning // in order of address
+
+	// Deepest nesting of calls, -1 if calls recurse
+	MaxCallDepth int
+
+	// Name of the quirks preset the rom likely expects, empty if it doesn't
+	// depend on quirks, and why
+	Quirks        string
+	QuirksReasons []string
+}
+
+// Bugs returns the number of warnings which aren't about quirks
+func (r *Report) Bugs() int {
+	bugs := 0
+	for _, w := range r.Warnings {
+		if w.Kind != Quirk {
+			bugs++
+		}
+	}
+	return bugs
+}
+
+// WriteText writes a line per warning, followed by the call depth and quirks
+func (r *Report) WriteText(w io.Writer) error {
+	for _, warning := range r.Warnings {
+		if _, err := fmt.Fprintf(w, "%#04x  %-19s  %s\n", warning.Address, warning.Kind, warning.Message); err != nil {
+			return err
+		}
+	}
+
+	depth := fmt.Sprint(r.MaxCallDepth)
+	if r.MaxCallDepth < 0 {
+		depth = "unbounded, calls recurse"
+	}
+	if _, err := fmt.Fprintln(w, "max call depth:", depth); err != nil {
+		return err
+	}
+
+	quirks := "any, no quirk-sensitive instructions are used"
+	if r.Quirks != "" {
+		quirks = fmt.Sprintf("%s (-quirks %s), %s", r.Quirks, r.Quirks, strings.Join(r.QuirksReasons, "; "))
+	}
+	_, err := fmt.Fprintln(w, "likely quirks:", quirks)
+	return err
+}
+
+// linter holds the analysis of a rom while checks add warnings
+type linter struct {
+	g      *graph
+	states map[uint16]state
+	report *Report
+
+	// Bytes of instructions, by the address of the instruction
+	code map[uint16]uint16
+
+	// Bytes read or written through I, by the address of the instruction
+	data map[int]uint16
+
+	// Stores through I, to check for self-modifying code once all data is known
+	stores []access
+}
+
+// access is a read or write of memory through I
+type access struct {
+	address uint16 // of the instruction
+	start   int
+	size    int
+}
+
+// Lint checks rom for likely bugs, and which quirks it expects
+func Lint(rom []byte) *Report {
+	l := &linter{
+		g:      newGraph(rom),
+		report: &Report{},
+		code:   make(map[uint16]uint16),
+		data:   make(map[int]uint16),
+	}
+	if len(l.g.nodes) == 0 {
+		l.warn(chip8.ProgramStartAddress, InvalidInstruction, "the rom has no instructions")
+		return l.report
+	}
+	l.states = propagate(l.g)
+
+	for _, a := range l.g.addresses {
+		n := l.g.nodes[a]
+		for i := 0; i < n.size; i++ {
+			// Instructions decoded within another one belong to it
+			if _, ok := l.code[a+uint16(i)]; !ok {
+				l.code[a+uint16(i)] = a
+			}
+		}
+	}
+
+	for _, a := range l.g.addresses {
+		n := l.g.nodes[a]
+		s, reached := l.states[a]
+		if n.invalid {
+			l.warn(a, InvalidInstruction, fmt.Sprintf("%04X isn't an instruction", n.op))
+			continue
+		}
+		if reached {
+			l.checkReads(n, &s)
+			l.checkMemory(n, &s)
+		}
+	}
+	for a := range l.g.pastEnd {
+		l.warn(a, InvalidInstruction, "execution continues past the end of the rom")
+	}
+
+	// Jumps are checked once everything read as data is known
+	for _, a := range l.g.addresses {
+		l.checkTargets(l.g.nodes[a])
+	}
+	l.checkSelfModifying()
+	l.checkCalls()
+	l.inferQuirks()
+
+	sort.SliceStable(l.report.Warnings, func(i, j int) bool {
+		return l.report.Warnings[i].Address < l.report.Warnings[j].Address
+	})
+	return l.report
+}
+
+func (l *linter) warn(address uint16, kind Kind, message string) {
+	l.report.Warnings = append(l.report.Warnings, Warning{address, kind, message})
+}
+
+// registerNames lists the registers of bits, e.g. V0, V3
+func registerNames(bits uint16) string {
+	var names []string
+	for r := 0; r < 16; r++ {
+		if bits&(1<<r) != 0 {
+			names = append(names, fmt.Sprintf("V%X", r))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkReads warns about reads of registers which haven't been written
+func (l *linter) checkReads(n *node, s *state) {
+	op := n.op
+	x, y := op>>8&0xF, op>>4&0xF
+	bit := func(r uint16) uint16 { return 1 << r }
+
+	// Registers read, and registers of which either one is read depending on quirks
+	var reads, either uint16
+	switch op >> 12 {
+	case 0x3, 0x4, 0x7:
+		reads = bit(x)
+	case 0x5:
+		switch op & 0xF {
+		case 0x0:
+			reads = bit(x) | bit(y)
+		case 0x2:
+			reads = registerRange(x, y)
+		}
+	case 0x9, 0xD:
+		reads = bit(x) | bit(y)
+	case 0x8:
+		switch op & 0xF {
+		case 0x0:
+			reads = bit(y)
+		case 0x6, 0xE:
+			either = bit(x) | bit(y)
+		default:
+			reads = bit(x) | bit(y)
+		}
+	case 0xB:
+		either = bit(0) | bit(x)
+	case 0xE:
+		reads = bit(x)
+	case 0xF:
+		switch op & 0xFF {
+		case 0x15, 0x18, 0x1E, 0x29, 0x30, 0x33, 0x3A:
+			reads = bit(x)
+		case 0x55, 0x75:
+			reads = registerRange(0, x)
+		}
+	}
+
+	if either&^s.unwritten == 0 {
+		reads |= either
+	}
+	if unwritten := reads & s.unwritten; unwritten != 0 {
+		verb := "has"
+		if unwritten&(unwritten-1) != 0 {
+			verb = "have"
+		}
+		l.warn(n.address, UnwrittenRegister, fmt.Sprintf("%s reads %s, which %s never been written", disassembler.Mnemonic(op), registerNames(unwritten), verb))
+	}
+}
+
+// checkMemory records the data accessed through I when it's known, warning
+// about accesses past the end of memory
+func (l *linter) checkMemory(n *node, s *state) {
+	op := n.op
+	x, y := op>>8&0xF, op>>4&0xF
+
+	size, store := 0, false
+	switch {
+	case op>>12 == 0xD:
+		size = int(op & 0xF)
+		if size == 0 {
+			size = 32
+		}
+	case op>>12 == 0x5 && (op&0xF == 0x2 || op&0xF == 0x3):
+		size = int(x) - int(y)
+		if size < 0 {
+			size = -size
+		}
+		size++
+		store = op&0xF == 0x2
+	case op>>12 == 0xF && op&0xFF == 0x65:
+		size = int(x) + 1
+	case op>>12 == 0xF && op&0xFF == 0x55:
+		size, store = int(x)+1, true
+	case op>>12 == 0xF && op&0xFF == 0x33:
+		size, store = 3, true
+	}
+	if size == 0 || !s.i.known {
+		return
+	}
+
+	if s.i.n+size > chip8.MemorySize {
+		l.warn(n.address, PastMemory, fmt.Sprintf("%s accesses %d bytes from I = %#04x, past the end of memory", disassembler.Mnemonic(op), size, s.i.n))
+		size = chip8.MemorySize - s.i.n
+	}
+	for a := s.i.n; a < s.i.n+size; a++ {
+		if _, ok := l.data[a]; !ok {
+			l.data[a] = n.address
+		}
+	}
+	if store {
+		l.stores = append(l.stores, access{n.address, s.i.n, size})
+	}
+}
+
+// checkTargets warns about jumps and calls outside the rom, to odd addresses,
+// to data and to the middle of instructions
+func (l *linter) checkTargets(n *node) {
+	verb := "jumps"
+	if n.isCall() {
+		verb = "calls"
+	}
+
+	for _, t := range n.targets {
+		switch {
+		case !l.g.inROM(t):
+			l.warn(n.address, BadJump, fmt.Sprintf("%s %#04x, outside the rom", verb, t))
+			continue
+		case t&1 != n.address&1:
+			// Some roms run entirely at odd addresses, only changes are suspicious
+			l.warn(n.address, OddAddress, fmt.Sprintf("%s %#04x, changing the alignment of instructions", verb, t))
+		}
+
+		if owner := l.code[t]; owner != t {
+			l.warn(n.address, BadJump, fmt.Sprintf("%s %#04x, the middle of the instruction at %#04x", verb, t, owner))
+		} else if reader, ok := l.data[int(t)]; ok {
+			l.warn(n.address, BadJump, fmt.Sprintf("%s %#04x, data used by the instruction at %#04x", verb, t, reader))
+		}
+	}
+}
+
+// checkSelfModifying warns about stores to the bytes of instructions
+func (l *linter) checkSelfModifying() {
+	for _, store := range l.stores {
+		for a := store.start; a < store.start+store.size; a++ {
+			if owner, ok := l.code[uint16(a)]; ok {
+				l.warn(store.address, SelfModifying, fmt.Sprintf("%s stores to the instruction at %#04x", disassembler.Mnemonic(l.g.nodes[store.address].op), owner))
+				break
+			}
+		}
+	}
+}
+
+// checkCalls warns about returns from the main program, recursion, which
+// includes subroutines jumping back to their callers, and calls nested too deep
+func (l *linter) checkCalls() {
+	main := l.g.functions[chip8.ProgramStartAddress]
+	for _, r := range main.returns {
+		l.warn(r, UnbalancedCall, "RET from the main program, with nothing on the stack")
+	}
+
+	// Depth of each function's deepest chain of calls, -1 if it recurses
+	depths := make(map[uint16]int)
+	active := make(map[uint16]bool)
+	var depth func(f *function) int
+	depth = func(f *function) int {
+		if d, ok := depths[f.entry]; ok {
+			return d
+		}
+		active[f.entry] = true
+
+		deepest := 0
+		for _, c := range f.calls {
+			callee, ok := l.g.functions[l.g.nodes[c].targets[0]]
+			switch {
+			case !ok:
+				continue
+			case active[callee.entry] && len(callee.returns) == 0:
+				l.warn(c, UnbalancedCall, fmt.Sprintf("CALL %#04x, which never returns and reaches this call again, filling the stack", callee.entry))
+				deepest = -1
+			case active[callee.entry]:
+				l.warn(c, CallDepth, fmt.Sprintf("CALL %#04x, which can reach this call again before returning, the stack overflows unless that's bounded", callee.entry))
+				deepest = -1
+			default:
+				if d := depth(callee); d < 0 || deepest < 0 {
+					deepest = -1
+				} else if d+1 > deepest {
+					deepest = d + 1
+				}
+			}
+		}
+
+		active[f.entry] = false
+		depths[f.entry] = deepest
+		return deepest
+	}
+
+	l.report.MaxCallDepth = depth(main)
+	if l.report.MaxCallDepth > chip8.StackSize {
+		// Report the first call of the deepest chain
+		for _, c := range main.calls {
+			if callee := l.g.functions[l.g.nodes[c].targets[0]]; callee != nil && depths[callee.entry]+1 == l.report.MaxCallDepth {
+				l.warn(c, CallDepth, fmt.Sprintf("calls nest %d deep, more than the %d the stack holds", l.report.MaxCallDepth, chip8.StackSize))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/assembler"
+)
+
+// lint assembles src and lints it
+func lint(t *testing.T, src string) *Report {
+	p, err := assembler.Assemble("test.asm", func(string) ([]byte, error) {
+		return []byte(src), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Lint(p.ROM)
+}
+
+// expectWarnings checks the kinds and addresses of r's warnings
+func expectWarnings(t *testing.T, r *Report, expected ...Warning) {
+	t.Helper()
+	if len(r.Warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, actually %+v", len(expected), r.Warnings)
+	}
+	for i, w := range r.Warnings {
+		if w.Address != expected[i].Address || w.Kind != expected[i].Kind {
+			t.Errorf("expected %s at %#04x, actually %+v", expected[i].Kind, expected[i].Address, w)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	r := lint(t, `
+        LD V0, 5
+        LD V1, 0
+loop:   LD I, sprite
+        DRW V0, V1, 1
+        CALL wait
+        ADD V1, 1
+        JP loop
+wait:   LD DT, V0
+        RET
+sprite: DB 0x80`)
+
+	expectWarnings(t, r)
+	if r.MaxCallDepth != 1 || r.Quirks != "" {
+		t.Errorf("expected depth 1 and no quirks, actually %d %q", r.MaxCallDepth, r.Quirks)
+	}
+}
+
+func TestUnwrittenRegister(t *testing.T) {
+	r := lint(t, `
+        LD V0, 1
+        SE V1, 0        ; V1 is read before any write
+        LD V2, 1
+        ADD V0, V2
+        DRW V0, V3, 1   ; so is V3
+        JP $`)
+
+	expectWarnings(t, r,
+		Warning{0x202, UnwrittenRegister, ""},
+		Warning{0x208, UnwrittenRegister, ""},
+	)
+	if msg := r.Warnings[1].Message; msg != "DRW V0, V3, 1 reads V3, which has never been written" {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+// A register written on only one path to a read isn't reported
+func TestUnwrittenOnSomePaths(t *testing.T) {
+	r := lint(t, `
+        RND V0, 1
+        SE V0, 0
+        LD V1, 2
+        ADD V1, 1
+        JP $`)
+
+	expectWarnings(t, r)
+}
+
+func TestReturnFromMain(t *testing.T) {
+	r := lint(t, `
+        LD V0, 0
+        RET             ; nothing to return to`)
+
+	expectWarnings(t, r, Warning{0x202, UnbalancedCall, ""})
+}
+
+func TestNeverReturns(t *testing.T) {
+	r := lint(t, `
+loop:   CALL sub
+        JP loop
+sub:    JP loop         ; leaves the return address on the stack`)
+
+	expectWarnings(t, r, Warning{0x200, UnbalancedCall, ""})
+	if r.MaxCallDepth != -1 {
+		t.Errorf("expected unbounded depth, actually %d", r.MaxCallDepth)
+	}
+}
+
+func TestCallDepth(t *testing.T) {
+	// 17 subroutines each calling the next
+	src := "CALL s0\nJP $\n"
+	for i := 0; i < 16; i++ {
+		src += fmt.Sprintf("s%d: CALL s%d\nRET\n", i, i+1)
+	}
+	src += "s16: RET\n"
+
+	r := lint(t, src)
+	expectWarnings(t, r, Warning{0x200, CallDepth, ""})
+	if r.MaxCallDepth != 17 {
+		t.Errorf("expected depth 17, actually %d", r.MaxCallDepth)
+	}
+}
+
+func TestJumps(t *testing.T) {
+	r := lint(t, `
+        LD I, data
+        LD V0, 0
+        DRW V0, V0, 2
+        SE V0, 0
+        JP data         ; into data read by DRW
+        SE V0, 1
+        JP far + 2      ; into the middle of LD I, LONG
+        SE V0, 2
+        JP 0x0F00       ; outside the rom
+far:    LD I, LONG 0x1200
+        JP $
+data:   DB 0xFF, 0xFF`)
+
+	expectWarnings(t, r,
+		Warning{0x208, BadJump, ""},
+		Warning{0x20C, BadJump, ""},
+		Warning{0x210, BadJump, ""},
+		Warning{0x218, InvalidInstruction, ""},
+	)
+}
+
+func TestOddAddress(t *testing.T) {
+	r := lint(t, `
+        JP odd + 1
+odd:    DB 0x00
+        DW 0x1203       ; jumps between odd addresses are fine`)
+
+	expectWarnings(t, r, Warning{0x200, OddAddress, ""})
+}
+
+func TestInvalidInstruction(t *testing.T) {
+	r := lint(t, `
+        LD V0, 0
+        DW 0x5001       ; not an instruction`)
+
+	expectWarnings(t, r, Warning{0x202, InvalidInstruction, ""})
+}
+
+func TestSelfModifying(t *testing.T) {
+	r := lint(t, `
+        LD I, patch
+        LD V0, 0x61
+        LD [I], V0
+patch:  LD V1, 0
+        JP $`)
+
+	expectWarnings(t, r, Warning{0x204, SelfModifying, ""})
+}
+
+func TestPastMemory(t *testing.T) {
+	r := lint(t, `
+        LD I, LONG 0xFFFE
+        LD V0, 0
+        DRW V0, V0, 5
+        JP $`)
+
+	expectWarnings(t, r, Warning{0x206, PastMemory, ""})
+}
+
+func TestQuirks(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		src      string
+		quirks   string
+		warnings int
+	}{
+		{"shift in place", "LD V1, 3\nSHR V1, V2\nJP $", "chip48", 1},
+		{"shift vy", "LD V2, 3\nSHR V1, V2\nJP $", "vip", 1},
+		{"shift either", "LD V1, 3\nLD V2, 3\nSHR V1, V2\nJP $", "vip", 1},
+		{"shift same register", "LD V1, 3\nSHR V1, V1\nJP $", "", 0},
+		{"increment", "LD I, 0x300\nLD V0, 1\nLD [I], V0\nLD [I], V0\nJP $", "vip", 1},
+		{"no increment", "LD I, 0x300\nLD V0, [I]\nADD V0, 1\nLD [I], V0\nJP $", "chip48", 1},
+		{"increment by x", "LD I, 0x300\nLD V1, 1\nLD [I], V1\nLD [I], V1\nLD V2, 2\nJP V0, t\nt: JP $", "chip48", 2},
+		{"I reloaded", "LD I, 0x300\nLD V0, 1\nLD [I], V0\nLD I, 0x300\nLD [I], V0\nJP $", "", 0},
+		{"jump v0", "LD V0, 2\nJP V0, t\nt: JP $", "vip", 1},
+		{"jump vx", "LD V2, 2\nJP V0, t\nt: JP $", "chip48", 1},
+		{"schip", "HIGH\nJP $", "schip", 0},
+		{"xochip", "HIGH\nPLANE 1\nJP $", "xochip", 0},
+	} {
+		r := lint(t, test.src)
+		quirks := 0
+		for _, w := range r.Warnings {
+			if w.Kind == Quirk {
+				quirks++
+			}
+		}
+		if r.Quirks != test.quirks || quirks != test.warnings {
+			t.Errorf("%s: expected %q with %d warnings, actually %q with %+v", test.name, test.quirks, test.warnings, r.Quirks, r.Warnings)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	r := lint(t, "LD V2, 3\nSHR V1, V2\nJP $")
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `0x0202  quirk                SHR V1, V2 shifts V2 with -quirks vip or xochip, V1 in place with chip48 or schip
+max call depth: 0
+likely quirks: vip (-quirks vip), SHR V1, V2 at 0x0202 shifts V2, V1 is never written
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\nactually\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,181 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// vote is evidence of the behaviour a rom expects of a quirk
+type vote struct {
+	quirk  func(chip8.Quirks) bool
+	want   bool
+	reason string
+}
+
+// Presets considered for roms using only the original instructions, in order
+// of preference when nothing else decides
+var chip8Presets = []string{"vip", "chip48"}
+
+// extension returns the preset whose interpreter introduced op, empty for the
+// original instructions
+func extension(op uint16) string {
+	switch {
+	case op == 0xF000, op&0xF0FF == 0xF001, op == 0xF002, op&0xF0FF == 0xF03A,
+		op>>12 == 0x5 && (op&0xF == 0x2 || op&0xF == 0x3), op&0xFFF0 == 0x00D0:
+		return "xochip"
+	case op&0xFFF0 == 0x00C0, op >= 0x00FB && op <= 0x00FF, op>>12 == 0xD && op&0xF == 0,
+		op&0xF0FF == 0xF030, op&0xF0FF == 0xF075, op&0xF0FF == 0xF085:
+		return "schip"
+	}
+	return ""
+}
+
+// presetsWith lists the presets whose quirk is want, e.g. vip or xochip
+func presetsWith(quirk func(chip8.Quirks) bool, want bool) string {
+	var names []string
+	for _, name := range chip8.QuirksPresetNames() {
+		if quirk(chip8.QuirksPresets[name]) == want {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// inferQuirks flags instructions which depend on quirks and picks the preset
+// the rom most likely expects from them and the instructions it uses
+func (l *linter) inferQuirks() {
+	shiftVy := func(q chip8.Quirks) bool { return q.ShiftVy }
+	// Fx55/Fx65 move I past the last register, or to it as CHIP-48 does,
+	// which leaves I unchanged for V0
+	incrementI := func(x uint16) func(chip8.Quirks) bool {
+		return func(q chip8.Quirks) bool { return q.IncrementI || (q.IncrementIByX && x > 0) }
+	}
+	jumpVx := func(q chip8.Quirks) bool { return q.JumpVx }
+
+	platform, platformReason := "", ""
+	sensitive := false
+	var votes []vote
+
+	for _, a := range l.g.addresses {
+		n := l.g.nodes[a]
+		s, reached := l.states[a]
+		if n.invalid || !reached {
+			continue
+		}
+		op := n.op
+		x, y := op>>8&0xF, op>>4&0xF
+		mnemonic := disassembler.Mnemonic(op)
+
+		if ext := extension(op); ext != "" && platform != "xochip" && ext != platform {
+			platform = ext
+			platformReason = fmt.Sprintf("%s at %#04x is a %s instruction", mnemonic, a, ext)
+		}
+
+		switch {
+		case op>>12 == 0x8 && (op&0xF == 0x6 || op&0xF == 0xE) && x != y:
+			sensitive = true
+			l.warn(a, Quirk, fmt.Sprintf("%s shifts V%X with -quirks %s, V%X in place with %s",
+				mnemonic, y, presetsWith(shiftVy, true), x, presetsWith(shiftVy, false)))
+			switch {
+			case s.written(x) && !s.written(y):
+				votes = append(votes, vote{shiftVy, false, fmt.Sprintf("%s at %#04x shifts V%X in place, V%X is never written", mnemonic, a, x, y)})
+			case !s.written(x) && s.written(y):
+				votes = append(votes, vote{shiftVy, true, fmt.Sprintf("%s at %#04x shifts V%X, V%X is never written", mnemonic, a, y, x)})
+			}
+
+		case op>>12 == 0xF && (op&0xFF == 0x55 || op&0xFF == 0x65):
+			use, ok := l.nextUseOfI(n)
+			if !ok {
+				continue
+			}
+			sensitive = true
+			useOp := l.g.nodes[use].op
+			movesI := incrementI(x)
+			l.warn(a, Quirk, fmt.Sprintf("%s increments I with -quirks %s, and I is used by %s at %#04x",
+				mnemonic, presetsWith(movesI, true), disassembler.Mnemonic(useOp), use))
+			// Loads followed by loads, or stores by stores, step through memory.
+			// A load followed by a store modifies what it loaded
+			if useOp>>12 == 0xF && useOp&0xFF == op&0xFF {
+				votes = append(votes, vote{movesI, true, fmt.Sprintf("%s at %#04x is followed by %s, stepping through memory", mnemonic, a, disassembler.Mnemonic(useOp))})
+			} else if useOp>>12 == 0xF && (useOp&0xFF == 0x55 || useOp&0xFF == 0x65) {
+				votes = append(votes, vote{movesI, false, fmt.Sprintf("%s at %#04x is followed by %s, to the same memory", mnemonic, a, disassembler.Mnemonic(useOp))})
+			}
+
+		case op>>12 == 0xB && x != 0:
+			sensitive = true
+			nnn := op & 0xFFF
+			l.warn(a, Quirk, fmt.Sprintf("%s jumps to %#04x + V0, or + V%X with -quirks %s",
+				mnemonic, nnn, x, presetsWith(jumpVx, true)))
+			switch {
+			case s.written(0) && !s.written(x):
+				votes = append(votes, vote{jumpVx, false, fmt.Sprintf("%s at %#04x jumps from V0, V%X is never written", mnemonic, a, x)})
+			case !s.written(0) && s.written(x):
+				votes = append(votes, vote{jumpVx, true, fmt.Sprintf("%s at %#04x jumps from V%X, V0 is never written", mnemonic, a, x)})
+			}
+		}
+	}
+
+	candidates := chip8Presets
+	if platform != "" {
+		candidates = []string{platform}
+	} else if !sensitive {
+		return
+	}
+
+	// The candidate agreeing with the most votes, the first on a tie
+	best, bestScore := "", -1
+	for _, name := range candidates {
+		score := 0
+		for _, v := range votes {
+			if v.quirk(chip8.QuirksPresets[name]) == v.want {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+
+	l.report.Quirks = best
+	if platformReason != "" {
+		l.report.QuirksReasons = append(l.report.QuirksReasons, platformReason)
+	}
+	for _, v := range votes {
+		if v.quirk(chip8.QuirksPresets[best]) == v.want {
+			l.report.QuirksReasons = append(l.report.QuirksReasons, v.reason)
+		}
+	}
+	if len(l.report.QuirksReasons) == 0 {
+		l.report.QuirksReasons = []string{"nothing shows which behaviour quirk-sensitive instructions expect, assuming the original interpreter"}
+	}
+}
+
+// nextUseOfI returns the first instruction after n which uses I before
+// another instruction sets it, false if there's none
+func (l *linter) nextUseOfI(n *node) (uint16, bool) {
+	seen := make(map[uint16]bool)
+	pending := l.g.successors(n)
+	for len(pending) > 0 {
+		a := pending[0]
+		pending = pending[1:]
+		next, ok := l.g.nodes[a]
+		if !ok || seen[a] || next.invalid {
+			continue
+		}
+		seen[a] = true
+
+		op := next.op
+		switch {
+		case op>>12 == 0xA, op == 0xF000, op>>12 == 0xF && (op&0xFF == 0x29 || op&0xFF == 0x30):
+			continue
+		case op>>12 == 0xD, op>>12 == 0x5 && (op&0xF == 0x2 || op&0xF == 0x3),
+			op>>12 == 0xF && (op&0xFF == 0x1E || op&0xFF == 0x33 || op&0xFF == 0x55 || op&0xFF == 0x65):
+			return a, true
+		}
+		pending = append(pending, l.g.successors(next)...)
+	}
+	return 0, false
+}
@@ -0,0 +1,176 @@
+package lint
+
+import "github.com/pmcatominey/gochip8/chip8"
+
+// value is a register or I, which is known when every path sets it the same
+type value struct {
+	known bool
+	n     int
+}
+
+func known(n int) value {
+	return value{true, n}
+}
+
+func (v value) join(other value) value {
+	if v.known && other.known && v.n == other.n {
+		return v
+	}
+	return value{}
+}
+
+// state is what's known of the registers before an instruction runs
+type state struct {
+	unwritten uint16 // bit per V register which no path has written
+	v         [16]value
+	i         value
+}
+
+// initialState is the state at 0x200, registers are zero but unwritten
+func initialState() state {
+	s := state{unwritten: 0xFFFF}
+	for r := range s.v {
+		s.v[r] = known(0)
+	}
+	return s
+}
+
+func (s state) join(other state) state {
+	s.unwritten &= other.unwritten
+	for r := range s.v {
+		s.v[r] = s.v[r].join(other.v[r])
+	}
+	s.i = s.i.join(other.i)
+	return s
+}
+
+// written returns true if a path to the instruction has written Vr
+func (s *state) written(r uint16) bool {
+	return s.unwritten&(1<<r) == 0
+}
+
+// set records a write of Vr
+func (s *state) set(r uint16, v value) {
+	s.unwritten &^= 1 << r
+	s.v[r] = v
+}
+
+// registerRange returns the bits of Vx to Vy, in either order
+func registerRange(x, y uint16) uint16 {
+	if x > y {
+		x, y = y, x
+	}
+	return (0xFFFF >> (15 - y)) &^ (1<<x - 1)
+}
+
+// step returns the state after the instruction n runs
+func step(n *node, s state) state {
+	op := n.op
+	x, y := op>>8&0xF, op>>4&0xF
+	kk := int(op & 0xFF)
+
+	switch op >> 12 {
+	case 0x5:
+		if op&0xF == 0x3 {
+			for r := uint16(0); r < 16; r++ {
+				if registerRange(x, y)&(1<<r) != 0 {
+					s.set(r, value{})
+				}
+			}
+		}
+	case 0x6:
+		s.set(x, known(kk))
+	case 0x7:
+		v := s.v[x]
+		if v.known {
+			v.n = (v.n + kk) & 0xFF
+		}
+		s.set(x, v)
+	case 0x8:
+		vx, vy := s.v[x], s.v[y]
+		switch op & 0xF {
+		case 0x0:
+			s.set(x, vy)
+		case 0x1, 0x2, 0x3:
+			v := value{}
+			if vx.known && vy.known {
+				v = known(map[uint16]int{1: vx.n | vy.n, 2: vx.n & vy.n, 3: vx.n ^ vy.n}[op&0xF])
+			}
+			// VF may be reset, depending on quirks
+			s.v[0xF] = value{}
+			s.set(x, v)
+		default:
+			s.set(x, value{})
+			s.set(0xF, value{})
+		}
+	case 0xA:
+		s.i = known(int(op & 0xFFF))
+	case 0xC:
+		s.set(x, value{})
+	case 0xD:
+		s.set(0xF, value{})
+	case 0xF:
+		switch op & 0xFF {
+		case 0x00:
+			if op == 0xF000 {
+				s.i = known(int(n.long))
+			}
+		case 0x07, 0x0A:
+			s.set(x, value{})
+		case 0x1E:
+			if s.i.known && s.v[x].known {
+				s.i = known((s.i.n + s.v[x].n) % chip8.MemorySize)
+			} else {
+				s.i = value{}
+			}
+		case 0x29, 0x30:
+			s.i = value{}
+		case 0x55:
+			// I may be incremented, depending on quirks
+			s.i = value{}
+		case 0x65, 0x85:
+			for r := uint16(0); r <= x; r++ {
+				s.set(r, value{})
+			}
+			if op&0xFF == 0x65 {
+				s.i = value{}
+			}
+		}
+	}
+	return s
+}
+
+// propagate returns the state before each instruction reachable from 0x200,
+// joining the states of every path to it
+func propagate(g *graph) map[uint16]state {
+	states := map[uint16]state{chip8.ProgramStartAddress: initialState()}
+	pending := []uint16{chip8.ProgramStartAddress}
+	queued := map[uint16]bool{chip8.ProgramStartAddress: true}
+
+	for len(pending) > 0 {
+		a := pending[0]
+		pending = pending[1:]
+		queued[a] = false
+
+		n := g.nodes[a]
+		after := step(n, states[a])
+		for _, next := range g.successors(n) {
+			if _, ok := g.nodes[next]; !ok {
+				continue
+			}
+			s, seen := states[next]
+			joined := after
+			if seen {
+				joined = s.join(after)
+			}
+			if !seen || joined != s {
+				states[next] = joined
+				if !queued[next] {
+					queued[next] = true
+					pending = append(pending, next)
+				}
+			}
+		}
+	}
+	return states
+}
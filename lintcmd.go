@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pmcatominey/gochip8/lint"
+)
+
+// runLint checks a rom for likely bugs: gochip8 lint game.ch8, exiting with
+// status 1 if any are found
+func runLint(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Parse(args)
+
+	romFile := flags.Arg(0)
+	if len(romFile) == 0 {
+		fmt.Println("no rom file specified")
+		os.Exit(1)
+	}
+
+	program, _, err := readProgram(romFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	report := lint.Lint(program)
+	if err := report.WriteText(os.Stdout); err != nil {
+		fmt.Println("error writing report:", err.Error())
+		os.Exit(1)
+	}
+	if report.Bugs() > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,29 +1,31 @@
 package main
 
-// typedef unsigned char Uint8;
-// void AudioCallback(void *userdata, Uint8 *stream, int len);
-import "C"
-
 import (
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"math"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime"
+	"strings"
 	"time"
-	"unsafe"
 
 	"github.com/pmcatominey/gochip8/chip8"
-	"github.com/veandco/go-sdl2/sdl"
-)
-
-const (
-	toneHz   = 440
-	sampleHz = 4800
-	dPhase   = 2 * math.Pi * toneHz / sampleHz
+	"github.com/pmcatominey/gochip8/coverage"
+	"github.com/pmcatominey/gochip8/dap"
+	"github.com/pmcatominey/gochip8/debugger"
+	"github.com/pmcatominey/gochip8/disassembler"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/gdbstub"
+	"github.com/pmcatominey/gochip8/keymap"
+	"github.com/pmcatominey/gochip8/octo"
+	"github.com/pmcatominey/gochip8/profile"
+	"github.com/pmcatominey/gochip8/symbols"
+	"github.com/pmcatominey/gochip8/trace"
 )
 
 // Config
@@ -31,42 +33,91 @@ var (
 	// If this flag is present, the rom shold be loaded then output to stdout line by line with
 	// an explanation of each opcode
 	disassemble = flag.Bool("disassemble", false, "disassemble to stdout")
+	syntaxName  = flag.String("syntax", "cowgod", "assembly syntax of -disassemble, cowgod or octo")
+	jsonOutput  = flag.Bool("json", false, "write -disassemble output as JSON")
 
-	// Scale factor for upscaling the display from Chip 8 resolution of 64*32
-	scaleFactor = flag.Int("scaling", 10, "scale factor to multiply Chip 8 resolution (64*32) by")
+	// Run with an interactive debugger reading commands from stdin
+	debugMode = flag.Bool("debug", false, "debug the rom with commands from stdin, type help for a list")
+
+	// Serve the GDB remote serial protocol
+	gdbAddress = flag.String("gdb", "", "serve GDB clients on a TCP address such as localhost:1234, or unix:/path/to/socket")
+
+	// Serve the Debug Adapter Protocol for editors, the rom is given by the launch request
+	dapMode = flag.Bool("dap", false, "serve the Debug Adapter Protocol on stdin and stdout, the rom is given by the launch request")
 
 	// Controls execution speed, useful since some roms play at mad speeds compared to others
 	cyclesPerLoop = flag.Int("cycles", 10, "steps to emulate per loop")
 
-	// Default key bindings
-	defaultKeyBindings = map[sdl.Keycode]chip8.Key{
-		sdl.K_1: chip8.Key1, sdl.K_2: chip8.Key2, sdl.K_3: chip8.Key3, sdl.K_4: chip8.KeyC, // 1 2 3 4
-		sdl.K_q: chip8.Key4, sdl.K_w: chip8.Key5, sdl.K_e: chip8.Key6, sdl.K_r: chip8.KeyD, // Q W E R
-		sdl.K_a: chip8.Key7, sdl.K_s: chip8.Key8, sdl.K_d: chip8.Key9, sdl.K_f: chip8.KeyE, // A S D F
-		sdl.K_z: chip8.KeyA, sdl.K_x: chip8.Key0, sdl.K_c: chip8.KeyB, sdl.K_v: chip8.KeyF, // Z X C V
-	}
+	// Charge each instruction its COSMAC VIP cost instead of running a fixed number per loop
+	vipTiming = flag.Bool("timing", false, "emulate COSMAC VIP instruction timing, overrides -cycles")
 
-	keyBindings = defaultKeyBindings
-)
+	// Interpreter behaviours to emulate for ambiguous instructions, empty for gochip8 defaults
+	quirksName = flag.String("quirks", "", "quirks preset to emulate ("+strings.Join(chip8.QuirksPresetNames(), ", ")+")")
 
-// State
-var (
-	c8 *chip8.Chip8
+	// Random number generation for Cxkk, a fixed seed makes runs reproducible
+	seed           = flag.Int64("seed", 0, "seed for random numbers, defaults to the current time")
+	randSource     = flag.String("rand", "seeded", "random number generator, seeded or vip (COSMAC VIP routine)")
+	vipInterpreter = flag.String("vip-interpreter", "", "dump of COSMAC VIP memory 0x000 to 0x1FF, used by -rand vip to reproduce the original numbers")
 
-	window   *sdl.Window
-	renderer *sdl.Renderer
+	// Record key presses to a replay file, or play them back from one
+	recordFile   = flag.String("record", "", "record input to replay file")
+	recordHashes = flag.Bool("record-hashes", true, "include per frame state hashes in recording to detect desyncs")
+	replayFile   = flag.String("replay", "", "play back input from replay file")
 
-	// Reuse pixel for drawing
-	pixel = &sdl.Rect{
-		W: int32(*scaleFactor),
-		H: int32(*scaleFactor),
-	}
+	// Write a line per instruction executed, between start and stop conditions
+	traceFile   = flag.String("trace", "", "write a trace of every instruction executed to a file")
+	traceStart  = flag.String("trace-start", "", "start tracing at any of pc=<hex>, frame=<n> or count=<n>, comma separated")
+	traceStop   = flag.String("trace-stop", "", "stop tracing at any of pc=<hex>, frame=<n> or count=<n>, comma separated")
+	traceBinary = flag.Bool("trace-binary", false, "write a compact binary trace instead of text")
 
-	exitChan = make(chan bool, 1) // true sent this channel to exit main loop
+	// Count executions and memory accesses, reported at exit with a heatmap
+	profileFile = flag.String("profile", "", "write a report of hot spots to a file at exit, and a heatmap png next to it")
+
+	// Record which bytes of the rom are code, data or untouched, accumulating across runs
+	coverageFiles = flag.String("coverage", "", "record coverage to a file merged with it and any other comma separated files, with a listing next to it")
+
+	// Size of the rewind buffer, in frames and megabytes
+	rewindFrames = flag.Int("rewind-frames", 600, "frames which can be rewound, 60 per second")
+	rewindMemory = flag.Int("rewind-memory", 32, "maximum memory used for rewinding in megabytes")
+
+	// Settings of known roms, applied unless flags override them
+	romDB = flag.String("romdb", "", "directory of a chip-8-database to look roms up in instead of the embedded one, or off")
+
+	// Host keys bound to the keypad
+	keysName = flag.String("keys", "", "key bindings, a preset ("+strings.Join(keymap.PresetNames(), ", ")+") or a file, defaults to the global and per rom files")
+
+	// Frontend used for display, sound and input
+	frontendName  = flag.String("frontend", "sdl", "frontend to run in ("+strings.Join(frontend.Names(), ", ")+")")
+	terminalChars = flag.String("terminal-chars", "half", "characters used by the terminal frontend, half (blocks) or braille")
+
+	// Scale factor for upscaling the display from Chip 8 resolution of 64*32
+	scaleFactor = flag.Int("scaling", 10, "scale factor to multiply Chip 8 resolution (64*32) by")
+
+	// Quit after a number of frames, for running headless
+	maxFrames = flag.Int("frames", 0, "quit after this many frames with the headless frontend, 0 to run until quit")
 )
 
 func main() {
-	flag.Parse()
+	if len(os.Args) > 1 && os.Args[1] == "asm" {
+		runAsm(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		runLint(os.Args[2:])
+		return
+	}
+
+	// gochip8 run game.8o is the same as gochip8 game.8o
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "run" {
+		args = args[1:]
+	}
+	flag.CommandLine.Parse(args)
+
+	if *dapMode {
+		runDAP(readQuirks(*quirksName))
+		return
+	}
 
 	romFile := flag.Arg(0)
 	if len(romFile) == 0 {
@@ -74,169 +125,446 @@ func main() {
 		os.Exit(1)
 	}
 
-	program := readProgram(romFile)
+	program, _, err := readProgram(romFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	quirks := readQuirks(*quirksName)
 	if *disassemble {
-		fmt.Println("Disassembling ROM to stdout")
-		disassembleROM(program)
+		if len(*coverageFiles) > 0 {
+			cov, _ := readCoverage(*coverageFiles, program)
+			cov.WriteListing(os.Stdout, program)
+		} else {
+			disassembleROM(program)
+		}
 	} else {
 		fmt.Println("Running ROM")
-		runROM(program)
+		runROM(romFile, program, quirks, nil)
 	}
 }
 
-func readProgram(filename string) []byte {
-	if program, err := ioutil.ReadFile(filename); err != nil {
-		fmt.Println("error reading from rom file:", err.Error())
+// readProgram reads a rom, or compiles it from Octo source with a .8o
+// extension, returning its symbol map if compiled
+func readProgram(filename string) ([]byte, *symbols.Map, error) {
+	if filepath.Ext(filename) == ".8o" {
+		program, err := octo.CompileFile(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		return program.ROM, program.Symbols, nil
+	}
+
+	program, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading from rom file: %w", err)
+	}
+	return program, nil, nil
+}
+
+func readQuirks(name string) chip8.Quirks {
+	if len(name) == 0 {
+		return chip8.Quirks{}
+	}
+
+	quirks, ok := chip8.QuirksPreset(name)
+	if !ok {
+		fmt.Println("unknown quirks preset:", name)
 		os.Exit(1)
-		return nil
-	} else {
-		return program
 	}
+	return quirks
 }
 
-func setupSDL() {
-	var (
-		w = *scaleFactor * chip8.DisplayWidth
-		h = *scaleFactor * chip8.DisplayHeight
+// runDAP runs the rom launched by a debug adapter client
+func runDAP(quirks chip8.Quirks) {
+	server := dap.NewServer(os.Stdin, os.Stdout)
 
-		romName = filepath.Base(flag.Arg(0))
-		err     error
-	)
+	// Protocol messages are written to stdout, everything else goes to stderr
+	os.Stdout = os.Stderr
 
-	sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO)
-	window, err = sdl.CreateWindow("gochip8 - "+romName, sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, w, h, 0)
+	args, err := server.Launch()
 	if err != nil {
-		panic(err)
+		fmt.Println("error waiting for launch:", err.Error())
+		os.Exit(1)
 	}
 
-	renderer, err = sdl.CreateRenderer(window, -1, 0)
+	program, syms, err := readProgram(args.Program)
 	if err != nil {
-		panic(err)
+		server.Fail(err)
+		os.Exit(1)
 	}
-
-	// audio spec
-	spec := &sdl.AudioSpec{
-		Freq:     sampleHz,
-		Format:   sdl.AUDIO_U8,
-		Channels: 2,
-		Samples:  sampleHz,
-		Callback: sdl.AudioCallback(C.AudioCallback),
+	if syms != nil {
+		// Source paths are as given to the compiler, relative to the working directory
+		dir, _ := os.Getwd()
+		server.SetSymbols(syms, dir)
 	}
-	sdl.OpenAudio(spec, nil)
-}
 
-func cleanUpSDL() {
-	renderer.Destroy()
-	window.Destroy()
-	sdl.Quit()
+	runROM(args.Program, program, quirks, server)
 }
 
-func runROM(rom []byte) {
-	c8 = chip8.New(rom)
+func runROM(romFile string, rom []byte, quirks chip8.Quirks, adapter *dap.Server) {
+	opts := frontend.Options{
+		Title:  "gochip8 - " + filepath.Base(romFile),
+		Scale:  *scaleFactor,
+		Chars:  *terminalChars,
+		Frames: *maxFrames,
+	}
+	loadKeys(romFile, &opts)
+	if entry := lookupROM(rom); entry != nil {
+		quirks = applyROMSettings(entry, quirks, &opts)
+	}
+
+	// Check flags before anything is created, nothing is written on failure
+	recording, replaying := len(*recordFile) > 0, len(*replayFile) > 0
+	if *debugMode && (recording || replaying || *frontendName == "terminal") {
+		fmt.Println("-debug can't be used with -record, -replay or the terminal frontend")
+		os.Exit(1)
+	}
+
+	if len(*gdbAddress) > 0 && (*debugMode || recording || replaying) {
+		fmt.Println("-gdb can't be used with -debug, -record or -replay")
+		os.Exit(1)
+	}
+
+	if adapter != nil && (*debugMode || len(*gdbAddress) > 0 || recording || replaying) {
+		adapter.Fail(errors.New("-dap can't be used with -debug, -gdb, -record or -replay"))
+		os.Exit(1)
+	}
+
+	s := &session{
+		romFile:  romFile,
+		rewinder: chip8.NewRewinder(*rewindFrames, *rewindMemory*1024*1024),
+	}
+
+	switch {
+	case replaying:
+		s.player = readReplay(*replayFile, rom)
+		s.c8 = s.player.Chip8()
+		s.runner = s.player
+	case recording:
+		s.recorder, s.c8 = chip8.NewRecorder(rom, randSeed(), quirks, *cyclesPerLoop, *recordHashes)
+		if *vipTiming {
+			s.recorder.UseVIPTiming()
+		}
+		if v, ok := newRandSource().(*chip8.VIPRand); ok {
+			s.recorder.UseVIPRand(s.c8, v.Page)
+		}
+	default:
+		s.c8 = chip8.New(rom, quirks)
+		s.c8.SetRand(newRandSource())
+	}
+
+	if s.runner == nil {
+		s.runner = newRunner(s.c8)
+	}
+
+	var (
+		cov         *coverage.Coverage
+		covFilename string
+	)
+	if len(*coverageFiles) > 0 {
+		cov, covFilename = readCoverage(*coverageFiles, rom)
+	}
 
 	// Lock goroutine to main thread
 	runtime.LockOSThread()
 
-	// Setup and ensure cleanup of SDL
-	setupSDL()
-	defer cleanUpSDL()
-
-	// Run main loop at 60Hz
-	c := time.Tick(time.Second / 60)
-	for _ = range c {
-		select {
-		case <-exitChan:
-			return
-		default:
+	// Setup and ensure cleanup of the frontend
+	var err error
+	s.fe, err = frontend.Open(*frontendName, opts)
+	if err != nil {
+		if adapter != nil {
+			adapter.Fail(fmt.Errorf("error opening frontend: %w", err))
 		}
+		fmt.Println("error opening frontend:", err.Error())
+		os.Exit(1)
+	}
+	defer s.fe.Close()
 
-		c8.UpdateTimers()
-		processInput()
+	if len(*gdbAddress) > 0 {
+		s.gdb = serveGDB(*gdbAddress, s.runner.(*chip8.Runner))
+		defer s.gdb.Close()
+	}
 
-		for i := 0; i < *cyclesPerLoop; i++ {
-			c8.Step()
+	if adapter != nil {
+		if err := adapter.Start(s.runner.(*chip8.Runner)); err != nil {
+			fmt.Println("error starting debug adapter:", err.Error())
+			os.Exit(1)
 		}
+		s.dap = adapter
+	}
 
-		// Sound
-		if c8.ShouldBuzz() {
-			sdl.PauseAudio(false)
-		} else {
-			sdl.PauseAudio(true)
-		}
+	// Output files are created last, the trace first as it can still fail
+	if len(*traceFile) > 0 {
+		var f *os.File
+		s.tracer, f = startTrace(*traceFile, s.c8)
+		defer closeTrace(s.tracer, f)
+	}
 
-		// Draw if needed
-		if c8.DrawFlag {
-			c8.DrawFlag = false
-			draw()
-		}
+	if s.recorder != nil {
+		defer writeReplay(*recordFile, s.recorder)
+	}
+
+	if len(*profileFile) > 0 {
+		s.profiler = profile.New(s.c8)
+		defer writeProfile(*profileFile, s.profiler)
+	}
+
+	if cov != nil {
+		recorder := coverage.NewRecorder(s.c8, cov)
+		defer writeCoverage(covFilename, cov, rom, recorder)
+	}
+
+	if *debugMode {
+		s.debugger = debugger.New(s.runner.(*chip8.Runner), os.Stdout)
+		s.commands = readLines(os.Stdin)
+	}
+
+	err = s.run(context.Background())
+	if err != nil {
+		fmt.Println("error running rom:", err.Error())
+	}
+	if s.dap != nil && !s.dap.Disconnected() {
+		s.dap.Exited(err)
 	}
 }
 
-func disassembleROM(rom []byte) {
-	for i := 0; i < len(rom); i += 2 {
-		op := chip8.GetOpcode(rom[i], rom[i+1])
-		inst := chip8.DecodeOpcode(op)
-
-		fmt.Printf("%#x ; %s\n", op, inst.Description)
-	}
-}
-
-// processInput polls and handles SDL events
-func processInput() {
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-		switch e := event.(type) {
-		case *sdl.QuitEvent:
-			exitChan <- true
-		case *sdl.KeyDownEvent:
-			if e.Keysym.Sym == sdl.K_ESCAPE {
-				exitChan <- true
-			} else {
-				k, ok := keyBindings[e.Keysym.Sym]
-				if ok {
-					c8.PressKey(k)
+// startTrace starts tracing the instructions executed by c to filename
+func startTrace(filename string, c *chip8.Chip8) (*trace.Tracer, *os.File) {
+	start, err := trace.ParseConditions(*traceStart)
+	if err == nil {
+		var stop []trace.Condition
+		if stop, err = trace.ParseConditions(*traceStop); err == nil {
+			var f *os.File
+			if f, err = os.Create(filename); err == nil {
+				format := trace.Text
+				if *traceBinary {
+					format = trace.Binary
 				}
+
+				t := trace.New(c, f, format)
+				t.Start, t.Stop = start, stop
+				return t, f
 			}
-		case *sdl.KeyUpEvent:
-			k, ok := keyBindings[e.Keysym.Sym]
-			if ok {
-				c8.DePressKey(k)
-			}
 		}
 	}
+
+	fmt.Println("error starting trace:", err.Error())
+	os.Exit(1)
+	return nil, nil
+}
+
+// closeTrace completes the trace file f written by t
+func closeTrace(t *trace.Tracer, f *os.File) {
+	err := t.Close()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		fmt.Println("error writing trace file:", err.Error())
+	}
+}
+
+// writeProfile writes the report of p to filename, and its heatmap to a png
+// with the same name
+func writeProfile(filename string, p *profile.Profiler) {
+	p.Close()
+
+	err := createFile(filename, func(f *os.File) error {
+		return p.WriteReport(f, 30)
+	})
+	if err == nil {
+		heatmap := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".png"
+		err = createFile(heatmap, func(f *os.File) error {
+			return p.WriteHeatmap(f)
+		})
+	}
+	if err != nil {
+		fmt.Println("error writing profile:", err.Error())
+	}
+}
+
+// readCoverage returns the coverage of rom merged from a comma separated
+// list of files and the first file, which is written at exit and doesn't
+// need to exist yet
+func readCoverage(files string, rom []byte) (*coverage.Coverage, string) {
+	filenames := strings.Split(files, ",")
+	cov := coverage.New(rom)
+	for i, filename := range filenames {
+		other, err := coverage.ReadFile(filename)
+		if i == 0 && os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			err = cov.Merge(other)
+		}
+		if err != nil {
+			fmt.Println("error reading coverage file", filename+":", err.Error())
+			os.Exit(1)
+		}
+	}
+	return cov, filenames[0]
+}
+
+// writeCoverage stops recording cov and writes it to filename, with an
+// annotated listing of rom next to it with a .lst extension
+func writeCoverage(filename string, cov *coverage.Coverage, rom []byte, recorder *coverage.Recorder) {
+	recorder.Close()
+
+	err := cov.WriteFile(filename)
+	if err == nil {
+		listing := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".lst"
+		err = createFile(listing, func(f *os.File) error {
+			return cov.WriteListing(f, rom)
+		})
+	}
+	if err != nil {
+		fmt.Println("error writing coverage:", err.Error())
+	}
+}
+
+// createFile creates filename and writes it with write
+func createFile(filename string, write func(f *os.File) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// serveGDB starts serving GDB clients on address
+func serveGDB(address string, runner *chip8.Runner) *gdbstub.Stub {
+	l, err := gdbstub.Listen(address)
+	if err != nil {
+		fmt.Println("error listening for gdb:", err.Error())
+		os.Exit(1)
+	}
+
+	stub := gdbstub.New(runner)
+	go func() {
+		if err := stub.Serve(l); err != nil {
+			fmt.Println("error serving gdb:", err.Error())
+		}
+	}()
+
+	fmt.Println("gdb listening on", l.Addr())
+	return stub
 }
 
-//export AudioCallback
-func AudioCallback(userdata unsafe.Pointer, stream *C.Uint8, length C.int) {
-	n := int(length)
-	hdr := reflect.SliceHeader{Data: uintptr(unsafe.Pointer(stream)), Len: n, Cap: n}
-	buf := *(*[]C.Uint8)(unsafe.Pointer(&hdr))
+// readLines sends each line read from r to the returned channel, which is
+// closed at the end of r
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	return lines
+}
 
-	var phase float64
-	for i := 0; i < n; i += 2 {
-		phase += dPhase
-		sample := C.Uint8((math.Sin(phase) + 0.999999) * 128)
-		buf[i] = sample
-		buf[i+1] = sample
+// newRunner creates a Runner for c following the timing flags
+func newRunner(c *chip8.Chip8) *chip8.Runner {
+	runner := chip8.NewRunner(c, *cyclesPerLoop)
+	if *vipTiming {
+		runner.VIPTiming = true
+		runner.CyclesPerFrame = chip8.VIPCyclesPerFrame
 	}
+	return runner
 }
 
-func draw() {
-	display := c8.Display()
-	renderer.SetDrawColor(0, 0, 0, 1)
-	renderer.Clear()
+// randSeed returns the seed flag if set, otherwise the current time
+func randSeed() int64 {
+	if flagSet("seed") {
+		return *seed
+	}
+	return time.Now().UnixNano()
+}
 
-	renderer.SetDrawColor(255, 255, 255, 1)
-	for y := 0; y < chip8.DisplayHeight; y++ {
-		for x := 0; x < chip8.DisplayWidth; x++ {
-			// Only draw if set to 1
-			if display[x][y] == 1 {
-				pixel.X = int32(*scaleFactor * x)
-				pixel.Y = int32(*scaleFactor * y)
-				renderer.FillRect(pixel)
+// newRandSource creates the random number generator selected by flags
+func newRandSource() chip8.RandSource {
+	switch *randSource {
+	case "seeded":
+		return chip8.NewSeededRand(randSeed())
+	case "vip":
+		r := chip8.NewVIPRand(byte(randSeed()))
+		if len(*vipInterpreter) > 0 {
+			dump, err := ioutil.ReadFile(*vipInterpreter)
+			if err == nil {
+				err = r.SetInterpreter(dump)
+			}
+			if err != nil {
+				fmt.Println("error reading VIP interpreter:", err)
+				os.Exit(1)
 			}
+		}
+		return r
+	}
+
+	fmt.Println("unknown random number generator:", *randSource)
+	os.Exit(1)
+	return nil
+}
+
+func readReplay(filename string, rom []byte) *chip8.Player {
+	f, err := os.Open(filename)
+	if err != nil {
+		fmt.Println("error reading replay file:", err.Error())
+		os.Exit(1)
+	}
+	defer f.Close()
 
+	replay, err := chip8.ReadReplay(f)
+	if err == nil {
+		var p *chip8.Player
+		if p, err = chip8.NewPlayer(replay, rom); err == nil {
+			return p
 		}
 	}
 
-	renderer.Present()
+	fmt.Println("error reading replay file:", err.Error())
+	os.Exit(1)
+	return nil
+}
+
+func writeReplay(filename string, recorder *chip8.Recorder) {
+	f, err := os.Create(filename)
+	if err == nil {
+		err = chip8.WriteReplay(f, recorder.Replay())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		fmt.Println("error writing replay file:", err.Error())
+	} else {
+		fmt.Println("recorded", recorder.Replay().Frames, "frames to", filename)
+	}
+}
+
+func disassembleROM(rom []byte) {
+	syntax, err := disassembler.ParseSyntax(*syntaxName)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	program := disassembler.Disassemble(rom, syntax)
+	if *jsonOutput {
+		err = program.WriteJSON(os.Stdout)
+	} else {
+		err = program.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Println("error disassembling rom:", err.Error())
+		os.Exit(1)
+	}
 }
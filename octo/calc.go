@@ -0,0 +1,134 @@
+package octo
+
+import (
+	"math"
+)
+
+// calculator evaluates :calc expressions. As in Octo, operators have no
+// precedence and evaluate from right to left, so 2 * 3 + 1 is 8
+type calculator struct {
+	c      *compiler
+	tokens []token
+	pos    int
+	end    token // the opening brace, for errors at the end
+}
+
+// Binary operators
+var calcBinary = map[string]func(a, b float64) float64{
+	"+":   func(a, b float64) float64 { return a + b },
+	"-":   func(a, b float64) float64 { return a - b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"%":   func(a, b float64) float64 { return math.Mod(a, b) },
+	"&":   func(a, b float64) float64 { return float64(int64(a) & int64(b)) },
+	"|":   func(a, b float64) float64 { return float64(int64(a) | int64(b)) },
+	"^":   func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) },
+	"<<":  func(a, b float64) float64 { return float64(int64(a) << uint(b)) },
+	">>":  func(a, b float64) float64 { return float64(int64(a) >> uint(b)) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"<":   func(a, b float64) float64 { return boolean(a < b) },
+	"<=":  func(a, b float64) float64 { return boolean(a <= b) },
+	">":   func(a, b float64) float64 { return boolean(a > b) },
+	">=":  func(a, b float64) float64 { return boolean(a >= b) },
+	"==":  func(a, b float64) float64 { return boolean(a == b) },
+	"!=":  func(a, b float64) float64 { return boolean(a != b) },
+}
+
+// Unary operators
+var calcUnary = map[string]func(a float64) float64{
+	"-":     func(a float64) float64 { return -a },
+	"~":     func(a float64) float64 { return float64(^int64(a)) },
+	"!":     func(a float64) float64 { return boolean(a == 0) },
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"sign": func(a float64) float64 {
+		switch {
+		case a > 0:
+			return 1
+		case a < 0:
+			return -1
+		}
+		return 0
+	},
+}
+
+// boolean converts b to 1 or 0
+func boolean(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// next returns the next token, failing at the end of the expression
+func (e *calculator) next() token {
+	if e.pos >= len(e.tokens) {
+		e.c.fail(e.end, "unexpected end of expression")
+	}
+	t := e.tokens[e.pos]
+	e.pos++
+	return t
+}
+
+// expression evaluates a term, and a binary operator and expression if any
+func (e *calculator) expression() float64 {
+	v := e.term()
+	if e.pos < len(e.tokens) {
+		if f, ok := calcBinary[e.tokens[e.pos].text]; ok {
+			e.pos++
+			return f(v, e.expression())
+		}
+	}
+	return v
+}
+
+// term evaluates a unary operator, parentheses or a value
+func (e *calculator) term() float64 {
+	t := e.next()
+	if f, ok := calcUnary[t.text]; ok {
+		return f(e.term())
+	}
+
+	switch t.text {
+	case "(":
+		v := e.expression()
+		if closing := e.next(); closing.text != ")" {
+			e.c.fail(closing, "expected ), found %q", closing.text)
+		}
+		return v
+	case "@":
+		// The byte compiled at an address
+		address := int(e.term())
+		if address < 0 || address >= len(e.c.rom) {
+			e.c.fail(t, "address %d is out of range", address)
+		}
+		return float64(e.c.rom[address])
+	case "HERE":
+		return float64(e.c.here)
+	case "PI":
+		return math.Pi
+	case "E":
+		return math.E
+	}
+
+	if v, ok := parseNumber(t.text); ok {
+		return float64(v)
+	}
+	if v, ok := e.c.consts[t.text]; ok {
+		return v
+	}
+	if v, ok := e.c.labels[t.text]; ok {
+		return float64(v)
+	}
+	e.c.fail(t, "undefined name %s", t.text)
+	return 0
+}
@@ -0,0 +1,880 @@
+// Package octo compiles programs written in Octo, the high level assembly
+// language of John Earnest's Octo, into roms.
+//
+// Statements are whitespace separated tokens, comments start with #:
+//
+//	: main                  # labels start with :
+//		v0 := 5             # registers are v0 to vf
+//		i := sprite
+//		loop
+//			sprite v0 v1 4
+//			v0 += 1
+//			if v0 == 30 then v0 := 0
+//		again
+//	: sprite 0x90 0x60 0x60 0x90
+//
+// Supported are labels, every instruction of Chip 8, SUPER-CHIP and XO-CHIP,
+// if ... then, if ... begin ... else ... end, loop ... while ... again,
+// :const, :alias, :org, :byte, :call, :macro and :calc. Comparisons other
+// than == and != use vf as a temporary. Execution starts at main, which a
+// jump at 0x200 leads to unless main is the first thing in the program.
+package octo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/assembler"
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/symbols"
+)
+
+// Program is a compiled program
+type Program struct {
+	ROM     []byte
+	Symbols *symbols.Map // labels, and the line of each instruction
+}
+
+// token is a whitespace separated word of source
+type token struct {
+	text string
+	line int
+	col  int
+}
+
+// macro is a sequence of tokens with parameters substituted when invoked
+type macro struct {
+	params []string
+	body   []token
+}
+
+// fixup is an address operand using a label which wasn't defined yet
+type fixup struct {
+	address int // of the instruction
+	label   token
+	long    bool // a 16 bit address following F000, rather than nnn
+}
+
+// block is an if ... begin or loop waiting for its end
+type block struct {
+	start  token
+	jump   int   // if: the address of the jump to patch at else or end
+	loop   int   // loop: the address to jump back to
+	breaks []int // loop: the jumps of while to patch at again
+}
+
+// compiler holds the state of compiling a program
+type compiler struct {
+	file   string
+	tokens []token
+	pos    int
+
+	rom  []byte
+	here int // address of the next byte
+	end  int // address after the last byte written
+
+	jumpMain bool // the first instruction is a jump to main
+	labels   map[string]int
+	consts   map[string]float64
+	aliases  map[string]int
+	macros   map[string]*macro
+	fixups   []fixup
+	blocks   []*block
+
+	lines    []symbols.Line
+	lineDone bool // the line of the current statement has been recorded
+	line     int  // line of the current statement
+}
+
+// bailout is panicked with the first error, and recovered by Compile
+type bailout struct {
+	err error
+}
+
+// Keywords which can't be names
+var keywords = map[string]bool{
+	":": true, ":=": true, "+=": true, "-=": true, "=-": true, "|=": true, "&=": true, "^=": true,
+	">>=": true, "<<=": true, "==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"key": true, "-key": true, "hex": true, "bighex": true, "random": true, "delay": true, "buzzer": true,
+	"pitch": true, "long": true, "if": true, "then": true, "begin": true, "else": true, "end": true,
+	"loop": true, "while": true, "again": true, "return": true, ";": true, "clear": true, "bcd": true,
+	"save": true, "load": true, "saveflags": true, "loadflags": true, "sprite": true, "jump": true,
+	"jump0": true, "native": true, "hires": true, "lores": true, "exit": true, "scroll-up": true,
+	"scroll-down": true, "scroll-left": true, "scroll-right": true, "plane": true, "audio": true,
+	"i": true, "{": true, "}": true,
+}
+
+// CompileFile compiles the Octo source file at path
+func CompileFile(path string) (*Program, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(path, src)
+}
+
+// Compile compiles Octo source, naming it path in errors and symbols.
+// Errors are returned as an *assembler.Error
+func Compile(path string, src []byte) (p *Program, err error) {
+	c := &compiler{
+		file:     path,
+		tokens:   tokenize(string(src)),
+		rom:      make([]byte, chip8.MemorySize),
+		here:     chip8.ProgramStartAddress + 2,
+		end:      chip8.ProgramStartAddress + 2,
+		jumpMain: true,
+		labels:   make(map[string]int),
+		consts:   make(map[string]float64),
+		aliases:  make(map[string]int),
+		macros:   make(map[string]*macro),
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b, ok := r.(bailout)
+			if !ok {
+				panic(r)
+			}
+			p, err = nil, b.err
+		}
+	}()
+
+	for c.pos < len(c.tokens) {
+		c.line, c.lineDone = c.tokens[c.pos].line, false
+		c.statement()
+	}
+	return c.finish(), nil
+}
+
+// tokenize splits source into tokens, removing comments
+func tokenize(src string) []token {
+	var tokens []token
+	for i, line := range strings.Split(src, "\n") {
+		for col := 0; col < len(line); {
+			if isSpace(line[col]) {
+				col++
+				continue
+			}
+			if line[col] == '#' {
+				break
+			}
+
+			start := col
+			for col < len(line) && !isSpace(line[col]) {
+				col++
+			}
+			tokens = append(tokens, token{line[start:col], i + 1, start + 1})
+		}
+	}
+	return tokens
+}
+
+// isSpace returns true for whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
+
+// fail stops compiling with an error at t
+func (c *compiler) fail(t token, format string, args ...interface{}) {
+	panic(bailout{&assembler.Error{File: c.file, Line: t.line, Column: t.col, Msg: fmt.Sprintf(format, args...)}})
+}
+
+// next returns the next token, failing at the end of the source
+func (c *compiler) next() token {
+	if c.pos >= len(c.tokens) {
+		t := token{line: 1, col: 1}
+		if len(c.tokens) > 0 {
+			last := c.tokens[len(c.tokens)-1]
+			t = token{line: last.line, col: last.col + len(last.text)}
+		}
+		c.fail(t, "unexpected end of file")
+	}
+	t := c.tokens[c.pos]
+	c.pos++
+	return t
+}
+
+// peek returns the text of the next token, or "" at the end
+func (c *compiler) peek() string {
+	if c.pos < len(c.tokens) {
+		return c.tokens[c.pos].text
+	}
+	return ""
+}
+
+// expect fails unless the next token is text
+func (c *compiler) expect(text string) token {
+	t := c.next()
+	if t.text != text {
+		c.fail(t, "expected %s, found %q", text, t.text)
+	}
+	return t
+}
+
+// emit writes bytes at here
+func (c *compiler) emit(data ...byte) {
+	for _, b := range data {
+		if c.here >= len(c.rom) {
+			c.fail(c.tokens[c.pos-1], "program is larger than memory")
+		}
+		c.rom[c.here] = b
+		c.here++
+	}
+	if c.here > c.end {
+		c.end = c.here
+	}
+}
+
+// inst writes an instruction, recording the line of the statement it's in
+func (c *compiler) inst(op uint16) {
+	if !c.lineDone {
+		c.lines = append(c.lines, symbols.Line{File: c.file, Line: c.line, Address: uint16(c.here)})
+		c.lineDone = true
+	}
+	c.emit(byte(op>>8), byte(op))
+}
+
+// finish resolves forward references and the jump to main
+func (c *compiler) finish() *Program {
+	for _, f := range c.fixups {
+		address, ok := c.labels[f.label.text]
+		if !ok {
+			c.fail(f.label, "undefined name %s", f.label.text)
+		}
+		if f.long {
+			c.rom[f.address+2], c.rom[f.address+3] = byte(address>>8), byte(address)
+			continue
+		}
+		if address > 0xFFF {
+			c.fail(f.label, "address %#x of %s is out of range of a 12 bit address", address, f.label.text)
+		}
+		c.rom[f.address] |= byte(address >> 8)
+		c.rom[f.address+1] = byte(address)
+	}
+
+	if len(c.blocks) > 0 {
+		b := c.blocks[len(c.blocks)-1]
+		c.fail(b.start, "%s without a matching %s", b.start.text, map[string]string{"if": "end", "loop": "again"}[b.start.text])
+	}
+
+	if c.jumpMain {
+		main, ok := c.labels["main"]
+		if !ok {
+			c.fail(token{line: 1, col: 1}, "the program has no main label")
+		}
+		c.rom[chip8.ProgramStartAddress] = byte(0x10 | main>>8)
+		c.rom[chip8.ProgramStartAddress+1] = byte(main)
+	}
+
+	p := &Program{
+		ROM:     append([]byte(nil), c.rom[chip8.ProgramStartAddress:c.end]...),
+		Symbols: symbols.New(),
+	}
+	for name, address := range c.labels {
+		p.Symbols.Labels[name] = uint16(address)
+	}
+	p.Symbols.Lines = c.lines
+	return p
+}
+
+// statement compiles a statement
+func (c *compiler) statement() {
+	t := c.next()
+
+	if op, ok := simpleStatements[t.text]; ok {
+		c.inst(op)
+		return
+	}
+	if kk, ok := registerStatements[t.text]; ok {
+		c.inst(0xF000 | c.register()<<8 | kk)
+		return
+	}
+
+	switch t.text {
+	case ":":
+		c.label()
+	case ":const":
+		name := c.newName()
+		c.consts[name.text] = c.constant()
+	case ":alias":
+		// Aliases can be moved to other registers
+		name := c.next()
+		if _, ok := c.aliases[name.text]; !ok {
+			c.pos--
+			name = c.newName()
+		}
+		c.aliases[name.text] = int(c.register())
+	case ":org":
+		c.here = int(c.value(0, 0xFFFF))
+	case ":byte":
+		if c.peek() == "{" {
+			c.emit(byte(int(c.calc())))
+		} else {
+			c.emit(byte(c.value(-0x80, 0xFF)))
+		}
+	case ":call":
+		c.addressInst(0x2000)
+	case ":macro":
+		c.defineMacro()
+	case ":calc":
+		name := c.newName()
+		c.consts[name.text] = c.calc()
+	case ":breakpoint":
+		c.next()
+	case ":monitor":
+		c.next()
+		c.next()
+
+	case "save", "load":
+		x := c.register()
+		if c.peek() == "-" {
+			c.next()
+			y := c.register()
+			op := uint16(0x5002)
+			if t.text == "load" {
+				op = 0x5003
+			}
+			c.inst(op | x<<8 | y<<4)
+			return
+		}
+		op := uint16(0xF055)
+		if t.text == "load" {
+			op = 0xF065
+		}
+		c.inst(op | x<<8)
+	case "sprite":
+		x, y := c.register(), c.register()
+		c.inst(0xD000 | x<<8 | y<<4 | c.value(0, 0xF))
+	case "scroll-down", "scroll-up":
+		op := uint16(0x00C0)
+		if t.text == "scroll-up" {
+			op = 0x00D0
+		}
+		c.inst(op | c.value(0, 0xF))
+	case "plane":
+		c.inst(0xF001 | c.value(0, 0xF)<<8)
+	case "jump":
+		c.addressInst(0x1000)
+	case "jump0":
+		c.addressInst(0xB000)
+	case "native":
+		c.addressInst(0x0000)
+	case "delay", "buzzer", "pitch":
+		c.expect(":=")
+		c.inst(0xF000 | c.register()<<8 | map[string]uint16{"delay": 0x15, "buzzer": 0x18, "pitch": 0x3A}[t.text])
+	case "i":
+		c.index()
+	case "if":
+		c.ifStatement(t)
+	case "else":
+		b := c.block(t, "if")
+		jump := c.here
+		c.inst(0x1000)
+		c.patch(b.jump)
+		b.jump = jump
+	case "end":
+		b := c.block(t, "if")
+		c.blocks = c.blocks[:len(c.blocks)-1]
+		c.patch(b.jump)
+	case "loop":
+		c.blocks = append(c.blocks, &block{start: t, loop: c.here})
+	case "while":
+		b := c.block(t, "loop")
+		c.conditional(true)
+		b.breaks = append(b.breaks, c.here)
+		c.inst(0x1000)
+	case "again":
+		b := c.block(t, "loop")
+		c.blocks = c.blocks[:len(c.blocks)-1]
+		c.inst(0x1000 | uint16(b.loop))
+		for _, jump := range b.breaks {
+			c.patch(jump)
+		}
+	default:
+		c.other(t)
+	}
+}
+
+// Statements without operands
+var simpleStatements = map[string]uint16{
+	"return":       0x00EE,
+	";":            0x00EE,
+	"clear":        0x00E0,
+	"hires":        0x00FF,
+	"lores":        0x00FE,
+	"exit":         0x00FD,
+	"scroll-left":  0x00FC,
+	"scroll-right": 0x00FB,
+	"audio":        0xF002,
+}
+
+// Statements with a register, Fxkk by kk
+var registerStatements = map[string]uint16{
+	"bcd":       0x33,
+	"saveflags": 0x75,
+	"loadflags": 0x85,
+}
+
+// other compiles registers, numbers, macros and calls
+func (c *compiler) other(t token) {
+	if x, ok := c.registerNumber(t.text); ok {
+		c.assignment(uint16(x))
+		return
+	}
+	if _, ok := parseNumber(t.text); ok {
+		c.pos--
+		c.emit(byte(c.value(-0x80, 0xFF)))
+		return
+	}
+	if m, ok := c.macros[t.text]; ok {
+		c.expand(t, m)
+		return
+	}
+	if _, ok := c.consts[t.text]; ok {
+		// A constant on its own is a byte of data
+		c.pos--
+		c.emit(byte(c.value(-0x80, 0xFF)))
+		return
+	}
+	if keywords[t.text] {
+		c.fail(t, "unexpected %q", t.text)
+	}
+
+	// Anything else is a call to a label, which may be defined later
+	c.pos--
+	c.addressInst(0x2000)
+}
+
+// label defines a label at here
+func (c *compiler) label() {
+	name := c.newName()
+	if name.text == "main" && c.here == chip8.ProgramStartAddress+2 && c.end == c.here {
+		// main is first, so no jump to it is needed
+		c.here, c.end = chip8.ProgramStartAddress, chip8.ProgramStartAddress
+		c.jumpMain = false
+	}
+	c.labels[name.text] = c.here
+}
+
+// newName reads a name which is about to be defined
+func (c *compiler) newName() token {
+	t := c.next()
+	switch {
+	case keywords[t.text] || strings.HasPrefix(t.text, ":"):
+		c.fail(t, "%s is reserved", t.text)
+	case c.isRegister(t.text):
+		c.fail(t, "%s is a register", t.text)
+	}
+	if _, ok := parseNumber(t.text); ok {
+		c.fail(t, "%s isn't a valid name", t.text)
+	}
+
+	_, label := c.labels[t.text]
+	_, constant := c.consts[t.text]
+	_, macro := c.macros[t.text]
+	if label || constant || macro {
+		c.fail(t, "%s is already defined", t.text)
+	}
+	return t
+}
+
+// registerNumber returns the number of v0 to vf or an alias
+func (c *compiler) registerNumber(s string) (int, bool) {
+	if x, ok := c.aliases[s]; ok {
+		return x, true
+	}
+	if len(s) == 2 && (s[0] == 'v' || s[0] == 'V') {
+		if x, err := strconv.ParseUint(s[1:], 16, 4); err == nil {
+			return int(x), true
+		}
+	}
+	return 0, false
+}
+
+// isRegister returns true for v0 to vf and aliases
+func (c *compiler) isRegister(s string) bool {
+	_, ok := c.registerNumber(s)
+	return ok
+}
+
+// register reads a register
+func (c *compiler) register() uint16 {
+	t := c.next()
+	x, ok := c.registerNumber(t.text)
+	if !ok {
+		c.fail(t, "expected a register, found %q", t.text)
+	}
+	return uint16(x)
+}
+
+// constant reads a number or constant for :const
+func (c *compiler) constant() float64 {
+	t := c.next()
+	if v, ok := parseNumber(t.text); ok {
+		return float64(v)
+	}
+	if v, ok := c.consts[t.text]; ok {
+		return v
+	}
+	c.fail(t, "expected a number or constant, found %q", t.text)
+	return 0
+}
+
+// value reads a number or constant between min and max, returning it
+// masked to the bits of max so negative bytes wrap
+func (c *compiler) value(min, max int) uint16 {
+	t := c.next()
+	v, ok := parseNumber(t.text)
+	if !ok {
+		f, isConst := c.consts[t.text]
+		address, isLabel := c.labels[t.text]
+		switch {
+		case isConst:
+			v = int(f)
+		case isLabel:
+			v = address
+		default:
+			c.fail(t, "expected a number or constant, found %q", t.text)
+		}
+	}
+	if v < min || v > max {
+		c.fail(t, "%d is out of range %d to %d", v, min, max)
+	}
+	return uint16(v) & uint16(max)
+}
+
+// parseNumber parses decimal, 0x hex and 0b binary numbers, optionally negative
+func parseNumber(s string) (int, bool) {
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(s, "-")
+
+	base := 10
+	switch {
+	case strings.HasPrefix(digits, "0x"), strings.HasPrefix(digits, "0X"):
+		base, digits = 16, digits[2:]
+	case strings.HasPrefix(digits, "0b"), strings.HasPrefix(digits, "0B"):
+		base, digits = 2, digits[2:]
+	}
+
+	v, err := strconv.ParseInt(digits, base, 32)
+	if err != nil || digits == "" {
+		return 0, false
+	}
+	if negative {
+		v = -v
+	}
+	return int(v), true
+}
+
+// addressInst writes an instruction with a 12 bit address operand, which
+// may be a label defined later
+func (c *compiler) addressInst(op uint16) {
+	t := c.next()
+	if address, ok := c.labels[t.text]; ok {
+		if address > 0xFFF {
+			c.fail(t, "address %#x of %s is out of range of a 12 bit address", address, t.text)
+		}
+		c.inst(op | uint16(address))
+		return
+	}
+
+	_, isNumber := parseNumber(t.text)
+	_, isConst := c.consts[t.text]
+	if isNumber || isConst {
+		c.pos--
+		c.inst(op | c.value(0, 0xFFF))
+		return
+	}
+
+	if keywords[t.text] || c.isRegister(t.text) || strings.HasPrefix(t.text, ":") {
+		c.fail(t, "expected an address, found %q", t.text)
+	}
+	c.fixups = append(c.fixups, fixup{address: c.here, label: t})
+	c.inst(op)
+}
+
+// patch sets the address of the jump at address to here
+func (c *compiler) patch(address int) {
+	c.rom[address] = byte(0x10 | c.here>>8&0xF)
+	c.rom[address+1] = byte(c.here)
+}
+
+// block returns the innermost block, which must have been started by start
+func (c *compiler) block(t token, start string) *block {
+	if len(c.blocks) == 0 || c.blocks[len(c.blocks)-1].start.text != start {
+		c.fail(t, "%s without a matching %s", t.text, start)
+	}
+	return c.blocks[len(c.blocks)-1]
+}
+
+// index compiles assignments to i
+func (c *compiler) index() {
+	t := c.next()
+	switch t.text {
+	case "+=":
+		c.inst(0xF01E | c.register()<<8)
+	case ":=":
+		switch c.peek() {
+		case "hex":
+			c.next()
+			c.inst(0xF029 | c.register()<<8)
+		case "bighex":
+			c.next()
+			c.inst(0xF030 | c.register()<<8)
+		case "long":
+			c.next()
+			label := c.next()
+			address, ok := c.labels[label.text]
+			if !ok {
+				if v, isNumber := parseNumber(label.text); isNumber {
+					address, ok = v, true
+				} else if f, isConst := c.consts[label.text]; isConst {
+					address, ok = int(f), true
+				}
+			}
+			if !ok {
+				c.fixups = append(c.fixups, fixup{address: c.here, label: label, long: true})
+			} else if address < 0 || address > 0xFFFF {
+				c.fail(label, "%d is out of range 0 to 65535", address)
+			}
+			c.inst(0xF000)
+			c.emit(byte(address>>8), byte(address))
+		default:
+			c.addressInst(0xA000)
+		}
+	default:
+		c.fail(t, "expected := or += after i, found %q", t.text)
+	}
+}
+
+// Register to register operators, 8xyn by n
+var aluOperators = map[string]uint16{
+	":=":  0x0,
+	"|=":  0x1,
+	"&=":  0x2,
+	"^=":  0x3,
+	"+=":  0x4,
+	"-=":  0x5,
+	">>=": 0x6,
+	"=-":  0x7,
+	"<<=": 0xE,
+}
+
+// assignment compiles statements starting with the register x
+func (c *compiler) assignment(x uint16) {
+	operator := c.next()
+	n, ok := aluOperators[operator.text]
+	if !ok {
+		c.fail(operator, "expected an assignment operator, found %q", operator.text)
+	}
+
+	// Register operands are the same for every operator
+	if y, ok := c.registerNumber(c.peek()); ok {
+		c.next()
+		c.inst(0x8000 | x<<8 | uint16(y)<<4 | n)
+		return
+	}
+
+	switch operator.text {
+	case ":=":
+		switch c.peek() {
+		case "key":
+			c.next()
+			c.inst(0xF00A | x<<8)
+		case "delay":
+			c.next()
+			c.inst(0xF007 | x<<8)
+		case "random":
+			c.next()
+			c.inst(0xC000 | x<<8 | c.value(-0x80, 0xFF))
+		default:
+			c.inst(0x6000 | x<<8 | c.value(-0x80, 0xFF))
+		}
+	case "+=":
+		c.inst(0x7000 | x<<8 | c.value(-0x80, 0xFF))
+	case "-=":
+		c.inst(0x7000 | x<<8 | -c.value(-0x80, 0xFF)&0xFF)
+	default:
+		c.fail(c.next(), "%s needs a register", operator.text)
+	}
+}
+
+// ifStatement compiles if ... then and if ... begin
+func (c *compiler) ifStatement(t token) {
+	// Look ahead to whether the condition ends with then or begin
+	i := c.pos
+	for i < len(c.tokens) && c.tokens[i].text != "then" && c.tokens[i].text != "begin" {
+		i++
+	}
+	if i == len(c.tokens) {
+		c.fail(t, "if without then or begin")
+	}
+
+	if c.tokens[i].text == "then" {
+		c.conditional(false)
+		c.expect("then")
+		c.statement()
+		return
+	}
+
+	c.conditional(true)
+	c.expect("begin")
+	c.blocks = append(c.blocks, &block{start: t, jump: c.here})
+	c.inst(0x1000)
+}
+
+// Negations of comparisons
+var negations = map[string]string{
+	"==": "!=", "!=": "==",
+	"<": ">=", ">=": "<",
+	">": "<=", "<=": ">",
+	"key": "-key", "-key": "key",
+}
+
+// conditional compiles a condition as instructions which skip the next
+// instruction when it's false, or when negated, when it's true
+func (c *compiler) conditional(negated bool) {
+	x := c.register()
+	operator := c.next()
+	op, ok := negations[operator.text]
+	if !ok {
+		c.fail(operator, "expected a comparison, found %q", operator.text)
+	}
+	if !negated {
+		op = operator.text
+	}
+
+	switch op {
+	case "key":
+		c.inst(0xE0A1 | x<<8)
+		return
+	case "-key":
+		c.inst(0xE09E | x<<8)
+		return
+	}
+
+	y, isRegister := c.registerNumber(c.peek())
+	if isRegister {
+		c.next()
+	}
+
+	switch op {
+	case "==":
+		if isRegister {
+			c.inst(0x9000 | x<<8 | uint16(y)<<4)
+		} else {
+			c.inst(0x4000 | x<<8 | c.value(-0x80, 0xFF))
+		}
+		return
+	case "!=":
+		if isRegister {
+			c.inst(0x5000 | x<<8 | uint16(y)<<4)
+		} else {
+			c.inst(0x3000 | x<<8 | c.value(-0x80, 0xFF))
+		}
+		return
+	}
+
+	// Other comparisons subtract in vf, whose flag is set when there's no borrow
+	if isRegister {
+		c.inst(0x8F00 | uint16(y)<<4)
+	} else {
+		c.inst(0x6F00 | c.value(-0x80, 0xFF))
+	}
+	switch op {
+	case ">": // vf = y - x sets the flag when y >= x, so skip then
+		c.inst(0x8F05 | x<<4)
+		c.inst(0x3F01)
+	case "<=":
+		c.inst(0x8F05 | x<<4)
+		c.inst(0x4F01)
+	case "<": // vf = x - y sets the flag when x >= y, so skip then
+		c.inst(0x8F07 | x<<4)
+		c.inst(0x3F01)
+	case ">=":
+		c.inst(0x8F07 | x<<4)
+		c.inst(0x4F01)
+	}
+}
+
+// defineMacro reads :macro name params { body }
+func (c *compiler) defineMacro() {
+	name := c.newName()
+	m := &macro{}
+	for {
+		t := c.next()
+		if t.text == "{" {
+			break
+		}
+		m.params = append(m.params, t.text)
+	}
+	m.body = c.braces(name)
+	c.macros[name.text] = m
+}
+
+// braces reads tokens up to the } matching a { which has been read
+func (c *compiler) braces(start token) []token {
+	var body []token
+	for depth := 1; ; {
+		if c.pos >= len(c.tokens) {
+			c.fail(start, "missing }")
+		}
+		t := c.next()
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+		if depth == 0 {
+			return body
+		}
+		body = append(body, t)
+	}
+}
+
+// expand replaces a macro invocation with its body, substituting arguments
+// for parameters. Expanded tokens have the position of the invocation
+func (c *compiler) expand(t token, m *macro) {
+	args := make(map[string]string, len(m.params))
+	for _, param := range m.params {
+		args[param] = c.next().text
+	}
+
+	expanded := make([]token, len(m.body))
+	for i, bt := range m.body {
+		text := bt.text
+		if arg, ok := args[text]; ok {
+			text = arg
+		}
+		expanded[i] = token{text, t.line, t.col}
+	}
+
+	if len(c.tokens) > 1<<20 {
+		c.fail(t, "macro %s expands forever", t.text)
+	}
+	tokens := make([]token, 0, len(c.tokens)+len(expanded))
+	tokens = append(tokens, c.tokens[:c.pos]...)
+	tokens = append(tokens, expanded...)
+	c.tokens = append(tokens, c.tokens[c.pos:]...)
+}
+
+// calc reads { expression } and evaluates it
+func (c *compiler) calc() float64 {
+	start := c.expect("{")
+	e := &calculator{c: c, tokens: c.braces(start), end: start}
+	if len(e.tokens) == 0 {
+		c.fail(start, "expected an expression")
+	}
+	v := e.expression()
+	if e.pos < len(e.tokens) {
+		c.fail(e.tokens[e.pos], "unexpected %q", e.tokens[e.pos].text)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		c.fail(start, "expression isn't a number")
+	}
+	return v
+}
@@ -0,0 +1,186 @@
+package octo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// Each testdata/*.8o compiles to the rom in the .ch8 file of the same name
+func TestCompile(t *testing.T) {
+	sources, err := filepath.Glob("testdata/*.8o")
+	if err != nil || len(sources) == 0 {
+		t.Fatal("no test programs", err)
+	}
+
+	for _, source := range sources {
+		p, err := CompileFile(source)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+
+		expected, err := ioutil.ReadFile(strings.TrimSuffix(source, ".8o") + ".ch8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p.ROM, expected) {
+			t.Errorf("%s: expected\n% X\nactually\n% X", source, expected, p.ROM)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	p, err := CompileFile("testdata/basics.8o")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, address := range map[string]uint16{"paddle": 0x202, "draw": 0x205, "main": 0x209} {
+		if p.Symbols.Labels[name] != address {
+			t.Errorf("expected %s at %#04x, actually %#04x", name, address, p.Symbols.Labels[name])
+		}
+	}
+
+	// The first instruction of main is on line 10
+	if line, ok := p.Symbols.Line(0x209); !ok || line.Line != 10 || line.File != "testdata/basics.8o" {
+		t.Errorf("expected main at line 10, actually %+v", line)
+	}
+}
+
+// Every game disassembled in Octo syntax compiles back to the same rom
+func TestDisassembledGames(t *testing.T) {
+	games, err := filepath.Glob("../games/*")
+	if err != nil || len(games) == 0 {
+		t.Fatal("no games", err)
+	}
+
+	for _, game := range games {
+		rom, err := ioutil.ReadFile(game)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		src := &bytes.Buffer{}
+		if err := disassembler.Disassemble(rom, disassembler.Octo).WriteText(src); err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := Compile(filepath.Base(game)+".8o", src.Bytes())
+		if err != nil {
+			t.Errorf("%s: %v", game, err)
+			continue
+		}
+		if !bytes.Equal(p.ROM, rom) {
+			t.Errorf("%s: disassembly compiled to a different rom", game)
+		}
+	}
+}
+
+// run compiles src and runs it until it reaches a jump to itself
+func run(t *testing.T, src string) *chip8.Chip8 {
+	p, err := Compile("test.8o", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := chip8.New(p.ROM, chip8.Quirks{})
+	for i := 0; i < 10000; i++ {
+		pc := c.Registers().PC
+		if data, _ := c.ReadMemory(pc, 2); data[0] == byte(0x10|pc>>8) && data[1] == byte(pc) {
+			return c
+		}
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatal("program didn't finish")
+	return nil
+}
+
+// Comparisons run the statement after them when they're true, for every
+// pair of values around the boundaries
+func TestComparisons(t *testing.T) {
+	values := []int{0, 1, 2, 254, 255}
+	for _, operator := range []string{"==", "!=", "<", ">", "<=", ">="} {
+		for _, a := range values {
+			for _, b := range values {
+				expected := map[string]bool{
+					"==": a == b, "!=": a != b, "<": a < b, ">": a > b, "<=": a <= b, ">=": a >= b,
+				}[operator]
+
+				src := strings.NewReplacer("OP", operator, "A", strconv.Itoa(a), "B", strconv.Itoa(b)).Replace(`
+: main
+	v0 := A
+	v1 := B
+	v2 := 0
+	v3 := 0
+	if v0 OP v1 then v2 := 1
+	if v0 OP B then v3 := 1
+	v4 := 0
+	if v0 OP v1 begin v4 := 1 else v4 := 2 end
+	loop again
+`)
+				regs := run(t, src).Registers()
+				want := byte(0)
+				if expected {
+					want = 1
+				}
+				if regs.V[2] != want || regs.V[3] != want || regs.V[4] != 2-want {
+					t.Errorf("%d %s %d: expected %v, actually v2=%d v3=%d v4=%d", a, operator, b, expected, regs.V[2], regs.V[3], regs.V[4])
+				}
+			}
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	regs := run(t, `
+: main
+	v0 := 0
+	v1 := 0
+	loop
+		v0 += 1
+		while v0 != 10
+		if v0 == 5 then v1 += 100
+		v1 += 1
+	again
+	loop again
+`).Registers()
+
+	// v1 counts 9 iterations, plus 100 once
+	if regs.V[0] != 10 || regs.V[1] != 109 {
+		t.Errorf("expected v0=10 v1=109, actually v0=%d v1=%d", regs.V[0], regs.V[1])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for src, expected := range map[string]string{
+		": main\n  v0 := vz":                     "test.8o:2:9: expected a number or constant, found \"vz\"",
+		": main\n  jump nowhere":                 "test.8o:2:8: undefined name nowhere",
+		"  v0 := 1":                              "test.8o:1:1: the program has no main label",
+		": main\n  v0 := 256":                    "test.8o:2:9: 256 is out of range -128 to 255",
+		": main\n  if v0 == 1 begin\n  v0 := 1":  "test.8o:2:3: if without a matching end",
+		": main\n  again":                        "test.8o:2:3: again without a matching loop",
+		": main\n: main":                         "test.8o:2:3: main is already defined",
+		": main\n  :calc x { 1 + }":              "test.8o:2:11: unexpected end of expression",
+		": main\n  :const v0 1":                  "test.8o:2:10: v0 is a register",
+		": main\n  sprite v0 v1":                 "test.8o:2:15: unexpected end of file",
+		": main\n  v0 ? v1":                      "test.8o:2:6: expected an assignment operator, found \"?\"",
+		": main\n  if v0 ~ v1 then v0 := 1":      "test.8o:2:9: expected a comparison, found \"~\"",
+		":macro m a {\n  a := 1\n: main\n  m v0": "test.8o:1:8: missing }",
+	} {
+		_, err := Compile("test.8o", []byte(src))
+		if err == nil {
+			t.Errorf("%q: expected error %q", src, expected)
+		} else if err.Error() != expected {
+			t.Errorf("%q: expected error %q, actually %q", src, expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package profile
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+const (
+	// Bytes of memory shown by the heatmap, the 4KB of the original Chip 8
+	heatmapMemory = 0x1000
+
+	// Addresses per row, and pixels per address
+	heatmapColumns  = 64
+	heatmapCellSize = 6
+
+	// Pixels around and between the panels
+	heatmapMargin = 8
+)
+
+var heatmapBackground = color.RGBA{0x40, 0x40, 0x40, 0xFF}
+
+// WriteHeatmap writes a PNG with panels for executions, reads and writes
+// from left to right. Each panel has a row of 64 addresses for each 64
+// bytes of the first 4KB of memory, coloured from black (never) through red
+// and yellow to white (most often) on a log scale. Executions colour both
+// bytes of an instruction
+func (p *Profiler) WriteHeatmap(w io.Writer) error {
+	executed := make([]uint64, heatmapMemory)
+	for a := 0; a < heatmapMemory; a++ {
+		if n := p.Executed[a]; n > 0 {
+			executed[a] += n
+			if a+1 < heatmapMemory {
+				executed[a+1] += n
+			}
+		}
+	}
+
+	panels := [][]uint64{executed, p.Read[:heatmapMemory], p.Written[:heatmapMemory]}
+
+	panelWidth := heatmapColumns * heatmapCellSize
+	panelHeight := heatmapMemory / heatmapColumns * heatmapCellSize
+	img := image.NewRGBA(image.Rect(0, 0,
+		len(panels)*(panelWidth+heatmapMargin)+heatmapMargin,
+		panelHeight+2*heatmapMargin))
+
+	for i := range img.Pix {
+		img.Pix[i] = heatmapBackground.R
+	}
+
+	for i, counts := range panels {
+		var max uint64
+		for _, n := range counts {
+			if n > max {
+				max = n
+			}
+		}
+
+		left := heatmapMargin + i*(panelWidth+heatmapMargin)
+		for a, n := range counts {
+			c := heat(n, max)
+			x := left + a%heatmapColumns*heatmapCellSize
+			y := heatmapMargin + a/heatmapColumns*heatmapCellSize
+			for dy := 0; dy < heatmapCellSize; dy++ {
+				for dx := 0; dx < heatmapCellSize; dx++ {
+					img.SetRGBA(x+dx, y+dy, c)
+				}
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// heat returns the colour of a count, relative to the maximum on a log scale
+func heat(n, max uint64) color.RGBA {
+	if n == 0 || max == 0 {
+		return color.RGBA{0, 0, 0, 0xFF}
+	}
+
+	// From 1/3 for a single access to 1 for the maximum, through red,
+	// yellow and white
+	t := 1.0
+	if max > 1 {
+		t = 1.0/3 + 2.0/3*math.Log(float64(n))/math.Log(float64(max))
+	}
+	channel := func(v float64) uint8 {
+		return uint8(math.Max(0, math.Min(1, v)) * 0xFF)
+	}
+	return color.RGBA{channel(3 * t), channel(3*t - 1), channel(3*t - 2), 0xFF}
+}
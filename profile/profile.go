@@ -0,0 +1,276 @@
+// Package profile counts where a Chip 8 spends its instructions, to find
+// the hot spots of a rom. It reports the most executed addresses with their
+// disassembly, executions by opcode and tight busy-wait loops, and draws a
+// heatmap of how often memory is executed, read and written.
+package profile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+const (
+	// Largest distance a jump can go back to be a busy-wait loop, up to 5
+	// instructions including the jump
+	maxLoopLength = 8
+
+	// Loops which iterate fewer times aren't reported
+	minLoopIterations = 60
+)
+
+// Profiler counts the instructions executed by a Chip 8 and its memory accesses
+type Profiler struct {
+	// Executions, reads and writes of each address. Executions are counted
+	// at the address of the first byte of the instruction
+	Executed, Read, Written [chip8.MemorySize]uint64
+
+	// Executions of each opcode pattern, such as 8xy4 or Dxyn
+	Opcodes map[string]uint64
+
+	// Total instructions executed and frames run
+	Instructions uint64
+	Frames       int
+
+	// Jumps back to the start of a short loop, by the address of the jump
+	loops map[uint16]*Loop
+
+	c     *chip8.Chip8
+	hooks *chip8.Hooks
+}
+
+// Loop is a short loop executed repeatedly, usually waiting for a timer or key
+type Loop struct {
+	Start, End uint16 // address of the first instruction and of the jump back to it
+	Iterations uint64
+}
+
+// New creates a Profiler counting the execution of c until Close
+func New(c *chip8.Chip8) *Profiler {
+	p := &Profiler{
+		Opcodes: make(map[string]uint64),
+		loops:   make(map[uint16]*Loop),
+		c:       c,
+	}
+
+	p.hooks = &chip8.Hooks{
+		BeforeInstruction: p.instruction,
+		MemoryRead: func(address uint16, value byte, access chip8.MemoryAccess) {
+			p.Read[address]++
+		},
+		MemoryWrite: func(address uint16, value byte, access chip8.MemoryAccess) {
+			p.Written[address]++
+		},
+	}
+	c.AddHooks(p.hooks)
+	return p
+}
+
+// EndFrame should be called after each frame with its result, to count frames
+func (p *Profiler) EndFrame(result chip8.FrameResult) {
+	if !result.Paused {
+		p.Frames++
+	}
+}
+
+// Close stops counting
+func (p *Profiler) Close() {
+	p.c.RemoveHooks(p.hooks)
+}
+
+// instruction is the BeforeInstruction hook
+func (p *Profiler) instruction(pc uint16, inst chip8.Instruction) {
+	op := p.opcode(pc, inst)
+
+	p.Instructions++
+	p.Executed[pc]++
+	p.Opcodes[Pattern(op)]++
+
+	// Short jumps backwards, including to themselves
+	if op>>12 == 0x1 {
+		if target := op & 0xFFF; target <= pc && pc-target <= maxLoopLength {
+			l := p.loops[pc]
+			if l == nil {
+				l = &Loop{Start: target, End: pc}
+				p.loops[pc] = l
+			}
+			l.Iterations++
+		}
+	}
+}
+
+// opcode returns the opcode at pc, unknown opcodes are decoded as 0xFFFF
+func (p *Profiler) opcode(pc uint16, inst chip8.Instruction) uint16 {
+	if inst.Opcode != 0xFFFF {
+		return inst.Opcode
+	}
+	data, err := p.c.ReadMemory(pc, 2)
+	if err != nil {
+		return inst.Opcode
+	}
+	return chip8.GetOpcode(data[0], data[1])
+}
+
+// Pattern returns the opcode pattern op belongs to, e.g. 7xkk for 0x7101.
+// Opcodes identified by all their digits are returned in full
+func Pattern(op uint16) string {
+	switch op >> 12 {
+	case 0x0:
+		switch {
+		case op&0xFFF0 == 0x00C0:
+			return "00Cn"
+		case op&0xFFF0 == 0x00D0:
+			return "00Dn"
+		case op == 0x00E0, op == 0x00EE, op >= 0x00FB && op <= 0x00FF:
+			return fmt.Sprintf("%04X", op)
+		}
+		return "0nnn"
+	case 0x1, 0x2, 0xA, 0xB:
+		return fmt.Sprintf("%Xnnn", op>>12)
+	case 0x3, 0x4, 0x6, 0x7, 0xC:
+		return fmt.Sprintf("%Xxkk", op>>12)
+	case 0x5, 0x8, 0x9:
+		return fmt.Sprintf("%Xxy%X", op>>12, op&0xF)
+	case 0xD:
+		return "Dxyn"
+	}
+
+	// E and F
+	if op == 0xF000 || op == 0xF002 {
+		return fmt.Sprintf("%04X", op)
+	}
+	return fmt.Sprintf("%Xx%02X", op>>12, op&0xFF)
+}
+
+// Loops returns the short loops which iterated often, most iterations first
+func (p *Profiler) Loops() []Loop {
+	var loops []Loop
+	for _, l := range p.loops {
+		if l.Iterations >= minLoopIterations {
+			loops = append(loops, *l)
+		}
+	}
+
+	sort.Slice(loops, func(i, j int) bool {
+		if loops[i].Iterations != loops[j].Iterations {
+			return loops[i].Iterations > loops[j].Iterations
+		}
+		return loops[i].Start < loops[j].Start
+	})
+	return loops
+}
+
+// LoopKind describes what a loop is waiting for, from the instructions in it
+func (p *Profiler) LoopKind(l Loop) string {
+	data, err := p.c.ReadMemory(l.Start, int(l.End-l.Start)+2)
+	if err != nil {
+		return "spin"
+	}
+
+	for i := 0; i+1 < len(data); i += 2 {
+		op := chip8.GetOpcode(data[i], data[i+1])
+		switch Pattern(op) {
+		case "Fx07":
+			return "delay timer poll"
+		case "Ex9E", "ExA1":
+			return "key poll"
+		}
+	}
+	return "spin"
+}
+
+// percent returns n as a percentage of the instructions executed
+func (p *Profiler) percent(n uint64) float64 {
+	if p.Instructions == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(p.Instructions)
+}
+
+// WriteReport writes a text report of the top most executed addresses,
+// executions by opcode and busy-wait loops
+func (p *Profiler) WriteReport(w io.Writer, top int) error {
+	fmt.Fprintf(w, "%d instructions in %d frames", p.Instructions, p.Frames)
+	if p.Frames > 0 {
+		fmt.Fprintf(w, ", %.1f per frame", float64(p.Instructions)/float64(p.Frames))
+	}
+	fmt.Fprint(w, "\n\nHot addresses\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "executions\t%\taddress\topcode\t instruction")
+	for _, address := range p.hotAddresses(top) {
+		op := uint16(0)
+		if data, err := p.c.ReadMemory(address, 2); err == nil {
+			op = chip8.GetOpcode(data[0], data[1])
+		}
+		fmt.Fprintf(tw, "%d\t%.2f\t%#04x\t%04X\t %s\n", p.Executed[address], p.percent(p.Executed[address]), address, op, disassembler.Mnemonic(op))
+	}
+	tw.Flush()
+
+	fmt.Fprint(w, "\nOpcodes\n\n")
+	patterns := make([]string, 0, len(p.Opcodes))
+	for pattern := range p.Opcodes {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		a, b := p.Opcodes[patterns[i]], p.Opcodes[patterns[j]]
+		return a > b || (a == b && patterns[i] < patterns[j])
+	})
+
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "executions\t%\topcode\t")
+	for _, pattern := range patterns {
+		fmt.Fprintf(tw, "%d\t%.2f\t%s\t\n", p.Opcodes[pattern], p.percent(p.Opcodes[pattern]), pattern)
+	}
+	tw.Flush()
+
+	fmt.Fprint(w, "\nBusy-wait loops\n\n")
+	loops := p.Loops()
+	if len(loops) == 0 {
+		fmt.Fprintln(w, "none")
+	}
+	for _, l := range loops {
+		// Executions of the instructions in the loop
+		var executed uint64
+		for a := l.Start; a <= l.End; a += 2 {
+			executed += p.Executed[a]
+		}
+
+		fmt.Fprintf(w, "%#04x-%#04x %s, %d iterations, %.2f%% of instructions\n", l.Start, l.End, p.LoopKind(l), l.Iterations, p.percent(executed))
+		for a := l.Start; a <= l.End; a += 2 {
+			data, err := p.c.ReadMemory(a, 2)
+			if err != nil {
+				break
+			}
+			op := chip8.GetOpcode(data[0], data[1])
+			fmt.Fprintf(w, "    %#04x: %04X  %s\n", a, op, disassembler.Mnemonic(op))
+		}
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// hotAddresses returns up to n executed addresses, most executed first
+func (p *Profiler) hotAddresses(n int) []uint16 {
+	var addresses []uint16
+	for a, count := range p.Executed {
+		if count > 0 {
+			addresses = append(addresses, uint16(a))
+		}
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		a, b := p.Executed[addresses[i]], p.Executed[addresses[j]]
+		return a > b || (a == b && addresses[i] < addresses[j])
+	})
+
+	if len(addresses) > n {
+		addresses = addresses[:n]
+	}
+	return addresses
+}
@@ -0,0 +1,142 @@
+package profile
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// runProfile profiles frames of program, 10 instructions a frame
+func runProfile(t *testing.T, program []byte, frames int) *Profiler {
+	c := chip8.New(program, chip8.Quirks{})
+	r := chip8.NewRunner(c, 10)
+	p := New(c)
+
+	for i := 0; i < frames; i++ {
+		result, err := r.RunFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.EndFrame(result)
+	}
+	p.Close()
+	return p
+}
+
+// Waits for the delay timer then draws, repeatedly
+var delayProgram = []byte{
+	0x60, 0x02, // 0x200 V0 = 2
+	0xF0, 0x15, // 0x202 Delay = V0
+	0xF1, 0x07, // 0x204 V1 = Delay
+	0x31, 0x00, // 0x206 Skip if V1 == 0
+	0x12, 0x04, // 0x208 Jump to 0x204
+	0xA3, 0x00, // 0x20A I = 0x300
+	0xF0, 0x33, // 0x20C BCD of V0 at I
+	0xD0, 0x13, // 0x20E Draw 3 rows at V0, V1
+	0x12, 0x02, // 0x210 Jump to 0x202
+}
+
+func TestProfile(t *testing.T) {
+	p := runProfile(t, delayProgram, 100)
+
+	if p.Instructions != 1000 || p.Frames != 100 || p.Executed[0x200] != 1 {
+		t.Errorf("expected 1000 instructions in 100 frames, actually %d in %d", p.Instructions, p.Frames)
+	}
+
+	// Most time is spent polling the delay timer
+	hot := p.hotAddresses(3)
+	if len(hot) != 3 || hot[0] != 0x204 || hot[1] != 0x206 || hot[2] != 0x208 {
+		t.Errorf("expected the delay loop to be hottest, actually %#04x", hot)
+	}
+	if p.Opcodes["Fx07"] != p.Executed[0x204] || p.Opcodes["Dxyn"] != p.Executed[0x20E] {
+		t.Errorf("unexpected opcode counts %v", p.Opcodes)
+	}
+
+	// Each draw reads 3 bytes and BCD writes 3
+	draws := p.Executed[0x20E]
+	if p.Read[0x300] != draws || p.Read[0x303] != 0 || p.Written[0x302] != draws {
+		t.Errorf("expected %d reads and writes of 0x300, actually %d and %d", draws, p.Read[0x300], p.Written[0x300])
+	}
+
+	loops := p.Loops()
+	if len(loops) != 1 || loops[0].Start != 0x204 || loops[0].End != 0x208 || p.LoopKind(loops[0]) != "delay timer poll" {
+		t.Fatalf("expected a delay timer loop at 0x204-0x208, actually %+v", loops)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.WriteReport(buf, 5); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, expected := range []string{
+		"1000 instructions in 100 frames",
+		"0x0204    F107 LD V1, DT",
+		"Fx07",
+		"0x0204-0x0208 delay timer poll",
+		"    0x0208: 1204  JP 0x204",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain %q\n%s", expected, report)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	for op, expected := range map[uint16]string{
+		0x00E0: "00E0",
+		0x00C3: "00Cn",
+		0x00D3: "00Dn",
+		0x0123: "0nnn",
+		0x1204: "1nnn",
+		0x7101: "7xkk",
+		0x8124: "8xy4",
+		0xD125: "Dxyn",
+		0xE19E: "Ex9E",
+		0xF000: "F000",
+		0xF533: "Fx33",
+	} {
+		if got := Pattern(op); got != expected {
+			t.Errorf("%#04x: expected %s, actually %s", op, expected, got)
+		}
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	p := runProfile(t, delayProgram, 100)
+
+	buf := &bytes.Buffer{}
+	if err := p.WriteHeatmap(buf); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	panelWidth := heatmapColumns * heatmapCellSize
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != 3*panelWidth+4*heatmapMargin || h != 64*heatmapCellSize+2*heatmapMargin {
+		t.Fatalf("unexpected size %dx%d", w, h)
+	}
+
+	// pixel returns the colour of address in a panel
+	pixel := func(panel, address int) (r, g, b uint32) {
+		x := heatmapMargin + panel*(panelWidth+heatmapMargin) + address%heatmapColumns*heatmapCellSize
+		y := heatmapMargin + address/heatmapColumns*heatmapCellSize
+		r, g, b, _ = img.At(x, y).RGBA()
+		return
+	}
+
+	// The hottest address is white, unexecuted memory black
+	if r, g, b := pixel(0, 0x204); r != 0xFFFF || g != 0xFFFF || b != 0xFFFF {
+		t.Errorf("expected 0x204 to be white, actually %x %x %x", r, g, b)
+	}
+	if r, g, b := pixel(0, 0x100); r != 0 || g != 0 || b != 0 {
+		t.Errorf("expected 0x100 to be black, actually %x %x %x", r, g, b)
+	}
+	if r, _, _ := pixel(2, 0x300); r == 0 {
+		t.Error("expected 0x300 to be written")
+	}
+}
@@ -0,0 +1,53 @@
+package romdb
+
+import "github.com/pmcatominey/gochip8/chip8"
+
+// platform is the behaviour of a platform of the chip-8-database
+type platform struct {
+	quirks   chip8.Quirks
+	tickrate int // default instructions per frame
+}
+
+// Platforms by id, with the closest quirks gochip8 emulates
+var platforms = map[string]platform{
+	"originalChip8": {chip8.QuirksVIP, 15},
+	"hybridVIP":     {chip8.QuirksVIP, 15},
+	"modernChip8":   {chip8.Quirks{ShiftVy: true, IncrementI: true, ClipSprites: true}, 12},
+	"chip48":        {chip8.QuirksCHIP48, 30},
+	"superchip1":    {chip8.QuirksSCHIP, 30},
+	"superchip":     {chip8.QuirksSCHIP, 30},
+	"megachip8":     {chip8.QuirksSCHIP, 1000},
+	"xochip":        {chip8.QuirksXOCHIP, 100},
+}
+
+// setQuirk sets the quirk called name in the chip-8-database. Its quirks
+// name the behaviour which differs from the original interpreter, so shift
+// means shifting Vx in place. Without either memory quirk I is incremented
+// by x + 1, so memoryIncrementByX is set before memoryLeaveIUnchanged
+func setQuirk(q *chip8.Quirks, name string, on bool) {
+	switch name {
+	case "shift":
+		q.ShiftVy = !on
+	case "memoryLeaveIUnchanged":
+		if on {
+			q.IncrementI, q.IncrementIByX = false, false
+		} else if !q.IncrementIByX {
+			q.IncrementI = true
+		}
+	case "memoryIncrementByX":
+		if on {
+			q.IncrementI = false
+		} else if q.IncrementIByX {
+			q.IncrementI = true
+		}
+		q.IncrementIByX = on
+	case "wrap":
+		q.ClipSprites = !on
+	case "jump":
+		q.JumpVx = on
+	case "vblank":
+		q.DisplayWait = on
+	case "logic":
+		q.VFReset = on
+	}
+}
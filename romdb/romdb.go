@@ -0,0 +1,230 @@
+// Package romdb is a database of roms keyed by SHA-1, in the layout of the
+// community chip-8-database (https://github.com/chip-8/chip-8-database):
+// programs.json lists programs with their roms by hash, and sha1-hashes.json
+// maps each hash to the index of its program. A database of the roms in
+// games/ is embedded.
+package romdb
+
+import (
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// Files of a database in a directory
+const (
+	ProgramsFile = "programs.json"
+	HashesFile   = "sha1-hashes.json"
+)
+
+var (
+	//go:embed programs.json
+	embeddedPrograms []byte
+
+	//go:embed sha1-hashes.json
+	embeddedHashes []byte
+
+	embedded     *Database
+	embeddedOnce sync.Once
+)
+
+// Program is a game or other program, which may have several roms
+type Program struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Release     string          `json:"release,omitempty"`
+	Authors     []string        `json:"authors,omitempty"`
+	ROMs        map[string]*ROM `json:"roms"` // by SHA-1 in hex
+}
+
+// ROM is a version of a program and the settings it runs best with
+type ROM struct {
+	File      string   `json:"file,omitempty"`
+	Platforms []string `json:"platforms"` // ids of the platforms it runs on, best first
+
+	// Quirks which differ from those of a platform, by platform id then
+	// quirk name, e.g. {"originalChip8": {"shift": true}}
+	QuirkyPlatforms map[string]map[string]bool `json:"quirkyPlatforms,omitempty"`
+
+	// Instructions per frame, 0 for the default of the platform
+	Tickrate int `json:"tickrate,omitempty"`
+
+	// Chip 8 keys of the program's controls by name: up, down, left, right,
+	// a, b and the same for player 2, e.g. player2Up
+	Keys map[string]chip8.Key `json:"keys,omitempty"`
+
+	Colors *Colors `json:"colors,omitempty"`
+}
+
+// Colors are the colours a rom is designed for, as #rrggbb
+type Colors struct {
+	Pixels  []string `json:"pixels,omitempty"` // by the XO-CHIP planes a pixel is on in
+	Buzzer  string   `json:"buzzer,omitempty"`
+	Silence string   `json:"silence,omitempty"`
+}
+
+// Database is a set of programs with an index of their roms
+type Database struct {
+	Programs []*Program
+	hashes   map[string]int // index of the program of each rom
+}
+
+// Parse parses a database from the contents of programs.json and sha1-hashes.json
+func Parse(programs, hashes []byte) (*Database, error) {
+	db := &Database{}
+	if err := json.Unmarshal(programs, &db.Programs); err != nil {
+		return nil, fmt.Errorf("romdb: error parsing programs: %w", err)
+	}
+	if err := json.Unmarshal(hashes, &db.hashes); err != nil {
+		return nil, fmt.Errorf("romdb: error parsing hashes: %w", err)
+	}
+
+	for hash, i := range db.hashes {
+		if i < 0 || i >= len(db.Programs) {
+			return nil, fmt.Errorf("romdb: rom %s has program %d of %d", hash, i, len(db.Programs))
+		}
+		if _, ok := db.Programs[i].ROMs[hash]; !ok {
+			return nil, fmt.Errorf("romdb: rom %s isn't in program %d, %s", hash, i, db.Programs[i].Title)
+		}
+	}
+	return db, nil
+}
+
+// Open reads the database in dir, such as a checkout of chip-8-database's
+// database directory
+func Open(dir string) (*Database, error) {
+	programs, err := os.ReadFile(filepath.Join(dir, ProgramsFile))
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := os.ReadFile(filepath.Join(dir, HashesFile))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(programs, hashes)
+}
+
+// Embedded returns the database built into gochip8
+func Embedded() *Database {
+	embeddedOnce.Do(func() {
+		var err error
+		if embedded, err = Parse(embeddedPrograms, embeddedHashes); err != nil {
+			panic(err)
+		}
+	})
+	return embedded
+}
+
+// Entry is a rom found in a database
+type Entry struct {
+	SHA1    string
+	Program *Program
+	ROM     *ROM
+}
+
+// Hash returns the SHA-1 of rom in hex, as the database is keyed
+func Hash(rom []byte) string {
+	sum := sha1.Sum(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup finds rom in the database
+func (db *Database) Lookup(rom []byte) (*Entry, bool) {
+	hash := Hash(rom)
+	i, ok := db.hashes[hash]
+	if !ok {
+		return nil, false
+	}
+	program := db.Programs[i]
+	return &Entry{hash, program, program.ROMs[hash]}, true
+}
+
+// String returns the title and authors, e.g. Brix by Andreas Gustafsson
+func (e *Entry) String() string {
+	if len(e.Program.Authors) == 0 {
+		return e.Program.Title
+	}
+	return e.Program.Title + " by " + strings.Join(e.Program.Authors, ", ")
+}
+
+// Platform returns the id of the platform the rom runs best on, empty if
+// none is listed
+func (e *Entry) Platform() string {
+	if len(e.ROM.Platforms) == 0 {
+		return ""
+	}
+	return e.ROM.Platforms[0]
+}
+
+// Quirks returns the quirks of the rom's platform with those of its quirky
+// platforms applied, false if the platform isn't known
+func (e *Entry) Quirks() (chip8.Quirks, bool) {
+	p, ok := platforms[e.Platform()]
+	if !ok {
+		return chip8.Quirks{}, false
+	}
+
+	// In name order, which setQuirk relies on
+	quirky := e.ROM.QuirkyPlatforms[e.Platform()]
+	names := make([]string, 0, len(quirky))
+	for name := range quirky {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	q := p.quirks
+	for _, name := range names {
+		setQuirk(&q, name, quirky[name])
+	}
+	return q, true
+}
+
+// Tickrate returns the instructions per frame the rom runs at, the default
+// of its platform if it has none, 0 if neither is known
+func (e *Entry) Tickrate() int {
+	if e.ROM.Tickrate > 0 {
+		return e.ROM.Tickrate
+	}
+	return platforms[e.Platform()].tickrate
+}
+
+// Palette returns the colours of pixels by the XO-CHIP planes they're on in,
+// nil if the rom has none
+func (e *Entry) Palette() ([]color.RGBA, error) {
+	if e.ROM.Colors == nil {
+		return nil, nil
+	}
+
+	palette := make([]color.RGBA, len(e.ROM.Colors.Pixels))
+	for i, s := range e.ROM.Colors.Pixels {
+		c, err := parseColor(s)
+		if err != nil {
+			return nil, err
+		}
+		palette[i] = c
+	}
+	return palette, nil
+}
+
+// parseColor parses a #rrggbb colour
+func parseColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("romdb: invalid colour %q, expected #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("romdb: invalid colour %q, expected #rrggbb", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xFF}, nil
+}
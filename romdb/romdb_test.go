@@ -0,0 +1,141 @@
+package romdb
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+// Every rom in games/ is in the embedded database
+func TestEmbedded(t *testing.T) {
+	files, err := filepath.Glob("../games/*")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no games", err)
+	}
+
+	for _, file := range files {
+		rom, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry, ok := Embedded().Lookup(rom)
+		if !ok {
+			t.Errorf("%s isn't in the database", file)
+			continue
+		}
+		if entry.ROM.File != filepath.Base(file) || entry.Program.Title == "" {
+			t.Errorf("%s: unexpected entry %+v", file, entry.ROM)
+		}
+		if _, ok := entry.Quirks(); !ok || entry.Tickrate() == 0 {
+			t.Errorf("%s: unknown platform %q", file, entry.Platform())
+		}
+	}
+
+	if _, ok := Embedded().Lookup([]byte{0x12, 0x02}); ok {
+		t.Error("expected an unknown rom not to be found")
+	}
+}
+
+func TestSettings(t *testing.T) {
+	rom, err := os.ReadFile("../games/CONNECT4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := Embedded().Lookup(rom)
+
+	// The VIP's quirks, leaving I unchanged
+	expected := chip8.QuirksVIP
+	expected.IncrementI = false
+	if q, _ := entry.Quirks(); q != expected {
+		t.Errorf("expected quirks %+v, actually %+v", expected, q)
+	}
+	if entry.Tickrate() != 15 {
+		t.Errorf("expected the platform's tick rate 15, actually %d", entry.Tickrate())
+	}
+	if entry.ROM.Keys["a"] != chip8.Key5 || entry.String() != "Connect 4 by David Winter" {
+		t.Errorf("unexpected entry %s with keys %v", entry, entry.ROM.Keys)
+	}
+}
+
+const testPrograms = `[
+	{
+		"title": "Test",
+		"roms": {
+			"92a5652d382a18e89c4881ec57041fc7d885ca80": {
+				"platforms": ["xochip", "superchip"],
+				"tickrate": 1000,
+				"colors": {"pixels": ["#000000", "#ff8000"]}
+			}
+		}
+	}
+]`
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ProgramsFile), []byte(testPrograms), 0644)
+	os.WriteFile(filepath.Join(dir, HashesFile), []byte(`{"92a5652d382a18e89c4881ec57041fc7d885ca80": 0}`), 0644)
+
+	db, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := db.Lookup([]byte{0x12, 0x00})
+	if !ok {
+		t.Fatal("expected rom to be found")
+	}
+
+	if q, _ := entry.Quirks(); q != chip8.QuirksXOCHIP || entry.Tickrate() != 1000 {
+		t.Errorf("unexpected quirks %+v and tick rate %d", q, entry.Tickrate())
+	}
+
+	palette, err := entry.Palette()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(palette) != 2 || palette[1] != (color.RGBA{0xFF, 0x80, 0x00, 0xFF}) {
+		t.Errorf("unexpected palette %v", palette)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for hashes, expected := range map[string]string{
+		`{"92a5652d382a18e89c4881ec57041fc7d885ca80": 1}`: "romdb: rom 92a5652d382a18e89c4881ec57041fc7d885ca80 has program 1 of 1",
+		`{"da39a3ee5e6b4b0d3255bfef95601890afd80709": 0}`: "romdb: rom da39a3ee5e6b4b0d3255bfef95601890afd80709 isn't in program 0, Test",
+	} {
+		if _, err := Parse([]byte(testPrograms), []byte(hashes)); err == nil || err.Error() != expected {
+			t.Errorf("expected error %q, actually %v", expected, err)
+		}
+	}
+}
+
+func TestSetQuirk(t *testing.T) {
+	q := chip8.QuirksVIP
+	for name, on := range map[string]bool{"shift": true, "jump": true, "vblank": false, "logic": false, "wrap": true} {
+		setQuirk(&q, name, on)
+	}
+
+	expected := chip8.Quirks{IncrementI: true, JumpVx: true}
+	if q != expected {
+		t.Errorf("expected %+v, actually %+v", expected, q)
+	}
+
+	// The memory quirks of CHIP-48, which increments I by x
+	for _, test := range []struct {
+		quirks   map[string]bool
+		expected chip8.Quirks
+	}{
+		{map[string]bool{}, chip8.QuirksCHIP48},
+		{map[string]bool{"memoryIncrementByX": false}, chip8.Quirks{IncrementI: true, JumpVx: true, ClipSprites: true}},
+		{map[string]bool{"memoryIncrementByX": false, "memoryLeaveIUnchanged": true}, chip8.Quirks{JumpVx: true, ClipSprites: true}},
+		{map[string]bool{"memoryIncrementByX": true, "memoryLeaveIUnchanged": false}, chip8.QuirksCHIP48},
+	} {
+		e := &Entry{ROM: &ROM{Platforms: []string{"chip48"}, QuirkyPlatforms: map[string]map[string]bool{"chip48": test.quirks}}}
+		if q, _ := e.Quirks(); q != test.expected {
+			t.Errorf("%v: expected %+v, actually %+v", test.quirks, test.expected, q)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/romdb"
+)
+
+// lookupROM finds rom in the database selected by -romdb, nil if it isn't
+// there or the database is off
+func lookupROM(rom []byte) *romdb.Entry {
+	db := romdb.Embedded()
+	switch *romDB {
+	case "":
+	case "off":
+		return nil
+	default:
+		var err error
+		if db, err = romdb.Open(*romDB); err != nil {
+			fmt.Println("error reading rom database:", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	entry, ok := db.Lookup(rom)
+	if !ok {
+		return nil
+	}
+	fmt.Printf("Found %s in the rom database\n", entry)
+	return entry
+}
+
+// applyROMSettings applies the quirks and tick rate of entry unless -quirks
+// and -cycles are set, returning the quirks to use, and sets its colours and
+// keys in opts
+func applyROMSettings(entry *romdb.Entry, quirks chip8.Quirks, opts *frontend.Options) chip8.Quirks {
+	if q, ok := entry.Quirks(); ok && !flagSet("quirks") {
+		quirks = q
+	}
+	if tickrate := entry.Tickrate(); tickrate > 0 && !flagSet("cycles") {
+		*cyclesPerLoop = tickrate
+	}
+
+	palette, err := entry.Palette()
+	if err != nil {
+		fmt.Println("error in rom database:", err.Error())
+	}
+	opts.Palette = palette
+	opts.GameKeys = entry.ROM.Keys
+	opts.Title = "gochip8 - " + entry.Program.Title
+	return quirks
+}
+
+// flagSet returns true if the flag called name was set on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/dap"
+	"github.com/pmcatominey/gochip8/debugger"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/gdbstub"
+	"github.com/pmcatominey/gochip8/profile"
+	"github.com/pmcatominey/gochip8/trace"
+)
+
+// Number of save state slots, selected with F7
+const saveSlots = 10
+
+// session runs a Chip 8 in a frontend, handling hotkeys, rewinding and
+// recording or playing back input
+type session struct {
+	c8     *chip8.Chip8
+	fe     frontend.Frontend
+	runner chip8.FrameRunner
+
+	rewinder  *chip8.Rewinder
+	rewinding bool // true while the rewind key is held
+	paused    bool // true while the frontend has paused emulation
+
+	recorder *chip8.Recorder // set when recording input
+	player   *chip8.Player   // set when playing back a replay, input is ignored
+
+	debugger *debugger.Debugger // set when debugging
+	commands <-chan string      // debugger command lines
+	gdb      *gdbstub.Stub      // set when serving GDB clients
+	dap      *dap.Server        // set when launched by a debug adapter client
+	tracer   *trace.Tracer      // set when tracing instructions
+	profiler *profile.Profiler  // set when profiling
+
+	romFile  string // save states are written next to the rom
+	saveSlot int    // save state slot used by the save and load hotkeys
+
+	quit bool // set by the Quit event to exit at the end of the frame
+}
+
+// run runs frames at 60hz until the frontend quits, the program exits or
+// an error occurs. The end of a replay is not an error
+func (s *session) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	err := chip8.RunFrames(ctx, s, func(result chip8.FrameResult) error {
+		if err := s.endFrame(result); err != nil {
+			return err
+		}
+
+		if s.quit {
+			cancel()
+		}
+		return nil
+	})
+
+	switch err {
+	case chip8.ErrReplayFinished:
+		fmt.Println("replay finished after", s.player.Frame(), "frames")
+		return nil
+	case context.Canceled:
+		if s.quit {
+			return nil
+		}
+	}
+	return err
+}
+
+// RunFrame implements chip8.FrameRunner, running a frame of s.runner unless
+// the frontend has paused emulation. A fault is reported to an attached
+// debugger, which stops so its state can be inspected, instead of ending
+// the session
+func (s *session) RunFrame() (chip8.FrameResult, error) {
+	if s.paused {
+		// Nothing runs while the frontend shows something else
+		return chip8.FrameResult{Paused: true}, nil
+	}
+
+	result, err := s.runner.RunFrame()
+
+	var fault *chip8.Error
+	if errors.As(err, &fault) && ((s.dap != nil && s.dap.Fault(fault)) || (s.gdb != nil && s.gdb.Fault(fault))) {
+		return result, nil
+	}
+	return result, err
+}
+
+// endFrame records or rewinds the frame, outputs the sound and display,
+// then handles input for the next frame
+func (s *session) endFrame(result chip8.FrameResult) error {
+	if s.tracer != nil {
+		s.tracer.EndFrame(result)
+	}
+	if s.profiler != nil {
+		s.profiler.EndFrame(result)
+	}
+	if s.debugger != nil {
+		s.debugger.EndFrame(result)
+	}
+	if s.gdb != nil {
+		s.gdb.EndFrame(result)
+	}
+	if s.dap != nil {
+		s.dap.EndFrame(result)
+		s.quit = s.quit || s.dap.Disconnected()
+	}
+
+	switch {
+	case result.Paused && s.rewinding:
+		// Step backwards a frame at a time while the rewind key is held
+		s.fe.Buzz(false, s.c8.AudioPattern(), s.c8.AudioSampleRate())
+		if ok, err := s.rewinder.Rewind(s.c8); err != nil {
+			return fmt.Errorf("rewinding: %w", err)
+		} else if ok {
+			s.draw()
+		}
+	case result.Paused:
+		// Stopped in a debugger, show the display changed by stepping
+		s.fe.Buzz(false, s.c8.AudioPattern(), s.c8.AudioSampleRate())
+		if result.DisplayChanged {
+			s.draw()
+		}
+	default:
+		if s.recorder != nil {
+			if err := s.recorder.EndFrame(s.c8); err != nil {
+				return fmt.Errorf("recording replay: %w", err)
+			}
+		}
+
+		if s.player == nil {
+			if err := s.rewinder.Push(s.c8); err != nil {
+				return fmt.Errorf("recording rewind state: %w", err)
+			}
+		}
+
+		s.fe.Buzz(result.Buzzing, s.c8.AudioPattern(), s.c8.AudioSampleRate())
+
+		// Draw if needed
+		if result.DisplayChanged {
+			s.draw()
+		}
+	}
+
+	for _, e := range s.fe.Poll() {
+		s.handleEvent(e)
+	}
+
+	if s.debugger != nil {
+		s.debugCommands()
+	}
+
+	if r, ok := s.runner.(*chip8.Runner); ok {
+		r.Paused = s.rewinding || s.debugStopped()
+	}
+	return nil
+}
+
+// debugStopped returns true while a debugger has execution stopped
+func (s *session) debugStopped() bool {
+	return (s.debugger != nil && s.debugger.Stopped()) || (s.gdb != nil && s.gdb.Stopped()) ||
+		(s.dap != nil && s.dap.Stopped())
+}
+
+// debugCommands executes the debugger commands entered since the last frame
+func (s *session) debugCommands() {
+	for {
+		select {
+		case line, ok := <-s.commands:
+			if !ok {
+				// Stdin closed
+				s.commands = nil
+				s.quit = true
+				return
+			}
+			if err := s.debugger.Exec(line); err == debugger.ErrQuit {
+				s.quit = true
+			}
+		default:
+			return
+		}
+	}
+}
+
+// handleEvent handles an input event from the frontend
+func (s *session) handleEvent(e frontend.Event) {
+	switch e.Type {
+	case frontend.KeyDown:
+		s.pressKey(e.Key)
+	case frontend.KeyUp:
+		s.dePressKey(e.Key)
+	case frontend.Quit:
+		s.quit = true
+	case frontend.SaveState:
+		s.saveState()
+	case frontend.LoadState:
+		if !s.deterministic() {
+			s.loadState()
+		}
+	case frontend.NextSaveSlot:
+		s.saveSlot = (s.saveSlot + 1) % saveSlots
+		fmt.Println("selected save state slot", s.saveSlot)
+	case frontend.RewindStart:
+		s.rewinding = !s.deterministic()
+	case frontend.RewindStop:
+		s.rewinding = false
+	case frontend.PauseStart:
+		s.paused = true
+	case frontend.PauseStop:
+		s.paused = false
+	}
+}
+
+// draw outputs the display to the frontend
+func (s *session) draw() {
+	display := s.c8.Display()
+	width, height := s.c8.Resolution()
+	s.fe.Draw(&display, width, height)
+}
+
+// deterministic returns true when recording or playing back input, in
+// which case state must not be changed by loading or rewinding
+func (s *session) deterministic() bool {
+	if s.recorder != nil || s.player != nil {
+		fmt.Println("not available while recording or playing back a replay")
+		return true
+	}
+	return false
+}
+
+// pressKey presses k, recording it if input is being recorded
+func (s *session) pressKey(k chip8.Key) {
+	switch {
+	case s.player != nil:
+		// Input comes from the replay
+	case s.recorder != nil:
+		s.recorder.PressKey(s.c8, k)
+	default:
+		s.c8.PressKey(k)
+	}
+}
+
+// dePressKey releases k, recording it if input is being recorded
+func (s *session) dePressKey(k chip8.Key) {
+	switch {
+	case s.player != nil:
+		// Input comes from the replay
+	case s.recorder != nil:
+		s.recorder.DePressKey(s.c8, k)
+	default:
+		s.c8.DePressKey(k)
+	}
+}
+
+// stateFile returns the path of the save state file for slot, next to the rom
+func (s *session) stateFile(slot int) string {
+	return fmt.Sprintf("%s.state%d", s.romFile, slot)
+}
+
+// saveState writes the machine state to the selected slot
+func (s *session) saveState() {
+	data, err := s.c8.MarshalBinary()
+	if err == nil {
+		err = ioutil.WriteFile(s.stateFile(s.saveSlot), data, 0644)
+	}
+
+	if err != nil {
+		fmt.Println("error saving state:", err.Error())
+	} else {
+		fmt.Println("saved state to slot", s.saveSlot)
+	}
+}
+
+// loadState restores the machine state from the selected slot
+func (s *session) loadState() {
+	data, err := ioutil.ReadFile(s.stateFile(s.saveSlot))
+	if err == nil {
+		err = s.c8.UnmarshalBinary(data)
+	}
+
+	if err != nil {
+		fmt.Println("error loading state:", err.Error())
+	} else {
+		fmt.Println("loaded state from slot", s.saveSlot)
+		s.draw()
+	}
+}
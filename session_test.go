@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/debugger"
+	"github.com/pmcatominey/gochip8/frontend"
+	"github.com/pmcatominey/gochip8/frontend/headless"
+)
+
+// newTestSession creates a session running program in a headless frontend
+func newTestSession(program []byte) (*session, *headless.Frontend) {
+	c := chip8.New(program, chip8.Quirks{})
+	fe := headless.New()
+	return &session{
+		c8:       c,
+		fe:       fe,
+		runner:   chip8.NewRunner(c, 10),
+		rewinder: chip8.NewRewinder(60, 1024*1024),
+	}, fe
+}
+
+// runFrames runs n frames of s without waiting between them
+func runFrames(t *testing.T, s *session, n int) {
+	for i := 0; i < n; i++ {
+		result, err := s.RunFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.endFrame(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSessionInput(t *testing.T) {
+	s, fe := newTestSession([]byte{
+		0xF0, 0x0A, // Wait for key in V0
+		0xF0, 0x29, // I = sprite of V0
+		0xD1, 0x15, // Draw at V1, V1
+		0x12, 0x06, // Loop
+	})
+
+	fe.Send(1, frontend.Event{Type: frontend.KeyDown, Key: chip8.KeyA})
+	fe.Send(2, frontend.Event{Type: frontend.KeyUp, Key: chip8.KeyA})
+	runFrames(t, s, 4)
+
+	if fe.Draws != 1 {
+		t.Fatalf("expected 1 draw, actually %d", fe.Draws)
+	}
+
+	// Top row of "A" is 0xF0
+	if fe.Display[0][0] == 0 || fe.Display[4][0] != 0 || fe.Width != chip8.DisplayWidth {
+		t.Error("expected A to be drawn at 0, 0")
+	}
+}
+
+func TestSessionDebugDraw(t *testing.T) {
+	s, fe := newTestSession([]byte{
+		0xA0, 0x00, // I = 0
+		0xD0, 0x05, // Draw
+		0x12, 0x04, // Loop
+	})
+	s.debugger = debugger.New(s.runner.(*chip8.Runner), io.Discard)
+	commands := make(chan string, 2)
+	commands <- "step"
+	commands <- "step"
+	s.commands = commands
+
+	// Stepping the draw while stopped shows it
+	runFrames(t, s, 2)
+	if fe.Draws != 1 || s.c8.Registers().PC != 0x204 {
+		t.Errorf("expected the stepped draw to be shown, actually %d draws at %#04x", fe.Draws, s.c8.Registers().PC)
+	}
+}
+
+func TestSessionRewind(t *testing.T) {
+	s, fe := newTestSession([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Loop
+	})
+
+	var states []string
+	for i := 0; i < 5; i++ {
+		if i == 4 {
+			// Rewinding starts after the fifth frame
+			fe.Send(fe.Frames, frontend.Event{Type: frontend.RewindStart})
+		}
+		runFrames(t, s, 1)
+
+		state, _ := s.c8.MarshalBinary()
+		states = append(states, string(state))
+	}
+
+	// Each paused frame steps back one
+	runFrames(t, s, 2)
+	if state, _ := s.c8.MarshalBinary(); string(state) != states[2] {
+		t.Error("expected state after rewinding 2 frames to match the third frame")
+	}
+}
+
+func TestSessionPause(t *testing.T) {
+	s, fe := newTestSession([]byte{
+		0x70, 0x01, // V0 += 1
+		0x12, 0x00, // Loop
+	})
+
+	fe.Send(1, frontend.Event{Type: frontend.PauseStart})
+	fe.Send(3, frontend.Event{Type: frontend.PauseStop})
+	runFrames(t, s, 2)
+	before, _ := s.c8.MarshalBinary()
+
+	// Nothing runs while paused
+	runFrames(t, s, 2)
+	if state, _ := s.c8.MarshalBinary(); string(state) != string(before) {
+		t.Error("expected no change while paused")
+	}
+
+	runFrames(t, s, 1)
+	if state, _ := s.c8.MarshalBinary(); string(state) == string(before) {
+		t.Error("expected to run again after the pause")
+	}
+}
+
+func TestSessionQuit(t *testing.T) {
+	s, fe := newTestSession([]byte{
+		0x12, 0x00, // Loop
+	})
+	fe.QuitAfter = 3
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if fe.Frames != 3 {
+		t.Errorf("expected to quit after 3 frames, actually %d", fe.Frames)
+	}
+}
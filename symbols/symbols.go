@@ -0,0 +1,156 @@
+// Package symbols reads and writes symbol maps, which give the labels of a
+// rom and the source line each instruction was assembled from. They are
+// written by the assembler and read by debuggers.
+//
+// Symbol maps are JSON, addresses are numbers:
+//
+//	{
+//		"labels": {"main": 512, "draw": 530},
+//		"lines": [
+//			{"file": "game.asm", "line": 3, "address": 512},
+//			{"file": "game.asm", "line": 4, "address": 514}
+//		]
+//	}
+package symbols
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Map is a symbol map for a rom
+type Map struct {
+	Labels map[string]uint16 `json:"labels"`
+	Lines  []Line            `json:"lines"`
+}
+
+// Line is the source line an instruction was assembled from
+type Line struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Address uint16 `json:"address"`
+}
+
+// New creates an empty Map
+func New() *Map {
+	return &Map{
+		Labels: make(map[string]uint16),
+	}
+}
+
+// DefaultPath returns the path of the symbol map for a rom, the rom path
+// with its extension replaced by .sym.json
+func DefaultPath(rom string) string {
+	return strings.TrimSuffix(rom, filepath.Ext(rom)) + ".sym.json"
+}
+
+// Read decodes a Map written by Write
+func Read(r io.Reader) (*Map, error) {
+	m := New()
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("symbols: %w", err)
+	}
+	if m.Labels == nil {
+		m.Labels = make(map[string]uint16)
+	}
+	return m, nil
+}
+
+// ReadFile reads the Map at path
+func ReadFile(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Write encodes m as JSON
+func (m *Map) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// WriteFile writes m to path
+func (m *Map) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sameFile returns true if the file of a line matches path, either exactly
+// or by base name since debuggers give absolute paths
+func sameFile(file, path string) bool {
+	return file == path || filepath.Base(file) == filepath.Base(path)
+}
+
+// Address returns the address of the first instruction assembled from line
+// of file, or from the nearest following line with an instruction.
+// The line found is returned with it
+func (m *Map) Address(file string, line int) (Line, bool) {
+	var best Line
+	found := false
+	for _, l := range m.Lines {
+		if !sameFile(l.File, file) || l.Line < line {
+			continue
+		}
+		if !found || l.Line < best.Line || (l.Line == best.Line && l.Address < best.Address) {
+			best, found = l, true
+		}
+	}
+	return best, found
+}
+
+// Line returns the source line of the instruction at address
+func (m *Map) Line(address uint16) (Line, bool) {
+	for _, l := range m.Lines {
+		if l.Address == address {
+			return l, true
+		}
+	}
+	return Line{}, false
+}
+
+// Symbolize returns the nearest label at or before address, with the
+// offset from it if there is one, e.g. draw+0x4. Without a label the
+// address is returned in hex
+func (m *Map) Symbolize(address uint16) string {
+	name, labelAddress := "", uint16(0)
+	for label, a := range m.Labels {
+		// Prefer the closest label, then the first alphabetically
+		if a > address || (name != "" && (a < labelAddress || (a == labelAddress && label > name))) {
+			continue
+		}
+		name, labelAddress = label, a
+	}
+
+	switch {
+	case name == "":
+		return fmt.Sprintf("%#04x", address)
+	case labelAddress == address:
+		return name
+	}
+	return fmt.Sprintf("%s+%#x", name, address-labelAddress)
+}
+
+// Sort orders lines by address
+func (m *Map) Sort() {
+	sort.SliceStable(m.Lines, func(i, j int) bool {
+		return m.Lines[i].Address < m.Lines[j].Address
+	})
+}
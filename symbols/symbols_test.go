@@ -0,0 +1,76 @@
+package symbols
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testMap() *Map {
+	m := New()
+	m.Labels["main"] = 0x200
+	m.Labels["draw"] = 0x208
+	m.Lines = []Line{
+		{"game.asm", 3, 0x200},
+		{"game.asm", 4, 0x202},
+		{"game.asm", 8, 0x208},
+	}
+	return m
+}
+
+func TestReadWrite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := testMap().Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Labels["draw"] != 0x208 || len(m.Lines) != 3 || m.Lines[2].Line != 8 {
+		t.Errorf("unexpected map %+v", m)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	m := testMap()
+
+	if l, ok := m.Address("/home/dev/game.asm", 4); !ok || l.Address != 0x202 {
+		t.Errorf("expected line 4 at 0x202, actually %+v", l)
+	}
+
+	// Lines without instructions move to the next line with one
+	if l, ok := m.Address("game.asm", 5); !ok || l.Address != 0x208 || l.Line != 8 {
+		t.Errorf("expected line 5 to move to line 8 at 0x208, actually %+v", l)
+	}
+
+	if _, ok := m.Address("game.asm", 9); ok {
+		t.Error("expected no address after the last line")
+	}
+
+	if l, ok := m.Line(0x202); !ok || l.Line != 4 {
+		t.Errorf("expected 0x202 at line 4, actually %+v", l)
+	}
+}
+
+func TestSymbolize(t *testing.T) {
+	m := testMap()
+
+	for address, expected := range map[uint16]string{
+		0x100: "0x0100",
+		0x200: "main",
+		0x204: "main+0x4",
+		0x20A: "draw+0x2",
+	} {
+		if got := m.Symbolize(address); got != expected {
+			t.Errorf("%#04x: expected %s, actually %s", address, expected, got)
+		}
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	if got := DefaultPath("games/game.ch8"); got != "games/game.sym.json" {
+		t.Errorf("expected games/game.sym.json, actually %s", got)
+	}
+}
@@ -0,0 +1,329 @@
+// Package trace writes a record of every instruction a Chip 8 executes, to
+// compare runs against each other or against other emulators.
+//
+// Text traces have a line per instruction giving the state before it
+// executed, as space separated columns:
+//
+//	frame pc op v0 v1 v2 v3 v4 v5 v6 v7 v8 v9 va vb vc vd ve vf i sp dt st mnemonic
+//
+// frame is decimal, padded to 6 digits. The other columns are upper case hex
+// without a prefix: pc, op and i are 4 digits, the V registers, sp (the
+// number of return addresses on the stack), dt and st are 2. The mnemonic,
+// in the syntax of disassembler.Mnemonic, is the rest of the line and may
+// contain spaces. The first line is a header starting with #.
+//
+// Binary traces start with the 4 bytes "C8TR" and a version byte, currently
+// 1, followed by a 29 byte record per instruction with the same columns
+// except the mnemonic, big endian: frame (4 bytes), pc (2), op (2), v0 to vf
+// (1 each), i (2), sp, dt and st (1 each).
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pmcatominey/gochip8/chip8"
+	"github.com/pmcatominey/gochip8/disassembler"
+)
+
+// Header of text traces
+const Header = "# frame pc op v0 v1 v2 v3 v4 v5 v6 v7 v8 v9 va vb vc vd ve vf i sp dt st mnemonic"
+
+const (
+	binaryMagic   = "C8TR"
+	binaryVersion = 1
+	recordSize    = 29
+)
+
+// ErrInvalidTrace is returned when reading a binary trace with the wrong header
+var ErrInvalidTrace = errors.New("trace: not a binary trace")
+
+// Format is the encoding of a trace
+type Format int
+
+const (
+	Text Format = iota
+	Binary
+)
+
+// Record is the state before an instruction executed
+type Record struct {
+	Frame  int
+	PC     uint16
+	Opcode uint16
+	V      [chip8.VRegisterCount]byte
+	I      uint16
+	SP     byte
+	Delay  byte
+	Sound  byte
+}
+
+// String formats r as a line of a text trace, without the newline
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%06d %04X %04X", r.Frame, r.PC, r.Opcode)
+	for _, v := range r.V {
+		fmt.Fprintf(&b, " %02X", v)
+	}
+	fmt.Fprintf(&b, " %04X %02X %02X %02X %s", r.I, r.SP, r.Delay, r.Sound, disassembler.Mnemonic(r.Opcode))
+	return b.String()
+}
+
+// MarshalBinary encodes r as a record of a binary trace
+func (r Record) MarshalBinary() ([]byte, error) {
+	data := make([]byte, recordSize)
+	binary.BigEndian.PutUint32(data, uint32(r.Frame))
+	binary.BigEndian.PutUint16(data[4:], r.PC)
+	binary.BigEndian.PutUint16(data[6:], r.Opcode)
+	copy(data[8:], r.V[:])
+	binary.BigEndian.PutUint16(data[24:], r.I)
+	data[26], data[27], data[28] = r.SP, r.Delay, r.Sound
+	return data, nil
+}
+
+// UnmarshalBinary decodes a record of a binary trace
+func (r *Record) UnmarshalBinary(data []byte) error {
+	if len(data) != recordSize {
+		return fmt.Errorf("trace: record is %d bytes, expected %d", len(data), recordSize)
+	}
+	r.Frame = int(binary.BigEndian.Uint32(data))
+	r.PC = binary.BigEndian.Uint16(data[4:])
+	r.Opcode = binary.BigEndian.Uint16(data[6:])
+	copy(r.V[:], data[8:])
+	r.I = binary.BigEndian.Uint16(data[24:])
+	r.SP, r.Delay, r.Sound = data[26], data[27], data[28]
+	return nil
+}
+
+// Reader reads the records of a binary trace
+type Reader struct {
+	r    io.Reader
+	data []byte
+}
+
+// NewReader reads the header of a binary trace
+func NewReader(r io.Reader) (*Reader, error) {
+	header := make([]byte, len(binaryMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, ErrInvalidTrace
+	}
+	if header[len(binaryMagic)] != binaryVersion {
+		return nil, fmt.Errorf("trace: unsupported version %d", header[len(binaryMagic)])
+	}
+	return &Reader{r: r, data: make([]byte, recordSize)}, nil
+}
+
+// Read returns the next record, or io.EOF at the end of the trace
+func (r *Reader) Read() (Record, error) {
+	var rec Record
+	if _, err := io.ReadFull(r.r, r.data); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("trace: truncated record")
+		}
+		return rec, err
+	}
+	err := rec.UnmarshalBinary(r.data)
+	return rec, err
+}
+
+// ConditionType is what a Condition compares
+type ConditionType int
+
+const (
+	AtPC    ConditionType = iota // PC reaches an address
+	AtFrame                      // a frame is reached
+	AtCount                      // a number of instructions have executed
+)
+
+// Condition starts or stops tracing
+type Condition struct {
+	Type  ConditionType
+	Value int
+}
+
+// ParseConditions parses a comma separated list of conditions, each one of
+// pc=<hex address>, frame=<n> or count=<n>
+func ParseConditions(s string) ([]Condition, error) {
+	var conditions []Condition
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("trace: condition %q should be name=value", field)
+		}
+
+		var cond Condition
+		var n uint64
+		var err error
+		switch name {
+		case "pc":
+			cond.Type = AtPC
+			n, err = strconv.ParseUint(strings.TrimPrefix(strings.ToLower(value), "0x"), 16, 16)
+		case "frame":
+			cond.Type = AtFrame
+			n, err = strconv.ParseUint(value, 10, 31)
+		case "count":
+			cond.Type = AtCount
+			n, err = strconv.ParseUint(value, 10, 63)
+		default:
+			return nil, fmt.Errorf("trace: unknown condition %q, expected pc, frame or count", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("trace: invalid %s %q", name, value)
+		}
+
+		cond.Value = int(n)
+		conditions = append(conditions, cond)
+	}
+	return conditions, nil
+}
+
+// met returns true if the condition holds before the instruction at pc,
+// the count+1th instruction executed, in frame
+func (cond Condition) met(frame, count int, pc uint16) bool {
+	switch cond.Type {
+	case AtPC:
+		return int(pc) == cond.Value
+	case AtFrame:
+		return frame >= cond.Value
+	}
+	return count >= cond.Value
+}
+
+// Tracer writes a trace of the instructions executed by a Chip 8
+type Tracer struct {
+	// Tracing starts with the first instruction meeting any Start condition,
+	// or the first instruction without any. It stops before the first
+	// instruction after that meeting any Stop condition, and doesn't restart
+	Start, Stop []Condition
+
+	c      *chip8.Chip8
+	w      *bufio.Writer
+	format Format
+	hooks  *chip8.Hooks
+
+	frame   int // frame being run
+	count   int // instructions executed before the current one
+	tracing bool
+	done    bool
+
+	err error // first write error
+}
+
+// New creates a Tracer writing the instructions executed by c to w, the
+// trace isn't complete until Close is called
+func New(c *chip8.Chip8, w io.Writer, format Format) *Tracer {
+	t := &Tracer{
+		c:      c,
+		w:      bufio.NewWriter(w),
+		format: format,
+	}
+
+	if format == Binary {
+		t.w.WriteString(binaryMagic)
+		t.w.WriteByte(binaryVersion)
+	} else {
+		t.w.WriteString(Header + "\n")
+	}
+
+	t.hooks = &chip8.Hooks{BeforeInstruction: t.instruction}
+	c.AddHooks(t.hooks)
+	return t
+}
+
+// EndFrame should be called after each frame with its result, to number
+// the frames of the trace
+func (t *Tracer) EndFrame(result chip8.FrameResult) {
+	if !result.Paused {
+		t.frame = result.Frame + 1
+	}
+}
+
+// Done returns true once a Stop condition has been met
+func (t *Tracer) Done() bool {
+	return t.done
+}
+
+// Close stops tracing and flushes the trace, returning the first error
+// writing it. The underlying writer isn't closed
+func (t *Tracer) Close() error {
+	t.finish()
+	return t.err
+}
+
+// finish stops tracing and flushes the trace
+func (t *Tracer) finish() {
+	if t.hooks == nil {
+		return
+	}
+	t.c.RemoveHooks(t.hooks)
+	t.hooks = nil
+
+	if err := t.w.Flush(); err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// instruction is the BeforeInstruction hook
+func (t *Tracer) instruction(pc uint16, inst chip8.Instruction) {
+	count := t.count
+	t.count++
+
+	if !t.tracing {
+		if len(t.Start) > 0 && !anyMet(t.Start, t.frame, count, pc) {
+			return
+		}
+		t.tracing = true
+	} else if anyMet(t.Stop, t.frame, count, pc) {
+		t.done = true
+		t.finish()
+		return
+	}
+
+	// Unknown opcodes are decoded as 0xFFFF
+	op := inst.Opcode
+	if data, err := t.c.ReadMemory(pc, 2); err == nil {
+		op = chip8.GetOpcode(data[0], data[1])
+	}
+
+	regs := t.c.Registers()
+	rec := Record{
+		Frame:  t.frame,
+		PC:     pc,
+		Opcode: op,
+		V:      regs.V,
+		I:      regs.I,
+		SP:     byte(regs.SP),
+		Delay:  regs.Delay,
+		Sound:  regs.Sound,
+	}
+
+	var err error
+	if t.format == Binary {
+		data, _ := rec.MarshalBinary()
+		_, err = t.w.Write(data)
+	} else {
+		_, err = t.w.WriteString(rec.String() + "\n")
+	}
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// anyMet returns true if any of conditions is met
+func anyMet(conditions []Condition, frame, count int, pc uint16) bool {
+	for _, cond := range conditions {
+		if cond.met(frame, count, pc) {
+			return true
+		}
+	}
+	return false
+}
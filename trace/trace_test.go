@@ -0,0 +1,136 @@
+package trace
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pmcatominey/gochip8/chip8"
+)
+
+var testProgram = []byte{
+	0x60, 0x05, // 0x200 V0 = 5
+	0xA3, 0x00, // 0x202 I = 0x300
+	0x71, 0x01, // 0x204 V1 += 1
+	0x12, 0x04, // 0x206 Jump to 0x204
+}
+
+// runTrace runs frames of testProgram, 4 instructions a frame, tracing with
+// the start and stop conditions
+func runTrace(t *testing.T, format Format, frames int, start, stop string) *bytes.Buffer {
+	c := chip8.New(testProgram, chip8.Quirks{})
+	r := chip8.NewRunner(c, 4)
+
+	buf := &bytes.Buffer{}
+	tracer := New(c, buf, format)
+
+	var err error
+	if tracer.Start, err = ParseConditions(start); err != nil {
+		t.Fatal(err)
+	}
+	if tracer.Stop, err = ParseConditions(stop); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < frames; i++ {
+		result, err := r.RunFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracer.EndFrame(result)
+	}
+
+	if err := tracer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestText(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(runTrace(t, Text, 2, "", "").String()), "\n")
+	if len(lines) != 9 || lines[0] != Header {
+		t.Fatalf("expected a header and 8 lines, actually %q", lines)
+	}
+
+	expected := []string{
+		"000000 0200 6005 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0000 00 00 00 LD V0, 0x05",
+		"000000 0202 A300 05 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0000 00 00 00 LD I, 0x300",
+		"000000 0204 7101 05 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0300 00 00 00 ADD V1, 0x01",
+		"000000 0206 1204 05 01 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0300 00 00 00 JP 0x204",
+		"000001 0204 7101 05 01 00 00 00 00 00 00 00 00 00 00 00 00 00 00 0300 00 00 00 ADD V1, 0x01",
+	}
+	for i, line := range expected {
+		if lines[i+1] != line {
+			t.Errorf("line %d: expected\n%s\nactually\n%s", i+1, line, lines[i+1])
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+	for _, test := range []struct {
+		start, stop string
+		lines       int
+		first       string
+	}{
+		// Frames 2 and 3
+		{"frame=2", "frame=4", 8, "000002 0204"},
+		// The 3rd to 7th instructions
+		{"count=2", "count=7", 5, "000000 0204"},
+		// Each loop iteration starts at 0x204
+		{"pc=204", "pc=204", 2, "000000 0204"},
+		// Either condition starts tracing
+		{"frame=3,pc=206", "", 10*4 - 3, "000000 0206"},
+	} {
+		lines := strings.Split(strings.TrimSpace(runTrace(t, Text, 10, test.start, test.stop).String()), "\n")[1:]
+		if len(lines) != test.lines || !strings.HasPrefix(lines[0], test.first) {
+			t.Errorf("start %q stop %q: expected %d lines from %q, actually %q", test.start, test.stop, test.lines, test.first, lines)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	text := strings.Split(strings.TrimSpace(runTrace(t, Text, 3, "", "").String()), "\n")[1:]
+	r, err := NewReader(runTrace(t, Binary, 3, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Binary records format the same as the text trace
+	for i := 0; ; i++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			if i != len(text) {
+				t.Errorf("expected %d records, actually %d", len(text), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.String() != text[i] {
+			t.Errorf("record %d: expected %q, actually %q", i, text[i], rec.String())
+		}
+	}
+
+	if _, err := NewReader(strings.NewReader("C8")); err != ErrInvalidTrace {
+		t.Errorf("expected ErrInvalidTrace, actually %v", err)
+	}
+}
+
+func TestParseConditions(t *testing.T) {
+	conditions, err := ParseConditions("pc=0x2A4, frame=10,count=500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Condition{{AtPC, 0x2A4}, {AtFrame, 10}, {AtCount, 500}}
+	if len(conditions) != 3 || conditions[0] != expected[0] || conditions[1] != expected[1] || conditions[2] != expected[2] {
+		t.Errorf("expected %v, actually %v", expected, conditions)
+	}
+
+	for _, s := range []string{"pc", "pc=xyz", "frame=-1", "line=3"} {
+		if _, err := ParseConditions(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}